@@ -1,6 +1,6 @@
 package main
 
-import "github.com/dshaparenko/aim/cmd"
+import "aim/cmd"
 
 func main() {
 	cmd.Execute()
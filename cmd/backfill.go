@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"aim/common"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type BackfillOptions struct {
+	From       string
+	Checkpoint string
+	// PageDelay is the pause between two pages in milliseconds
+	PageDelay int
+}
+
+var backfillOptions = BackfillOptions{
+	From:       envGet("BACKFILL_FROM", "").(string),
+	Checkpoint: envGet("BACKFILL_CHECKPOINT", "aim-backfill.json").(string),
+	PageDelay:  envGet("BACKFILL_PAGE_DELAY", 1000).(int),
+}
+
+// backfillLayouts are the accepted --from formats, from the most to the least precise
+var backfillLayouts = []string{"2006-01-02", "2006-01", "2006"}
+
+// parseBackfillFrom reads the start of the backfill as a year, a month or a date
+func parseBackfillFrom(value string) (time.Time, error) {
+	for _, layout := range backfillLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --from %q, expected YYYY, YYYY-MM or YYYY-MM-DD", value)
+}
+
+func newBackfillCommand() *cobra.Command {
+	backfillCmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Load the incident history once, resuming an interrupted run from its checkpoint",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if backfillOptions.From == "" {
+				return fmt.Errorf("--from is required")
+			}
+			from, err := parseBackfillFrom(backfillOptions.From)
+			if err != nil {
+				return err
+			}
+			if backfillOptions.PageDelay < 0 {
+				return fmt.Errorf("invalid page delay %d", backfillOptions.PageDelay)
+			}
+
+			obs := common.NewObservability(logs, metrics, tracer)
+			jiraClient, err := common.NewJiraClient(jiraOptions, obs, metrics)
+			if err != nil {
+				return err
+			}
+			if err := addOpenSearchSink(jiraClient, jiraOptions.Tenant, obs); err != nil {
+				return err
+			}
+
+			issues, err := jiraClient.Backfill(cmd.Context(), common.BackfillOptions{
+				From:           from,
+				CheckpointPath: backfillOptions.Checkpoint,
+				PageDelay:      time.Duration(backfillOptions.PageDelay) * time.Millisecond,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Backfilled %d issues created since %s\n", len(issues), from.Format(time.DateOnly))
+			return nil
+		},
+	}
+
+	flags := backfillCmd.Flags()
+	flags.StringVar(&backfillOptions.From, "from", backfillOptions.From, "Load issues created since this year, month or date: 2019, 2019-06 or 2019-06-01")
+	flags.StringVar(&backfillOptions.Checkpoint, "checkpoint", backfillOptions.Checkpoint, "File the progress is saved to after every page, an interrupted backfill resumes from it")
+	flags.IntVar(&backfillOptions.PageDelay, "page-delay", backfillOptions.PageDelay, "Pause between two pages in milliseconds, on top of the Jira rate limit")
+
+	return backfillCmd
+}
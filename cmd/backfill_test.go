@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBackfillFrom(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2019", want: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2019-06", want: time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2019-06-15", want: time.Date(2019, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{value: "06/2019", wantErr: true},
+		{value: "last year", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBackfillFrom(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBackfillFrom(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseBackfillFrom(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackfillCommand(t *testing.T) {
+	server := newJiraStub(t, []map[string]interface{}{
+		{"id": "1", "key": "INCI-1", "fields": map[string]interface{}{"summary": "first", "created": "2019-03-01T10:00:00.000+0000"}},
+		{"id": "2", "key": "INCI-2", "fields": map[string]interface{}{"summary": "second", "created": "2020-03-01T10:00:00.000+0000"}},
+	})
+	stubJiraOptions(t, server.URL)
+	saved := backfillOptions
+	t.Cleanup(func() { backfillOptions = saved })
+
+	checkpoint := filepath.Join(t.TempDir(), "backfill.json")
+	var out bytes.Buffer
+	cmd := newBackfillCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--from", "2019", "--checkpoint", checkpoint, "--page-delay", "0"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if !strings.Contains(out.String(), "Backfilled 2 issues created since 2019-01-01") {
+		t.Errorf("backfill printed %q", out.String())
+	}
+	if _, err := os.Stat(checkpoint); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checkpoint kept after the backfill: %v", err)
+	}
+
+	for _, args := range [][]string{{"--checkpoint", checkpoint}, {"--from", "yesterday"}} {
+		backfillOptions = saved
+		cmd := newBackfillCommand()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("backfill %v accepted", args)
+		}
+	}
+}
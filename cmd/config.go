@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// configEnvNames maps flags to their environment variables where the names differ
+var configEnvNames = map[string]string{
+	"prometheus-url":        "PROMETHEUS_METRICS_URL",
+	"prometheus-listen":     "PROMETHEUS_METRICS_LISTEN",
+	"prometheus-prefix":     "PROMETHEUS_METRICS_PREFIX",
+	"prometheus-go-runtime": "PROMETHEUS_METRICS_GO_RUNTIME",
+	"out":                   "EXPORT_OUT",
+	"format":                "EXPORT_FORMAT",
+	"since":                 "EXPORT_SINCE",
+	"until":                 "EXPORT_UNTIL",
+	"from":                  "BACKFILL_FROM",
+	"checkpoint":            "BACKFILL_CHECKPOINT",
+	"page-delay":            "BACKFILL_PAGE_DELAY",
+}
+
+// envName returns the environment variable backing the flag
+func envName(flag string) string {
+	name, ok := configEnvNames[flag]
+	if !ok {
+		name = strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+	}
+	return fmt.Sprintf("%s_%s", APPNAME, name)
+}
+
+// commandFlags returns the flag names of every command of the tree, as one config file is shared by
+// all the commands
+func commandFlags(root *cobra.Command) map[string]bool {
+	names := make(map[string]bool)
+	add := func(flag *pflag.Flag) { names[flag.Name] = true }
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(add)
+		cmd.PersistentFlags().VisitAll(add)
+		for _, sub := range cmd.Commands() {
+			visit(sub)
+		}
+	}
+	visit(root)
+	return names
+}
+
+// loadConfig applies a YAML or JSON file keyed by flag names to the flags,
+// values from the environment and the command line take precedence over the file.
+// Keys of the known flags of other commands are accepted and left to them
+func loadConfig(flags *pflag.FlagSet, known map[string]bool, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	var unknown []string
+	for key := range values {
+		if key == "config" || (flags.Lookup(key) == nil && !known[key]) {
+			unknown = append(unknown, key)
+		}
+		keys = append(keys, key)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(unknown, ", "))
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		flag := flags.Lookup(key)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if _, ok := os.LookupEnv(envName(key)); ok {
+			continue
+		}
+		if err := setFlagValue(flag, values[key]); err != nil {
+			return fmt.Errorf("invalid value of %s in config file %s: %w", key, path, err)
+		}
+	}
+	return nil
+}
+
+// setFlagValue sets a decoded config value, lists and maps use the flag list and key=value syntax
+func setFlagValue(flag *pflag.Flag, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			return slice.Replace(items)
+		}
+		return flag.Value.Set(strings.Join(items, ","))
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, item))
+		}
+		sort.Strings(pairs)
+		return flag.Value.Set(strings.Join(pairs, ","))
+	default:
+		return flag.Value.Set(fmt.Sprint(v))
+	}
+}
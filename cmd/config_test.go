@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configTestValues holds the flag values set by a config file
+type configTestValues struct {
+	url        string
+	maxResults int
+	severities []string
+	priorities map[string]string
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		args    []string
+		env     map[string]string
+		want    configTestValues
+		wantErr bool
+	}{
+		{
+			name:   "file values applied",
+			config: `{"jira-url": "https://jira.example.com", "jira-max-results": 50, "jira-severity-order": ["P1", "P2"], "jira-priority-map": {"Highest": "P1"}}`,
+			want:   configTestValues{url: "https://jira.example.com", maxResults: 50, severities: []string{"P1", "P2"}, priorities: map[string]string{"Highest": "P1"}},
+		},
+		{
+			name:   "command line wins",
+			config: `{"jira-url": "https://file.example.com", "jira-max-results": 50}`,
+			args:   []string{"--jira-url", "https://flag.example.com"},
+			want:   configTestValues{url: "https://flag.example.com", maxResults: 50},
+		},
+		{
+			name:   "environment wins",
+			config: `{"jira-max-results": 50}`,
+			env:    map[string]string{"AIM_JIRA_MAX_RESULTS": "20"},
+			want:   configTestValues{maxResults: 1000},
+		},
+		{name: "unknown key", config: `{"jira-uri": "https://jira.example.com"}`, wantErr: true},
+		{name: "invalid value", config: `{"jira-max-results": "many"}`, wantErr: true},
+		{name: "config key rejected", config: `{"config": "other.yaml"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			path := filepath.Join(t.TempDir(), "aim.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var got configTestValues
+			var config string
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.StringVar(&got.url, "jira-url", "", "")
+			flags.IntVar(&got.maxResults, "jira-max-results", 1000, "")
+			flags.StringSliceVar(&got.severities, "jira-severity-order", nil, "")
+			flags.StringToStringVar(&got.priorities, "jira-priority-map", nil, "")
+			flags.StringVar(&config, "config", "", "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			err := loadConfig(flags, nil, path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadConfig() set %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if err := loadConfig(pflag.NewFlagSet("test", pflag.ContinueOnError), nil, filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
+
+func TestConfigSharedBySubcommands(t *testing.T) {
+	savedExport, savedBackfill := exportOptions, backfillOptions
+	t.Cleanup(func() { exportOptions, backfillOptions = savedExport, savedBackfill })
+
+	path := filepath.Join(t.TempDir(), "aim.yaml")
+	config := "jira-url: https://jira.example.com\nout: incidents.csv\nformat: csv\nfrom: \"2019\"\ncheckpoint: backfill.json\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A root like the aim one, whose subcommands only record that they ran
+	var url string
+	newRoot := func(config string) *cobra.Command {
+		root := &cobra.Command{
+			Use: "aim",
+			PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+				return loadConfig(cmd.Flags(), commandFlags(cmd.Root()), config)
+			},
+		}
+		root.PersistentFlags().StringVar(&url, "jira-url", "", "")
+		for _, sub := range []*cobra.Command{newExportCommand(), newBackfillCommand()} {
+			sub.RunE = func(cmd *cobra.Command, args []string) error { return nil }
+			root.AddCommand(sub)
+		}
+		return root
+	}
+
+	root := newRoot(path)
+	root.SetArgs([]string{"export"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("export with the shared config: %v", err)
+	}
+	if exportOptions.Out != "incidents.csv" || exportOptions.Format != "csv" || url != "https://jira.example.com" {
+		t.Errorf("export options %+v and url %q, want the file values", exportOptions, url)
+	}
+	if backfillOptions != savedBackfill {
+		t.Errorf("export applied the backfill keys: %+v", backfillOptions)
+	}
+
+	exportOptions = savedExport
+	root = newRoot(path)
+	root.SetArgs([]string{"backfill"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("backfill with the shared config: %v", err)
+	}
+	if backfillOptions.From != "2019" || backfillOptions.Checkpoint != "backfill.json" {
+		t.Errorf("backfill options %+v, want the file values", backfillOptions)
+	}
+	if exportOptions != savedExport {
+		t.Errorf("backfill applied the export keys: %+v", exportOptions)
+	}
+
+	// Keys no command knows are still rejected
+	unknown := filepath.Join(t.TempDir(), "unknown.yaml")
+	if err := os.WriteFile(unknown, []byte("jira-uri: https://jira.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root = newRoot(unknown)
+	root.SetArgs([]string{"export"})
+	root.SetErr(&bytes.Buffer{})
+	root.SetOut(&bytes.Buffer{})
+	if err := root.Execute(); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// dashboardPanel is a Grafana time series panel over a single query
+type dashboardPanel struct {
+	title  string
+	expr   string
+	legend string
+	unit   string
+}
+
+// dashboardPanels lists the panels of the generated dashboard, %[1]s is replaced by the metric prefix
+var dashboardPanels = []dashboardPanel{
+	{title: "Refreshes", expr: `sum by (result) (increase(%[1]srefresh_total[$__rate_interval]))`, legend: "{{result}}", unit: "short"},
+	{title: "Time since last refresh", expr: `time() - %[1]slast_refresh_timestamp_seconds`, legend: "{{tenant}}", unit: "s"},
+	{title: "Refresh duration", expr: `%[1]srefresh_duration_seconds`, legend: "{{tenant}}", unit: "s"},
+	{title: "Jira query duration", expr: `rate(%[1]sjira_query_duration_seconds_sum[$__rate_interval]) / rate(%[1]sjira_query_duration_seconds_count[$__rate_interval])`, legend: "{{tenant}}", unit: "s"},
+	{title: "Jira page duration p95", expr: `histogram_quantile(0.95, sum by (le, tenant) (rate(%[1]sjira_page_duration_seconds_bucket[$__rate_interval])))`, legend: "{{tenant}}", unit: "s"},
+	{title: "Jira API errors", expr: `sum by (code) (increase(%[1]sjira_api_errors_total[$__rate_interval]))`, legend: "{{code}}", unit: "short"},
+	{title: "Jira circuit state", expr: `%[1]sjira_circuit_state`, legend: "{{tenant}}", unit: "short"},
+	{title: "Cached issues by project", expr: `sum by (project) (%[1]sjira_issues_cached)`, legend: "{{project}}", unit: "short"},
+	{title: "Incidents by severity", expr: `sum by (severity) (%[1]sincidents_total)`, legend: "{{severity}}", unit: "short"},
+	{title: "Incidents by service", expr: `sum by (service) (%[1]sincidents_total)`, legend: "{{service}}", unit: "short"},
+	{title: "Incidents by environment", expr: `sum by (environment) (%[1]sincidents_total)`, legend: "{{environment}}", unit: "short"},
+	{title: "MTTR by severity", expr: `%[1]sincident_mttr_seconds`, legend: "{{severity}}", unit: "s"},
+	{title: "MTTD by severity", expr: `%[1]sincident_mttd_seconds`, legend: "{{severity}}", unit: "s"},
+	{title: "Incidents missing lifecycle fields", expr: `sum by (field) (%[1]sincident_missing_field_total)`, legend: "{{field}}", unit: "short"},
+	{title: "Recurring incidents", expr: `topk(10, %[1]sincident_recurrence > 1)`, legend: "{{service}}: {{root_cause}}", unit: "short"},
+	{title: "Open incidents by age", expr: `sum by (bucket) (%[1]sopen_incident_age)`, legend: "{{bucket}}", unit: "short"},
+	{title: "Open incidents by assignee", expr: `sum by (assignee) (%[1]sopen_incidents_by_assignee)`, legend: "{{assignee}}", unit: "short"},
+}
+
+// writeDashboard writes a Grafana dashboard over the AIM metrics named with the Prometheus prefix
+func writeDashboard(w io.Writer, prefix string) error {
+	if prefix != "" {
+		prefix += "_"
+	}
+
+	datasource := map[string]string{"type": "prometheus", "uid": "${datasource}"}
+	panels := make([]map[string]interface{}, 0, len(dashboardPanels))
+	for n, panel := range dashboardPanels {
+		panels = append(panels, map[string]interface{}{
+			"id":         n + 1,
+			"type":       "timeseries",
+			"title":      panel.title,
+			"datasource": datasource,
+			"gridPos":    map[string]int{"h": 8, "w": 12, "x": 12 * (n % 2), "y": 8 * (n / 2)},
+			"fieldConfig": map[string]interface{}{
+				"defaults":  map[string]string{"unit": panel.unit},
+				"overrides": []interface{}{},
+			},
+			"targets": []map[string]interface{}{{
+				"refId":        "A",
+				"datasource":   datasource,
+				"expr":         fmt.Sprintf(panel.expr, prefix),
+				"legendFormat": panel.legend,
+			}},
+		})
+	}
+
+	dashboard := map[string]interface{}{
+		"title":         "AIM",
+		"uid":           "aim",
+		"tags":          []string{"aim", "jira", "incidents"},
+		"schemaVersion": 39,
+		"time":          map[string]string{"from": "now-7d", "to": "now"},
+		"refresh":       "5m",
+		"templating": map[string]interface{}{
+			"list": []map[string]interface{}{{
+				"name":  "datasource",
+				"label": "Data source",
+				"type":  "datasource",
+				"query": "prometheus",
+			}},
+		},
+		"panels": panels,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dashboard)
+}
+
+func newDashboardCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "dashboard",
+		Short: "Print a Grafana dashboard over the AIM metrics as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeDashboard(cmd.OutOrStdout(), prometheusOptions.Prefix)
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDashboardCommand(t *testing.T) {
+	saved := prometheusOptions
+	t.Cleanup(func() { prometheusOptions = saved })
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "aim", want: []string{"aim_refresh_total", "aim_jira_query_duration_seconds_sum", "aim_incidents_total", "aim_incident_mttr_seconds", "aim_open_incident_age"}},
+		{prefix: "ops", want: []string{"ops_refresh_total", "ops_incidents_total"}},
+		{prefix: "", want: []string{"(refresh_total[", "(incidents_total)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			prometheusOptions.Prefix = tt.prefix
+
+			var out bytes.Buffer
+			cmd := newDashboardCommand()
+			cmd.SetOut(&out)
+			cmd.SetArgs(nil)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("dashboard: %v", err)
+			}
+
+			var dashboard struct {
+				Title  string `json:"title"`
+				Panels []struct {
+					Title   string `json:"title"`
+					Targets []struct {
+						Expr string `json:"expr"`
+					} `json:"targets"`
+				} `json:"panels"`
+			}
+			if err := json.Unmarshal(out.Bytes(), &dashboard); err != nil {
+				t.Fatalf("dashboard is not JSON: %v\n%s", err, out.String())
+			}
+			if dashboard.Title != "AIM" || len(dashboard.Panels) != len(dashboardPanels) {
+				t.Fatalf("dashboard %q has %d panels, want %d", dashboard.Title, len(dashboard.Panels), len(dashboardPanels))
+			}
+
+			var exprs []string
+			for _, panel := range dashboard.Panels {
+				if len(panel.Targets) != 1 || panel.Targets[0].Expr == "" {
+					t.Errorf("panel %q has no query", panel.Title)
+					continue
+				}
+				exprs = append(exprs, panel.Targets[0].Expr)
+			}
+			all := strings.Join(exprs, "\n")
+			for _, metric := range tt.want {
+				if !strings.Contains(all, metric) {
+					t.Errorf("no panel queries %s:\n%s", metric, all)
+				}
+			}
+			if tt.prefix != "aim" && strings.Contains(all, "aim_") {
+				t.Errorf("queries use the default prefix:\n%s", all)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"aim/common"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	"github.com/spf13/cobra"
+)
+
+type ExportOptions struct {
+	Out    string
+	Format string
+	Sample common.SampleOptions
+	Window CreatedWindow
+}
+
+// CreatedWindow selects issues created between two dates for one-off reports, replacing the lookback
+type CreatedWindow struct {
+	Since string
+	Until string
+}
+
+var exportOptions = ExportOptions{
+	Out:    envGet("EXPORT_OUT", "").(string),
+	Format: envGet("EXPORT_FORMAT", "json").(string),
+	Window: CreatedWindow{
+		Since: envGet("EXPORT_SINCE", "").(string),
+		Until: envGet("EXPORT_UNTIL", "").(string),
+	},
+}
+
+// windowLayouts are the accepted window bounds, a date alone covers the whole day
+var windowLayouts = []string{"2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// Enabled reports whether a bound of the window is set
+func (w CreatedWindow) Enabled() bool {
+	return w.Since != "" || w.Until != ""
+}
+
+// Parse validates the bounds, read in the timezone of the Jira instance. An until date without a time
+// includes the whole day
+func (w CreatedWindow) Parse(location *time.Location) (time.Time, time.Time, error) {
+	since, err := parseWindowBound("since", w.Since, false, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	until, err := parseWindowBound("until", w.Until, true, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--until %s is before --since %s", w.Until, w.Since)
+	}
+	return since, until, nil
+}
+
+func parseWindowBound(name, value string, endOfDay bool, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range windowLayouts {
+		t, err := time.ParseInLocation(layout, value, location)
+		if err != nil {
+			continue
+		}
+		if endOfDay && layout == "2006-01-02" {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 0, 0, location)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s %q, expected YYYY-MM-DD or YYYY-MM-DD HH:MM", name, value)
+}
+
+// fetchIssues runs the configured query, or the query of the created window when one is set
+func fetchIssues(ctx context.Context, jiraClient *common.JiraClient, window CreatedWindow) ([]*common.JiraIssue, error) {
+	var issues []*jira.Issue
+	var err error
+	if window.Enabled() {
+		var since, until time.Time
+		if since, until, err = window.Parse(jiraClient.Location()); err != nil {
+			return nil, err
+		}
+		issues, err = jiraClient.GetIssuesCreatedBetween(ctx, since, until)
+	} else {
+		issues, err = jiraClient.GetIssues(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jiraClient.ConvertToCustomIssues(issues)
+}
+
+func newExportCommand() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Fetch issues once and export them as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := exportOptions.Sample.Validate(); err != nil {
+				return err
+			}
+			if exportOptions.Format != "json" && exportOptions.Format != "csv" {
+				return fmt.Errorf("invalid export format %q, expected json or csv", exportOptions.Format)
+			}
+			if _, _, err := exportOptions.Window.Parse(time.UTC); err != nil {
+				return err
+			}
+
+			obs := common.NewObservability(logs, metrics, tracer)
+			jiraClient, err := common.NewJiraClient(jiraOptions, obs, metrics)
+			if err != nil {
+				return err
+			}
+
+			customIssues, err := fetchIssues(cmd.Context(), jiraClient, exportOptions.Window)
+			if err != nil {
+				return err
+			}
+
+			if exportOptions.Sample.Enabled() {
+				total := len(customIssues)
+				customIssues = common.SampleIssues(customIssues, exportOptions.Sample)
+				logs.Warn("Export is sampled (%d of %d issues) and is not authoritative", len(customIssues), total)
+			}
+
+			w := cmd.OutOrStdout()
+			if exportOptions.Out != "" {
+				f, err := os.Create(exportOptions.Out)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			if exportOptions.Format == "csv" {
+				return common.WriteIssuesCSV(w, customIssues)
+			}
+
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(customIssues)
+		},
+	}
+
+	flags := exportCmd.Flags()
+	flags.StringVar(&exportOptions.Out, "out", exportOptions.Out, "Output file, stdout when empty")
+	flags.StringVar(&exportOptions.Format, "format", exportOptions.Format, "Output format: json, csv")
+	flags.Float64Var(&exportOptions.Sample.Rate, "sample", exportOptions.Sample.Rate, "Export only this fraction of issues (0-1); sampled exports are not authoritative")
+	flags.IntVar(&exportOptions.Sample.Max, "sample-max", exportOptions.Sample.Max, "Export at most this many randomly sampled issues")
+	flags.StringVar(&exportOptions.Window.Since, "since", exportOptions.Window.Since, "Export issues created from this date, YYYY-MM-DD or YYYY-MM-DD HH:MM, instead of the lookback")
+	flags.StringVar(&exportOptions.Window.Until, "until", exportOptions.Window.Until, "Export issues created up to this date, a date alone includes the whole day")
+	flags.StringVar(&exportOptions.Sample.Stratify, "sample-stratify", exportOptions.Sample.Stratify, "Keep sample proportions by: severity, service")
+
+	return exportCmd
+}
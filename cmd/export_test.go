@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportCommandCSV(t *testing.T) {
+	server := newJiraStub(t, []map[string]interface{}{
+		{"id": "1", "key": "INCI-1", "fields": map[string]interface{}{"summary": "first, and only", "created": "2024-03-01T10:00:00.000+0000"}},
+	})
+	stubJiraOptions(t, server.URL)
+	saved := exportOptions
+	t.Cleanup(func() { exportOptions = saved })
+
+	out := filepath.Join(t.TempDir(), "issues.csv")
+	cmd := newExportCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--format", "csv", "--out", out})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want a header and 1 row", len(records))
+	}
+	row := map[string]string{}
+	for n, name := range records[0] {
+		row[name] = records[1][n]
+	}
+	if row["key"] != "INCI-1" || row["summary"] != "first, and only" || row["created"] != "2024-03-01T10:00:00Z" || row["resolved"] != "" {
+		t.Errorf("unexpected row %v", row)
+	}
+}
+
+func TestExportCommandRejectsFormat(t *testing.T) {
+	saved := exportOptions
+	t.Cleanup(func() { exportOptions = saved })
+
+	cmd := newExportCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--format", "xml"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("xml format accepted")
+	}
+}
+
+func TestCreatedWindowParse(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		window    CreatedWindow
+		location  *time.Location
+		wantSince time.Time
+		wantUntil time.Time
+		wantErr   bool
+	}{
+		{name: "empty"},
+		{
+			name:      "dates include the until day",
+			window:    CreatedWindow{Since: "2024-01-01", Until: "2024-06-30"},
+			wantSince: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, 6, 30, 23, 59, 0, 0, time.UTC),
+		},
+		{
+			name:      "times",
+			window:    CreatedWindow{Since: "2024-01-01 08:30", Until: "2024-01-02T18:00"},
+			wantSince: time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "jira timezone",
+			window:    CreatedWindow{Since: "2024-01-01", Until: "2024-01-31"},
+			location:  tokyo,
+			wantSince: time.Date(2023, 12, 31, 15, 0, 0, 0, time.UTC),
+			wantUntil: time.Date(2024, 1, 31, 14, 59, 0, 0, time.UTC),
+		},
+		{name: "invalid since", window: CreatedWindow{Since: "01/02/2024"}, wantErr: true},
+		{name: "invalid until", window: CreatedWindow{Until: "2024-13-01"}, wantErr: true},
+		{name: "until before since", window: CreatedWindow{Since: "2024-06-30", Until: "2024-01-01"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location := tt.location
+			if location == nil {
+				location = time.UTC
+			}
+			since, until, err := tt.window.Parse(location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !since.Equal(tt.wantSince) || !until.Equal(tt.wantUntil) {
+				t.Errorf("Parse() = %s, %s, want %s, %s", since, until, tt.wantSince, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestExportCommandWindow(t *testing.T) {
+	var jql string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		jql = r.URL.Query().Get("jql")
+		w.Write([]byte(`{"issues":[],"startAt":0,"maxResults":0,"total":0}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	stubJiraOptions(t, server.URL)
+	saved := exportOptions
+	t.Cleanup(func() { exportOptions = saved })
+
+	cmd := newExportCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--since", "2024-01-01", "--until", "2024-06-30"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	for _, clause := range []string{`created >= "2024/01/01 00:00"`, `created <= "2024/06/30 23:59"`} {
+		if !strings.Contains(jql, clause) {
+			t.Errorf("JQL %q lacks %s", jql, clause)
+		}
+	}
+	if strings.Contains(jql, "startOfYear") {
+		t.Errorf("JQL %q still carries the lookback", jql)
+	}
+
+	cmd = newExportCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--since", "yesterday"})
+	if err := cmd.Execute(); err == nil {
+		t.Error("invalid --since accepted")
+	}
+}
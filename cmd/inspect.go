@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"aim/common"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func newInspectCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect ISSUE-KEY",
+		Short: "Print the custom fields of an issue with their shape and a sample value",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			obs := common.NewObservability(logs, metrics, tracer)
+			jiraClient, err := common.NewJiraClient(jiraOptions, obs, metrics)
+			if err != nil {
+				return err
+			}
+
+			fields, err := jiraClient.InspectIssue(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "FIELD\tNAME\tMAPPED\tSHAPE\tSAMPLE")
+			for _, field := range fields {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", field.ID, field.Name, field.Mapped, field.Shape, field.Sample)
+			}
+			return w.Flush()
+		},
+	}
+}
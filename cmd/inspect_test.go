@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInspectCommand(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/issue/INCI-1", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fields") != "*all" {
+			t.Errorf("fields = %q, want *all", r.URL.Query().Get("fields"))
+		}
+		w.Write([]byte(`{"id":"1","key":"INCI-1","fields":{"summary":"Incident","customfield_18119":{"value":"SEV1"},"customfield_7":"eu"}}`))
+	})
+	mux.HandleFunc("/rest/api/2/field", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"customfield_18119","name":"Severity"}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	stubJiraOptions(t, server.URL)
+
+	var out bytes.Buffer
+	cmd := newInspectCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"INCI-1"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := [][]string{
+		{"FIELD", "NAME", "MAPPED", "SHAPE", "SAMPLE"},
+		{"customfield_18119", "Severity", "severity", "object{value}", `{"value":"SEV1"}`},
+		{"customfield_7", "string", `"eu"`},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("report has %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for n, line := range lines {
+		if got := strings.Fields(line); strings.Join(got, " ") != strings.Join(want[n], " ") {
+			t.Errorf("line %d = %q, want the columns %q", n, line, want[n])
+		}
+	}
+}
+
+func TestInspectCommandMissingIssue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errorMessages":["Issue does not exist"]}`))
+	}))
+	defer server.Close()
+	stubJiraOptions(t, server.URL)
+
+	cmd := newInspectCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"INCI-404"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "INCI-404") {
+		t.Errorf("inspect error = %v, want the missing issue", err)
+	}
+}
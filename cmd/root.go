@@ -3,9 +3,13 @@ package cmd
 import (
 	"aim/common"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -15,6 +19,9 @@ import (
 	sreProvider "github.com/devopsext/sre/provider"
 	utils "github.com/devopsext/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -24,6 +31,13 @@ var (
 	APPNAME = "AIM"
 
 	mainWG sync.WaitGroup
+	// metricsWG tracks the metrics endpoint, waited for by its shutdown hook
+	metricsWG sync.WaitGroup
+
+	// rootCtx is cancelled on the first shutdown signal, which stops scheduling new refreshes
+	rootCtx, rootCancel = context.WithCancel(context.Background())
+	// refreshCtx is cancelled once the shutdown timeout is reached, aborting the refreshes still running
+	refreshCtx, refreshCancel = context.WithCancel(context.Background())
 
 	// Observability components
 	logs    = sreCommon.NewLogs()
@@ -31,28 +45,151 @@ var (
 
 	stdout     *sreProvider.Stdout
 	prometheus *sreProvider.PrometheusMeter
+
+	// tracer is nil unless an OTLP endpoint is configured
+	tracerProvider *sdktrace.TracerProvider
+	tracer         trace.Tracer
 )
 
 type RootOptions struct {
-	Logs    []string
-	Metrics []string
+	Config            string
+	Logs              []string
+	Metrics           []string
+	Tenants           []string
+	HeartbeatInterval int
+	ShutdownTimeout   int
+	AllowUnconfigured bool
+}
+
+// shutdownHook flushes pending data of a component on graceful shutdown
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
 }
 
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []shutdownHook
+)
+
 // Default options
 var rootOptions = RootOptions{
-	Logs:    strings.Split(envGet("LOGS", "stdout").(string), ","),
-	Metrics: strings.Split(envGet("METRICS", "prometheus").(string), ","),
+	Config:            envGet("CONFIG", "").(string),
+	Logs:              strings.Split(envGet("LOGS", "stdout").(string), ","),
+	Metrics:           strings.Split(envGet("METRICS", "prometheus").(string), ","),
+	Tenants:           strings.Split(envGet("TENANTS", "").(string), ","),
+	HeartbeatInterval: envGet("HEARTBEAT_INTERVAL", 0).(int),
+	ShutdownTimeout:   envGet("SHUTDOWN_TIMEOUT", 10).(int),
+	AllowUnconfigured: envGet("ALLOW_UNCONFIGURED", false).(bool),
 }
 
+// unconfiguredCommands run without a Jira configuration, validate reports the missing settings itself
+var unconfiguredCommands = map[string]bool{"version": true, "validate": true, "dashboard": true}
+
 // Jira options with defaults
 var jiraOptions = common.JiraOptions{
-	URL:             envGet("JIRA_URL", "").(string),
-	Username:        envGet("JIRA_USERNAME", "").(string),
-	Password:        envGet("JIRA_PASSWORD", "").(string),
-	ApiToken:        envGet("JIRA_API_TOKEN", "").(string),
-	ProjectKey:      envGet("JIRA_PROJECT_KEY", "INCI").(string),
-	QueryFilter:     envGet("JIRA_QUERY_FILTER", "").(string),
-	RefreshInterval: envGet("JIRA_REFRESH_INTERVAL", 300).(int),
+	URL:                  envGet("JIRA_URL", "").(string),
+	Username:             envGet("JIRA_USERNAME", "").(string),
+	Password:             envGet("JIRA_PASSWORD", "").(string),
+	ApiToken:             envGet("JIRA_API_TOKEN", "").(string),
+	AuthMethod:           envGet("JIRA_AUTH_METHOD", "basic").(string),
+	OAuthClientID:        envGet("JIRA_OAUTH_CLIENT_ID", "").(string),
+	OAuthClientSecret:    envGet("JIRA_OAUTH_CLIENT_SECRET", "").(string),
+	OAuthTokenURL:        envGet("JIRA_OAUTH_TOKEN_URL", "").(string),
+	OAuthScopes:          strings.Split(envGet("JIRA_OAUTH_SCOPES", "").(string), ","),
+	ProjectKey:           envGet("JIRA_PROJECT_KEY", "INCI").(string),
+	QueryFilter:          envGet("JIRA_QUERY_FILTER", "").(string),
+	JQL:                  envGet("JIRA_JQL", "").(string),
+	LookbackJQL:          envGet("JIRA_LOOKBACK_JQL", "startOfYear(-1y)").(string),
+	LookbackDays:         envGet("JIRA_LOOKBACK_DAYS", 0).(int),
+	RefreshInterval:      envGet("JIRA_REFRESH_INTERVAL", 300).(int),
+	MaxResults:           envGet("JIRA_MAX_RESULTS", 1000).(int),
+	MaxIssues:            envGet("JIRA_MAX_ISSUES", 0).(int),
+	HTTPTimeout:          envGet("JIRA_HTTP_TIMEOUT", 30).(int),
+	ConnectTimeout:       envGet("JIRA_CONNECT_TIMEOUT", 10).(int),
+	FetchConcurrency:     envGet("JIRA_FETCH_CONCURRENCY", 4).(int),
+	ConvertWorkers:       envGet("JIRA_CONVERT_WORKERS", 0).(int),
+	RequestsPerSecond:    envGet("JIRA_REQUESTS_PER_SECOND", 0.0).(float64),
+	RateLimitWarnBelow:   envGet("JIRA_RATE_LIMIT_WARN_BELOW", 10).(int),
+	Proxy:                envGet("JIRA_PROXY", "").(string),
+	CustomHeaders:        parseKeyValues(envGet("JIRA_CUSTOM_HEADERS", "").(string)),
+	CACertPath:           envGet("JIRA_CA_CERT_PATH", "").(string),
+	InsecureSkipVerify:   envGet("JIRA_INSECURE_SKIP_VERIFY", false).(bool),
+	DateOnlyFields:       strings.Split(envGet("JIRA_DATE_ONLY_FIELDS", "").(string), ","),
+	TimeFormats:          strings.Split(envGet("JIRA_TIME_FORMATS", "").(string), ","),
+	Timezone:             envGet("JIRA_TIMEZONE", "UTC").(string),
+	ServiceMap:           parseKeyValues(envGet("JIRA_SERVICE_MAP", "").(string)),
+	ServiceMapFile:       envGet("JIRA_SERVICE_MAP_FILE", "").(string),
+	DoneFields:           strings.Split(envGet("JIRA_DONE_FIELDS", "resolved").(string), ","),
+	DoneSelect:           envGet("JIRA_DONE_SELECT", "first").(string),
+	PriorityMap:          parseKeyValues(envGet("JIRA_PRIORITY_MAP", "").(string)),
+	CacheFilePath:        envGet("JIRA_CACHE_FILE_PATH", "").(string),
+	RecordDir:            envGet("JIRA_RECORD_DIR", "").(string),
+	ReplayDir:            envGet("JIRA_REPLAY_DIR", "").(string),
+	UserFields:           strings.Split(envGet("JIRA_USER_FIELDS", "name,key,accountId,displayName").(string), ","),
+	MinTotal:             envGet("JIRA_MIN_TOTAL", 0).(int),
+	MaxTotal:             envGet("JIRA_MAX_TOTAL", 0).(int),
+	MaxTotalChange:       envGet("JIRA_MAX_TOTAL_CHANGE", 0.0).(float64),
+	RefreshScope:         envGet("JIRA_REFRESH_SCOPE", "full").(string),
+	OnlyUnresolved:       envGet("JIRA_ONLY_UNRESOLVED", false).(bool),
+	OnlyResolved:         envGet("JIRA_ONLY_RESOLVED", false).(bool),
+	SeverityOrder:        strings.Split(envGet("JIRA_SEVERITY_ORDER", "SEV1,SEV2,SEV3,SEV4,SEV5").(string), ","),
+	MinSeverityForAlerts: envGet("JIRA_MIN_SEVERITY_FOR_ALERTS", "").(string),
+	LabelFilter:          strings.Split(envGet("JIRA_LABEL_FILTER", "").(string), ","),
+	SeverityWeights:      parseKeyInts(envGet("JIRA_SEVERITY_WEIGHTS", "").(string)),
+	SeverityAliases:      parseKeyValues(envGet("JIRA_SEVERITY_ALIASES", "").(string)),
+	EnvironmentSources:   strings.Split(envGet("JIRA_ENVIRONMENT_SOURCES", "").(string), ","),
+	OnlyEnvironments:     strings.Split(envGet("JIRA_ONLY_ENVIRONMENTS", "").(string), ","),
+	EnvironmentSynonyms:  parseKeyValues(envGet("JIRA_ENVIRONMENT_SYNONYMS", "production=prod,prd=prod,staging=stage,stg=stage").(string)),
+	FieldMapping:         parseKeyValues(envGet("JIRA_FIELD_MAP", "").(string)),
+	RequestFields:        strings.Split(envGet("JIRA_REQUEST_FIELDS", "").(string), ","),
+	MaxRetries:           envGet("JIRA_MAX_RETRIES", 3).(int),
+	RetryBackoff:         envGet("JIRA_RETRY_BACKOFF", 1000).(int),
+	IncrementalRefresh:   envGet("JIRA_INCREMENTAL_REFRESH", false).(bool),
+	CircuitFailures:      envGet("JIRA_CIRCUIT_FAILURES", 5).(int),
+	CircuitCooldown:      envGet("JIRA_CIRCUIT_COOLDOWN", 300).(int),
+	AcceptPartialRefresh: envGet("JIRA_ACCEPT_PARTIAL_REFRESH", false).(bool),
+	UseChangelog:         envGet("JIRA_USE_CHANGELOG", false).(bool),
+	IncludeCommentCounts: envGet("JIRA_INCLUDE_COMMENT_COUNTS", false).(bool),
+	IncludeAttachments:   envGet("JIRA_INCLUDE_ATTACHMENTS", false).(bool),
+	WorkloadThreshold:    envGet("JIRA_WORKLOAD_THRESHOLD", 0).(int),
+	ChangelogFallback:    envGet("JIRA_CHANGELOG_FALLBACK", false).(bool),
+	StatusStageMap:       parseKeyValues(envGet("JIRA_STATUS_STAGE_MAP", "").(string)),
+}
+
+// API server options
+var apiOptions = common.ApiOptions{
+	Listen:         envGet("API_LISTEN", "0.0.0.0:8080").(string),
+	BasePath:       envGet("API_BASE_PATH", "").(string),
+	ReadyStaleness: envGet("API_READY_STALENESS", 900).(int),
+	ReloadToken:    envGet("API_RELOAD_TOKEN", "").(string),
+	AccessLog:      envGet("API_ACCESS_LOG", false).(bool),
+}
+
+// Notification options
+var notifyOptions = common.NotifyOptions{
+	WebhookURL:  envGet("NOTIFY_WEBHOOK_URL", "").(string),
+	Timeout:     envGet("NOTIFY_TIMEOUT", 10).(int),
+	MinSeverity: envGet("NOTIFY_MIN_SEVERITY", "SEV2").(string),
+}
+
+// OpenSearch sink options
+var openSearchOptions = common.OpenSearchOptions{
+	URL:       envGet("ES_URL", "").(string),
+	Index:     envGet("ES_INDEX", "aim-issues").(string),
+	Username:  envGet("ES_USERNAME", "").(string),
+	Password:  envGet("ES_PASSWORD", "").(string),
+	Timeout:   envGet("ES_TIMEOUT", 30).(int),
+	BatchSize: envGet("ES_BATCH_SIZE", 500).(int),
+}
+
+// Audit storage options
+var auditOptions = common.AuditOptions{
+	Dir:         envGet("AUDIT_DIR", "").(string),
+	Compress:    envGet("AUDIT_COMPRESS", true).(bool),
+	Consolidate: envGet("AUDIT_CONSOLIDATE", false).(bool),
+	MaxAgeHours: envGet("AUDIT_MAX_AGE_HOURS", 168).(int),
+	MaxSizeMB:   envGet("AUDIT_MAX_SIZE_MB", 1024).(int),
 }
 
 // Provider options
@@ -71,21 +208,134 @@ var prometheusOptions = sreProvider.PrometheusOptions{
 	GoRuntime: envGet("PROMETHEUS_METRICS_GO_RUNTIME", true).(bool),
 }
 
+var tracingOptions = TracingOptions{
+	Endpoint:    envGet("OTEL_ENDPOINT", "").(string),
+	ServiceName: envGet("OTEL_SERVICE_NAME", "aim").(string),
+	Headers:     parseKeyValues(envGet("OTEL_HEADERS", "").(string)),
+}
+
 func envGet(s string, def interface{}) interface{} {
 	return utils.EnvGet(fmt.Sprintf("%s_%s", APPNAME, s), def)
 }
 
-// interceptSyscall handles system signals for graceful shutdown
+// parseKeyInts parses key=number pairs, skipping pairs with an invalid number
+func parseKeyInts(s string) map[string]int {
+	m := make(map[string]int)
+	for key, value := range parseKeyValues(s) {
+		if n, err := strconv.Atoi(value); err == nil {
+			m[key] = n
+		}
+	}
+	return m
+}
+
+// parseKeyValues parses comma-separated key=value pairs, ignoring malformed entries
+func parseKeyValues(s string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); ok && key != "" {
+			m[key] = strings.TrimSpace(value)
+		}
+	}
+	return m
+}
+
+// interceptSyscall cancels the root context on the first signal and forces the exit on the second one
 func interceptSyscall() {
-	c := make(chan os.Signal, 1)
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-c
 		logs.Info("Received shutdown signal - exiting gracefully...")
-		// Allow time for cleanup operations
-		time.Sleep(1 * time.Second)
-		os.Exit(0)
+		rootCancel()
+
+		<-c
+		logs.Warn("Received second shutdown signal - exiting immediately")
+		os.Exit(1)
+	}()
+}
+
+// shutdown stops the background work once the root context is cancelled: it runs the shutdown hooks,
+// which stop the HTTP servers and flush pending data once the running refreshes are done, and waits for
+// the main goroutines within the timeout
+func shutdown(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	runShutdownHooks(ctx)
+
+	if err := waitDone(ctx, &mainWG); err != nil {
+		refreshCancel()
+		logs.Warn("Shutdown timeout reached, exiting with work still running")
+		return
+	}
+	logs.Info("Shutdown completed")
+}
+
+// stopMetrics stops the metrics providers and waits for the metrics endpoint to be closed
+func stopMetrics(ctx context.Context) error {
+	metrics.Stop()
+	return waitDone(ctx, &metricsWG)
+}
+
+// waitDone waits for the wait group, returning the context error when it ends first
+func waitDone(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
 	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// onShutdown registers a flush to run on graceful shutdown
+func onShutdown(name string, fn func(ctx context.Context) error) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, shutdownHook{name: name, fn: fn})
+}
+
+// runShutdownHooks runs all registered flushes concurrently, logging those not done before the context ends
+func runShutdownHooks(ctx context.Context) {
+	shutdownMu.Lock()
+	hooks := append([]shutdownHook(nil), shutdownHooks...)
+	shutdownMu.Unlock()
+
+	done := make(chan string, len(hooks))
+	pending := make(map[string]bool, len(hooks))
+	for _, hook := range hooks {
+		pending[hook.name] = true
+		go func(hook shutdownHook) {
+			if err := hook.fn(ctx); err != nil {
+				logs.Error("Failed to flush %s on shutdown: %v", hook.name, err)
+			} else {
+				logs.Info("Flushed %s on shutdown", hook.name)
+			}
+			done <- hook.name
+		}(hook)
+	}
+
+	for len(pending) > 0 {
+		select {
+		case name := <-done:
+			delete(pending, name)
+		case <-ctx.Done():
+			var names []string
+			for name := range pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			logs.Warn("Shutdown timeout reached, not flushed: %s", strings.Join(names, ", "))
+			return
+		}
+	}
 }
 
 func Execute() error {
@@ -95,7 +345,13 @@ func Execute() error {
 		Short: "AIM - Analysis Issues and Metrics",
 		Long: `AIM is a service that collects data from Jira,
 processes it, and exposes metrics that can be scraped by Prometheus.`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if rootOptions.Config != "" {
+				if err := loadConfig(cmd.Flags(), commandFlags(cmd.Root()), rootOptions.Config); err != nil {
+					return err
+				}
+			}
+
 			// Initialize logging
 			stdoutOptions.Version = version
 			stdout = sreProvider.NewStdout(stdoutOptions)
@@ -110,64 +366,48 @@ processes it, and exposes metrics that can be scraped by Prometheus.`,
 			prometheusOptions.Version = version
 			prometheus = sreProvider.NewPrometheusMeter(prometheusOptions, logs, stdout)
 			if utils.Contains(rootOptions.Metrics, "prometheus") && prometheus != nil {
-				prometheus.StartInWaitGroup(&mainWG)
+				prometheus.StartInWaitGroup(&metricsWG)
 				metrics.Register(prometheus)
+				onShutdown("metrics server", stopMetrics)
 				logs.Info("Prometheus metrics endpoint started at %s%s", prometheusOptions.Listen, prometheusOptions.URL)
 			}
 
-			// Validate Jira configuration
-			if jiraOptions.URL == "" {
-				logs.Error("Jira URL is not configured")
-			}
-			if jiraOptions.Username == "" || jiraOptions.ApiToken == "" {
-				logs.Error("Jira credentials are not configured")
-			}
-		},
-		Run: func(cmd *cobra.Command, args []string) {
-			logs.Info("AIM service is running. Press Ctrl+C to exit.")
-
-			// Create observability wrapper
-			obs := common.NewObservability(logs, metrics)
-
-			jiraClient, err := common.NewJiraClient(
-				jiraOptions.URL,
-				jiraOptions.Username,
-				jiraOptions.ApiToken,
-				jiraOptions.ProjectKey,
-				jiraOptions.QueryFilter,
-				jiraOptions.RefreshInterval,
-				obs,
-				metrics,
-			)
-
-			if err != nil {
-				logs.Error("Failed to create Jira client: %v", err)
-				os.Exit(1)
+			// Initialize traces
+			if tracingOptions.Endpoint != "" {
+				provider, err := newTracerProvider(cmd.Context(), tracingOptions)
+				if err != nil {
+					return err
+				}
+				tracerProvider = provider
+				tracer = provider.Tracer("aim")
+				logs.Info("OpenTelemetry traces are exported to %s", tracingOptions.Endpoint)
 			}
 
-			// Test the connection
-			if err := jiraClient.TestConnection(); err != nil {
-				logs.Error("Failed to connect to Jira: %v", err)
-				// Continue anyway, might be a temporary issue
+			// Refuse to run without the Jira URL or credentials instead of running while doing nothing
+			if !unconfiguredCommands[cmd.Name()] {
+				if err := checkJiraConfig(); err != nil {
+					if !rootOptions.AllowUnconfigured {
+						return err
+					}
+					logs.Warn("Jira is not configured, continuing as unconfigured runs are allowed: %v", err)
+				}
 			}
-
-			// Start the data refresh loop
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-
-			jiraClient.StartRefreshLoop(ctx, &mainWG)
-			logs.Info("Jira data collection started with refresh interval of %d seconds", jiraOptions.RefreshInterval)
-
-			// Keep the app running
-			select {}
+			return nil
 		},
+		Run: runService,
 	}
 
 	flags := rootCmd.PersistentFlags()
 
+	flags.StringVar(&rootOptions.Config, "config", rootOptions.Config, "YAML or JSON file keyed by flag names, environment variables and flags take precedence")
+
 	// Logging flags
 	flags.StringSliceVar(&rootOptions.Logs, "logs", rootOptions.Logs, "Log providers: stdout")
 	flags.StringSliceVar(&rootOptions.Metrics, "metrics", rootOptions.Metrics, "Metric providers: prometheus")
+	flags.StringSliceVar(&rootOptions.Tenants, "tenants", rootOptions.Tenants, "Names of the Jira instances polled by the service, each configured by AIM_<TENANT>_JIRA_* variables over the shared Jira options")
+	flags.IntVar(&rootOptions.HeartbeatInterval, "heartbeat-interval", rootOptions.HeartbeatInterval, "Interval in seconds between heartbeat status logs, 0 disables")
+	flags.IntVar(&rootOptions.ShutdownTimeout, "shutdown-timeout", rootOptions.ShutdownTimeout, "Seconds to wait for servers to stop, pending data to be flushed and refreshes to finish on shutdown")
+	flags.BoolVar(&rootOptions.AllowUnconfigured, "allow-unconfigured", rootOptions.AllowUnconfigured, "Start without the Jira URL or credentials, for test runs")
 
 	// Stdout flags
 	flags.StringVar(&stdoutOptions.Format, "stdout-format", stdoutOptions.Format, "Stdout format: json, text, template")
@@ -182,13 +422,40 @@ processes it, and exposes metrics that can be scraped by Prometheus.`,
 	flags.StringVar(&prometheusOptions.Prefix, "prometheus-prefix", prometheusOptions.Prefix, "Prometheus metrics prefix")
 	flags.BoolVar(&prometheusOptions.GoRuntime, "prometheus-go-runtime", prometheusOptions.GoRuntime, "Include Go runtime metrics")
 
+	// OpenTelemetry flags
+	flags.StringVar(&tracingOptions.Endpoint, "otel-endpoint", tracingOptions.Endpoint, "OTLP/HTTP traces endpoint URL, e.g. http://localhost:4318/v1/traces, empty disables tracing")
+	flags.StringVar(&tracingOptions.ServiceName, "otel-service-name", tracingOptions.ServiceName, "Service name of the exported spans")
+	flags.StringToStringVar(&tracingOptions.Headers, "otel-headers", tracingOptions.Headers, "Headers sent to the OTLP endpoint, e.g. for authentication: Authorization=...")
+
+	// API flags
+	flags.StringVar(&apiOptions.Listen, "api-listen", apiOptions.Listen, "API listen address and port, empty disables the API")
+	flags.StringVar(&apiOptions.BasePath, "api-base-path", apiOptions.BasePath, "Prefix for all API routes, e.g. /aim behind a reverse proxy")
+	flags.IntVar(&apiOptions.ReadyStaleness, "api-ready-staleness", apiOptions.ReadyStaleness, "Seconds since the last successful refresh after which /readyz fails, 0 disables")
+	flags.StringVar(&apiOptions.ReloadToken, "api-reload-token", apiOptions.ReloadToken, "Bearer token required by POST /reload, empty disables the endpoint")
+	flags.BoolVar(&apiOptions.AccessLog, "api-access-log", apiOptions.AccessLog, "Log every API request, formatted like the stdout logs")
+
+	// Audit flags
+	flags.StringVar(&auditOptions.Dir, "audit-dir", auditOptions.Dir, "Directory to store raw Jira issues of every refresh, empty disables auditing")
+	flags.BoolVar(&auditOptions.Compress, "audit-compress", auditOptions.Compress, "Gzip audit files")
+	flags.BoolVar(&auditOptions.Consolidate, "audit-consolidate", auditOptions.Consolidate, "Write a single NDJSON file per refresh instead of a file per issue")
+	flags.IntVar(&auditOptions.MaxAgeHours, "audit-max-age-hours", auditOptions.MaxAgeHours, "Remove audit files older than this many hours, 0 keeps them")
+	flags.IntVar(&auditOptions.MaxSizeMB, "audit-max-size-mb", auditOptions.MaxSizeMB, "Remove oldest audit files above this total size in MB, 0 is unlimited")
+
+	// Notification flags
+	flags.StringVar(&notifyOptions.WebhookURL, "notify-webhook-url", notifyOptions.WebhookURL, "Slack compatible webhook notified about new incidents at or above the minimum notification severity")
+	flags.IntVar(&notifyOptions.Timeout, "notify-timeout", notifyOptions.Timeout, "Timeout in seconds of a webhook notification")
+	flags.StringVar(&notifyOptions.MinSeverity, "notify-min-severity", notifyOptions.MinSeverity, "Least severe severity notified, empty notifies all incidents at or above the minimum alert severity")
+
+	// OpenSearch flags
+	flags.StringVar(&openSearchOptions.URL, "es-url", openSearchOptions.URL, "OpenSearch or Elasticsearch URL the converted issues are indexed into on every refresh, empty disables")
+	flags.StringVar(&openSearchOptions.Index, "es-index", openSearchOptions.Index, "Index of the converted issues, suffixed with the tenant name for every tenant")
+	flags.StringVar(&openSearchOptions.Username, "es-username", openSearchOptions.Username, "OpenSearch basic auth username")
+	flags.StringVar(&openSearchOptions.Password, "es-password", openSearchOptions.Password, "OpenSearch basic auth password")
+	flags.IntVar(&openSearchOptions.Timeout, "es-timeout", openSearchOptions.Timeout, "Timeout in seconds of an OpenSearch bulk request")
+	flags.IntVar(&openSearchOptions.BatchSize, "es-batch-size", openSearchOptions.BatchSize, "Issues indexed per OpenSearch bulk request")
+
 	// Jira flags
-	flags.StringVar(&jiraOptions.URL, "jira-url", jiraOptions.URL, "Jira server URL")
-	flags.StringVar(&jiraOptions.Username, "jira-username", jiraOptions.Username, "Jira username")
-	flags.StringVar(&jiraOptions.ApiToken, "jira-api-token", jiraOptions.ApiToken, "Jira API token")
-	flags.StringVar(&jiraOptions.ProjectKey, "jira-project-key", jiraOptions.ProjectKey, "Jira project key (default: INCI)")
-	flags.StringVar(&jiraOptions.QueryFilter, "jira-query-filter", jiraOptions.QueryFilter, "Additional JQL filter for Jira queries")
-	flags.IntVar(&jiraOptions.RefreshInterval, "jira-refresh-interval", jiraOptions.RefreshInterval, "Interval in seconds between Jira data refreshes")
+	addJiraFlags(flags, &jiraOptions)
 
 	interceptSyscall()
 
@@ -200,8 +467,186 @@ processes it, and exposes metrics that can be scraped by Prometheus.`,
 		},
 	})
 
-	if err := rootCmd.Execute(); err != nil {
+	rootCmd.AddCommand(newRunCommand())
+	rootCmd.AddCommand(newExportCommand())
+	rootCmd.AddCommand(newBackfillCommand())
+	rootCmd.AddCommand(newValidateCommand())
+	rootCmd.AddCommand(newInspectCommand())
+	rootCmd.AddCommand(newDashboardCommand())
+
+	err := rootCmd.ExecuteContext(rootCtx)
+	stopTracing(time.Duration(rootOptions.ShutdownTimeout) * time.Second)
+	if err != nil {
 		logs.Error(err)
 		os.Exit(1)
 	}
+	return nil
+}
+
+// addJiraFlags binds the Jira flags to the options, their current values are the defaults
+func addJiraFlags(flags *pflag.FlagSet, options *common.JiraOptions) {
+	flags.StringVar(&options.URL, "jira-url", options.URL, "Jira server URL")
+	flags.StringVar(&options.Username, "jira-username", options.Username, "Jira username")
+	flags.StringVar(&options.ApiToken, "jira-api-token", options.ApiToken, "Jira API token, or the personal access token with pat auth")
+	flags.StringVar(&options.Password, "jira-password", options.Password, "Jira password, used by basic auth without an API token")
+	flags.StringVar(&options.AuthMethod, "jira-auth-method", options.AuthMethod, "Jira authentication: basic, pat, oauth, session")
+	flags.StringVar(&options.OAuthClientID, "jira-oauth-client-id", options.OAuthClientID, "OAuth 2.0 client ID")
+	flags.StringVar(&options.OAuthClientSecret, "jira-oauth-client-secret", options.OAuthClientSecret, "OAuth 2.0 client secret")
+	flags.StringVar(&options.OAuthTokenURL, "jira-oauth-token-url", options.OAuthTokenURL, "OAuth 2.0 token endpoint for the client credentials flow")
+	flags.StringSliceVar(&options.OAuthScopes, "jira-oauth-scopes", options.OAuthScopes, "OAuth 2.0 scopes to request")
+	flags.StringVar(&options.ProjectKey, "jira-project-key", options.ProjectKey, "Jira project key(s), comma-separated; empty relies on the query filter only")
+	flags.StringVar(&options.QueryFilter, "jira-query-filter", options.QueryFilter, "Additional JQL filter for Jira queries")
+	flags.StringVar(&options.JQL, "jira-jql", options.JQL, "Custom JQL replacing the generated query, ignoring project key, filters, refresh scope and incremental refresh")
+	flags.StringVar(&options.LookbackJQL, "jira-lookback-jql", options.LookbackJQL, "JQL date or function issues are fetched from, e.g. startOfYear(-2y)")
+	flags.IntVar(&options.LookbackDays, "jira-lookback-days", options.LookbackDays, "Fetch issues created in this many last days, overrides the lookback JQL when set")
+	flags.IntVar(&options.RefreshInterval, "jira-refresh-interval", options.RefreshInterval, "Interval in seconds between Jira data refreshes")
+	flags.IntVar(&options.HTTPTimeout, "jira-http-timeout", options.HTTPTimeout, "Timeout in seconds of a single Jira request")
+	flags.IntVar(&options.ConnectTimeout, "jira-connect-timeout", options.ConnectTimeout, "Timeout in seconds of every attempt of the startup connection test, retried up to the Jira retries")
+	flags.StringVar(&options.Proxy, "jira-proxy", options.Proxy, "Outbound proxy URL for Jira requests, HTTPS_PROXY is used when empty")
+	flags.StringToStringVar(&options.CustomHeaders, "jira-custom-headers", options.CustomHeaders, "Headers added to every Jira request, e.g. for an auth proxy: X-Gateway-Token=...")
+	flags.StringVar(&options.CACertPath, "jira-ca-cert-path", options.CACertPath, "PEM file with CA certificates trusted for Jira in addition to the system ones")
+	flags.BoolVar(&options.InsecureSkipVerify, "jira-insecure-skip-verify", options.InsecureSkipVerify, "Skip TLS certificate verification of Jira, insecure")
+	flags.IntVar(&options.FetchConcurrency, "jira-fetch-concurrency", options.FetchConcurrency, "Search pages fetched in parallel, 1 fetches sequentially")
+	flags.IntVar(&options.ConvertWorkers, "jira-convert-workers", options.ConvertWorkers, "Issues converted in parallel, 0 uses the number of CPUs")
+	flags.Float64Var(&options.RequestsPerSecond, "jira-requests-per-second", options.RequestsPerSecond, "Limit of outbound Jira requests per second, 0 is unlimited")
+	flags.IntVar(&options.RateLimitWarnBelow, "jira-rate-limit-warn-below", options.RateLimitWarnBelow, "Warn when Jira reports fewer requests left in its rate limit window (X-RateLimit-Remaining)")
+	flags.IntVar(&options.MaxResults, "jira-max-results", options.MaxResults, "Issues requested per Jira search page (1-1000)")
+	flags.IntVar(&options.MaxIssues, "jira-max-issues", options.MaxIssues, "Stop paging once that many issues are fetched, 0 is unlimited")
+	flags.IntVar(&options.WorkloadThreshold, "jira-workload-threshold", options.WorkloadThreshold, "Open incidents an assignee needs to exceed for an own workload series, the others are summed as other")
+	flags.StringSliceVar(&options.DateOnlyFields, "jira-date-only-fields", options.DateOnlyFields, "Logical fields or custom field IDs holding date-only values (2006-01-02)")
+	flags.StringSliceVar(&options.TimeFormats, "jira-time-formats", options.TimeFormats, "Go layouts tried in order to parse timestamp fields, the Jira format, RFC3339 and 2006-01-02 when empty")
+	flags.StringVar(&options.Timezone, "jira-timezone", options.Timezone, "Timezone used to interpret date-only fields")
+	flags.StringToStringVar(&options.ServiceMap, "jira-service-map", options.ServiceMap, "Jira component to service mapping: component=service,...")
+	flags.StringVar(&options.ServiceMapFile, "jira-service-map-file", options.ServiceMapFile, "JSON file with Jira component to service mapping")
+	flags.StringSliceVar(&options.DoneFields, "jira-done-fields", options.DoneFields, "Timestamps marking an issue as done, in precedence order: resolved, closed, fixed")
+	flags.StringVar(&options.DoneSelect, "jira-done-select", options.DoneSelect, "How to pick among done timestamps: first, earliest, latest")
+	flags.StringToStringVar(&options.PriorityMap, "jira-priority-map", options.PriorityMap, "Priority to severity mapping used when severity is empty: priority=severity,...")
+	flags.StringVar(&options.CacheFilePath, "jira-cache-file-path", options.CacheFilePath, "JSON file persisting the cached issues to serve them right after a restart")
+	flags.StringVar(&options.RecordDir, "jira-record-dir", options.RecordDir, "Directory to record raw Jira search responses to")
+	flags.StringVar(&options.ReplayDir, "jira-replay-dir", options.ReplayDir, "Directory to replay recorded Jira search responses from instead of calling Jira")
+	flags.StringSliceVar(&options.UserFields, "jira-user-fields", options.UserFields, "User attributes tried in order for assignee, reporter and head: name, key, accountId, displayName")
+	flags.IntVar(&options.MinTotal, "jira-min-total", options.MinTotal, "Readiness fails when the query matches fewer issues, 0 disables")
+	flags.IntVar(&options.MaxTotal, "jira-max-total", options.MaxTotal, "Readiness fails when the query matches more issues, 0 disables")
+	flags.Float64Var(&options.MaxTotalChange, "jira-max-total-change", options.MaxTotalChange, "Readiness fails when the matched total changes by more than this fraction from the last sane refresh, 0 disables")
+	flags.StringVar(&options.RefreshScope, "jira-refresh-scope", options.RefreshScope, "Issues fetched by the scheduled refresh: full, open (export always fetches full history)")
+	flags.BoolVar(&options.OnlyUnresolved, "jira-only-unresolved", options.OnlyUnresolved, "Serve only open issues on the API listings, metrics still cover all issues")
+	flags.BoolVar(&options.OnlyResolved, "jira-only-resolved", options.OnlyResolved, "Serve only done issues on the API listings, metrics still cover all issues")
+	flags.StringSliceVar(&options.SeverityOrder, "jira-severity-order", options.SeverityOrder, "Severities from the most to the least severe")
+	flags.StringVar(&options.MinSeverityForAlerts, "jira-min-severity-for-alerts", options.MinSeverityForAlerts, "Least severe severity still notified and SLA tracked, empty includes all")
+	flags.StringToStringVar(&options.SeverityAliases, "jira-severity-aliases", options.SeverityAliases, "Raw to canonical severity mapping, case-insensitive: Sev 1=SEV1,S1=SEV1,...")
+	flags.StringToIntVar(&options.SeverityWeights, "jira-severity-weights", options.SeverityWeights, "Severity weights multiplied with the business impact into the issue score: severity=weight,...")
+	flags.StringSliceVar(&options.LabelFilter, "jira-label-filter", options.LabelFilter, "Cache only issues carrying all of these labels")
+	flags.StringSliceVar(&options.EnvironmentSources, "jira-environment-sources", options.EnvironmentSources, "Ordered environment sources: customfield ID, label:<prefix>, component:<prefix>")
+	flags.StringToStringVar(&options.EnvironmentSynonyms, "jira-environment-synonyms", options.EnvironmentSynonyms, "Environment synonyms normalized to a canonical value: synonym=canonical,...")
+	flags.StringSliceVar(&options.OnlyEnvironments, "jira-only-environments", options.OnlyEnvironments, "Cache only issues of these environments, e.g. prod")
+	flags.StringToStringVar(&options.FieldMapping, "jira-field-map", options.FieldMapping, "Logical field to custom field ID mapping replacing the defaults: head=customfield_22501,...")
+	flags.StringSliceVar(&options.RequestFields, "jira-request-fields", options.RequestFields, "Fields requested from Jira replacing the standard and mapped ones")
+	flags.IntVar(&options.MaxRetries, "jira-max-retries", options.MaxRetries, "Retries of a Jira search page on server or network errors")
+	flags.IntVar(&options.RetryBackoff, "jira-retry-backoff", options.RetryBackoff, "Initial delay between retries in milliseconds, doubled on every attempt")
+	flags.BoolVar(&options.UseChangelog, "jira-use-changelog", options.UseChangelog, "Derive lifecycle timestamps from status transitions in the issue changelog")
+	flags.BoolVar(&options.IncludeCommentCounts, "jira-include-comment-counts", options.IncludeCommentCounts, "Fetch the comments of every issue to count them, inflating the search payload")
+	flags.BoolVar(&options.IncludeAttachments, "jira-include-attachments", options.IncludeAttachments, "Fetch the attachment metadata of every issue, without the contents")
+	flags.BoolVar(&options.ChangelogFallback, "jira-changelog-fallback", options.ChangelogFallback, "Only fill the lifecycle timestamps left empty by the custom fields from the changelog")
+	flags.StringToStringVar(&options.StatusStageMap, "jira-status-stage-map", options.StatusStageMap, "Status to lifecycle stage mapping for the changelog: In Progress=started,Resolved=resolved,...")
+	flags.BoolVar(&options.IncrementalRefresh, "jira-incremental-refresh", options.IncrementalRefresh, "After the first full load only fetch issues updated since the last refresh")
+	flags.IntVar(&options.CircuitFailures, "jira-circuit-failures", options.CircuitFailures, "Consecutive failed refreshes after which Jira is not called for the cool-down, 0 disables")
+	flags.IntVar(&options.CircuitCooldown, "jira-circuit-cooldown", options.CircuitCooldown, "Seconds refreshes are skipped once the circuit to Jira opened")
+	flags.BoolVar(&options.AcceptPartialRefresh, "jira-accept-partial-refresh", options.AcceptPartialRefresh, "Cache the issues fetched before a search page failed instead of discarding the refresh")
+}
+
+// runService keeps Jira data refreshed until a shutdown signal, then drains the background work
+func runService(cmd *cobra.Command, args []string) {
+	logs.Info("AIM service is running. Press Ctrl+C to exit.")
+
+	// Create observability wrapper
+	obs := common.NewObservability(logs, metrics, tracer)
+
+	clients, err := newJiraClients(obs)
+	if err != nil {
+		logs.Error("Failed to create Jira client: %v", err)
+		os.Exit(1)
+	}
+
+	ctx := rootCtx
+
+	// Test the connections
+	for _, jiraClient := range clients.Clients() {
+		if err := jiraClient.TestConnection(ctx); err != nil {
+			logs.Error("Failed to connect to Jira %s: %v", jiraClient.Tenant(), err)
+			// Continue anyway, might be a temporary issue
+		}
+	}
+
+	// Serve cached data over HTTP
+	if apiOptions.Listen != "" {
+		apiOptions.AccessLogFormat = stdoutOptions.Format
+		apiServer := common.NewApiServer(apiOptions, clients, obs)
+		apiServer.StartInWaitGroup(&mainWG)
+		onShutdown("api server", apiServer.Shutdown)
+	}
+
+	for _, jiraClient := range clients.Clients() {
+		// Serve the issues of the previous run until the first refresh completes
+		jiraClient.LoadCacheFile()
+		onShutdown(strings.TrimSpace("jira data "+jiraClient.Tenant()), jiraClient.Flush)
+
+		// Start the data refresh loop
+		jiraClient.StartRefreshLoop(ctx, refreshCtx, &mainWG)
+
+		if rootOptions.HeartbeatInterval > 0 {
+			jiraClient.StartHeartbeatLoop(ctx, &mainWG, time.Duration(rootOptions.HeartbeatInterval)*time.Second)
+		}
+	}
+	logs.Info("Jira data collection started for %d Jira instances", len(clients.Clients()))
+
+	// Keep the app running until a shutdown signal
+	<-ctx.Done()
+	shutdown(time.Duration(rootOptions.ShutdownTimeout) * time.Second)
+}
+
+// runOnce fetches and converts issues a single time, within the created window when set, and writes them as JSON
+func runOnce(ctx context.Context, w io.Writer, window CreatedWindow) error {
+	if _, _, err := window.Parse(time.UTC); err != nil {
+		return err
+	}
+
+	obs := common.NewObservability(logs, metrics, tracer)
+	jiraClient, err := common.NewJiraClient(jiraOptions, obs, metrics)
+	if err != nil {
+		return err
+	}
+
+	customIssues, err := fetchIssues(ctx, jiraClient, window)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(customIssues)
+}
+
+func newRunCommand() *cobra.Command {
+	var once bool
+	var window CreatedWindow
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the service, or fetch issues a single time with --once",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if once {
+				return runOnce(cmd.Context(), os.Stdout, window)
+			}
+			if window.Enabled() {
+				return fmt.Errorf("--since and --until require --once")
+			}
+			runService(cmd, args)
+			return nil
+		},
+	}
+
+	runCmd.Flags().BoolVar(&once, "once", false, "Fetch and print issues as JSON once and exit, without the refresh loop and API")
+	runCmd.Flags().StringVar(&window.Since, "since", "", "With --once, fetch issues created from this date instead of the lookback")
+	runCmd.Flags().StringVar(&window.Until, "until", "", "With --once, fetch issues created up to this date, a date alone includes the whole day")
+	return runCmd
 }
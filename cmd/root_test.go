@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"aim/common"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdownRunsHooksWithinTimeout(t *testing.T) {
+	saved := shutdownHooks
+	defer func() { shutdownHooks = saved }()
+	shutdownHooks = nil
+
+	var flushed atomic.Int32
+	onShutdown("cache", func(ctx context.Context) error {
+		flushed.Add(1)
+		return nil
+	})
+	onShutdown("slow sink", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	timeout := 50 * time.Millisecond
+	started := time.Now()
+	shutdown(timeout)
+	elapsed := time.Since(started)
+
+	if flushed.Load() != 1 {
+		t.Errorf("cache hook ran %d times, want 1", flushed.Load())
+	}
+	if elapsed < timeout || elapsed > timeout+time.Second {
+		t.Errorf("shutdown took %s with a %s timeout", elapsed, timeout)
+	}
+}
+
+func TestStopMetricsWaitsForEndpoint(t *testing.T) {
+	metricsWG.Add(1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		metricsWG.Done()
+	}()
+	if err := stopMetrics(context.Background()); err != nil {
+		t.Errorf("stopMetrics() = %v, want nil once the endpoint is closed", err)
+	}
+
+	metricsWG.Add(1)
+	defer metricsWG.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := stopMetrics(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("stopMetrics() = %v, want the deadline error while the endpoint serves", err)
+	}
+}
+
+// newJiraStub serves the Jira search and current user APIs with the issues given as JSON objects
+func newJiraStub(t *testing.T, issues []map[string]interface{}) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		startAt, _ := strconv.Atoi(r.URL.Query().Get("startAt"))
+		page := issues[min(startAt, len(issues)):]
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issues": page, "startAt": startAt, "maxResults": len(page), "total": len(issues),
+		})
+	})
+	mux.HandleFunc("/rest/api/2/myself", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "aim"})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// stubJiraOptions points the Jira options at the stub for the duration of the test
+func stubJiraOptions(t *testing.T, url string) {
+	t.Helper()
+	saved := jiraOptions
+	t.Cleanup(func() { jiraOptions = saved })
+	jiraOptions.URL = url
+	jiraOptions.Username = "aim"
+	jiraOptions.ApiToken = "token"
+	jiraOptions.ProjectKey = "INCI"
+	jiraOptions.RetryBackoff = 1
+}
+
+func TestRunOnce(t *testing.T) {
+	server := newJiraStub(t, []map[string]interface{}{
+		{"id": "2", "key": "INCI-2", "fields": map[string]interface{}{"summary": "second", "created": "2024-03-02T10:00:00.000+0000"}},
+		{"id": "1", "key": "INCI-1", "fields": map[string]interface{}{"summary": "first", "created": "2024-03-01T10:00:00.000+0000"}},
+	})
+	stubJiraOptions(t, server.URL)
+
+	var out bytes.Buffer
+	if err := runOnce(context.Background(), &out, CreatedWindow{}); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+	var issues []common.JiraIssue
+	if err := json.Unmarshal(out.Bytes(), &issues); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if len(issues) != 2 || issues[0].Key != "INCI-2" || issues[1].Summary != "first" {
+		t.Errorf("runOnce printed %+v", issues)
+	}
+}
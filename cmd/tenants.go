@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"aim/common"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// tenantName keeps tenant names usable in environment variable names and metric labels
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tenantEnvName returns the environment variable overriding a Jira flag for the tenant, e.g. AIM_PROD_JIRA_URL
+func tenantEnvName(tenant, flag string) string {
+	tenant = strings.ToUpper(strings.ReplaceAll(tenant, "-", "_"))
+	return fmt.Sprintf("%s_%s_%s", APPNAME, tenant, strings.TrimPrefix(envName(flag), APPNAME+"_"))
+}
+
+// tenantJiraOptions returns the shared Jira options overridden by the environment variables of the tenant
+func tenantJiraOptions(tenant string, base common.JiraOptions) (common.JiraOptions, error) {
+	options := base
+	options.Tenant = tenant
+
+	flags := pflag.NewFlagSet(tenant, pflag.ContinueOnError)
+	addJiraFlags(flags, &options)
+
+	var errs []error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		name := tenantEnvName(tenant, flag.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := flag.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s: %w", name, err))
+		}
+	})
+	return options, errors.Join(errs...)
+}
+
+// configuredTenants returns the tenant names, or a single unnamed tenant without tenants
+func configuredTenants() []string {
+	tenants := make([]string, 0, len(rootOptions.Tenants))
+	for _, tenant := range rootOptions.Tenants {
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			tenants = append(tenants, tenant)
+		}
+	}
+	if len(tenants) == 0 {
+		tenants = []string{""}
+	}
+	return tenants
+}
+
+// validateJiraConfig checks that the Jira URL and the credentials of the auth method are set
+func validateJiraConfig(options common.JiraOptions) error {
+	var errs []error
+	if strings.TrimSpace(options.URL) == "" {
+		errs = append(errs, errors.New("Jira URL is not configured"))
+	}
+
+	var credentials bool
+	switch options.AuthMethod {
+	case "", "basic":
+		credentials = options.Username != "" && (options.ApiToken != "" || options.Password != "")
+	case "pat":
+		credentials = options.ApiToken != ""
+	case "session":
+		credentials = options.Username != "" && (options.Password != "" || options.ApiToken != "")
+	case "oauth":
+		credentials = options.OAuthClientID != "" && options.OAuthClientSecret != "" && options.OAuthTokenURL != ""
+	default:
+		// Unknown methods are reported by the client
+		credentials = true
+	}
+	if !credentials {
+		errs = append(errs, errors.New("Jira credentials are not configured"))
+	}
+	return errors.Join(errs...)
+}
+
+// checkJiraConfig validates the Jira configuration of every tenant
+func checkJiraConfig() error {
+	var errs []error
+	for _, tenant := range configuredTenants() {
+		options := jiraOptions
+		if tenant != "" {
+			var err error
+			if options, err = tenantJiraOptions(tenant, jiraOptions); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := validateJiraConfig(options); err != nil {
+			if tenant != "" {
+				err = fmt.Errorf("tenant %s: %w", tenant, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// newJiraClients creates a Jira client for every configured tenant, or a single one without tenants
+func newJiraClients(obs *common.Observability) (*common.ClientRegistry, error) {
+	tenants := configuredTenants()
+
+	registry := common.NewClientRegistry()
+	cacheFiles := make(map[string]string)
+	for _, tenant := range tenants {
+		options := jiraOptions
+		if tenant != "" {
+			if !tenantName.MatchString(tenant) {
+				return nil, fmt.Errorf("invalid tenant name %q, use letters, digits, - and _", tenant)
+			}
+			var err error
+			if options, err = tenantJiraOptions(tenant, jiraOptions); err != nil {
+				return nil, err
+			}
+		}
+
+		// Tenants sharing a cache file would overwrite each other's issues
+		if path := options.CacheFilePath; path != "" {
+			if other, ok := cacheFiles[path]; ok {
+				return nil, fmt.Errorf("tenants %s and %s use the same cache file %s", other, tenant, path)
+			}
+			cacheFiles[path] = tenant
+		}
+
+		logs.Debug("Jira options: %s", options.String())
+		client, err := common.NewJiraClient(options, obs, metrics)
+		if err != nil {
+			if tenant != "" {
+				return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+			}
+			return nil, err
+		}
+
+		if auditOptions.Dir != "" {
+			audit := auditOptions
+			if tenant != "" {
+				audit.Dir = filepath.Join(audit.Dir, tenant)
+				audit.Tenant = tenant
+			}
+			writer, err := common.NewAuditWriter(audit, obs.WithTenant(tenant), metrics)
+			if err != nil {
+				return nil, err
+			}
+			client.SetAuditWriter(writer)
+		}
+
+		if notifyOptions.WebhookURL != "" {
+			if err := client.SetNotifier(common.NewNotifier(notifyOptions, obs.WithTenant(tenant))); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := addOpenSearchSink(client, tenant, obs); err != nil {
+			return nil, err
+		}
+
+		if err := registry.Register(client); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// addOpenSearchSink indexes the issues of the client into OpenSearch when configured, every tenant into
+// its own index
+func addOpenSearchSink(client *common.JiraClient, tenant string, obs *common.Observability) error {
+	if openSearchOptions.URL == "" {
+		return nil
+	}
+	sinkOptions := openSearchOptions
+	if tenant != "" {
+		sinkOptions.Index = strings.ToLower(sinkOptions.Index + "-" + tenant)
+		sinkOptions.Tenant = tenant
+	}
+	sink, err := common.NewOpenSearchSink(sinkOptions, obs.WithTenant(tenant), metrics)
+	if err != nil {
+		return err
+	}
+	client.AddSink(sink)
+	return nil
+}
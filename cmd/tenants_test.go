@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"aim/common"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTenantEnvName(t *testing.T) {
+	tests := []struct {
+		tenant, flag, want string
+	}{
+		{tenant: "prod", flag: "jira-url", want: "AIM_PROD_JIRA_URL"},
+		{tenant: "eu-west", flag: "jira-api-token", want: "AIM_EU_WEST_JIRA_API_TOKEN"},
+	}
+	for _, tt := range tests {
+		if got := tenantEnvName(tt.tenant, tt.flag); got != tt.want {
+			t.Errorf("tenantEnvName(%q, %q) = %q, want %q", tt.tenant, tt.flag, got, tt.want)
+		}
+	}
+}
+
+func TestTenantJiraOptions(t *testing.T) {
+	base := common.JiraOptions{
+		URL:           "https://jira.example.com",
+		ProjectKey:    "INCI",
+		SeverityOrder: []string{"SEV1", "SEV2"},
+		ServiceMap:    map[string]string{"API": "api"},
+	}
+	t.Setenv("AIM_EU_JIRA_URL", "https://jira.eu.example.com")
+	t.Setenv("AIM_EU_JIRA_SEVERITY_ORDER", "P1,P2,P3")
+	t.Setenv("AIM_EU_JIRA_MAX_RESULTS", "50")
+
+	options, err := tenantJiraOptions("eu", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Tenant != "eu" || options.URL != "https://jira.eu.example.com" || options.MaxResults != 50 {
+		t.Errorf("tenant options not overridden: %+v", options)
+	}
+	if !reflect.DeepEqual(options.SeverityOrder, []string{"P1", "P2", "P3"}) {
+		t.Errorf("severity order = %v", options.SeverityOrder)
+	}
+	if options.ProjectKey != "INCI" || !reflect.DeepEqual(options.ServiceMap, base.ServiceMap) {
+		t.Errorf("shared options not inherited: %+v", options)
+	}
+	if base.URL != "https://jira.example.com" || len(base.SeverityOrder) != 2 {
+		t.Errorf("shared options modified: %+v", base)
+	}
+
+	t.Setenv("AIM_BAD_JIRA_MAX_RESULTS", "many")
+	if _, err := tenantJiraOptions("bad", base); err == nil {
+		t.Error("invalid tenant value accepted")
+	}
+}
+
+func TestValidateJiraConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		options common.JiraOptions
+		want    []string
+	}{
+		{name: "basic with token", options: common.JiraOptions{URL: "https://jira", Username: "aim", ApiToken: "token"}},
+		{name: "basic with password", options: common.JiraOptions{URL: "https://jira", AuthMethod: "basic", Username: "aim", Password: "secret"}},
+		{name: "pat", options: common.JiraOptions{URL: "https://jira", AuthMethod: "pat", ApiToken: "token"}},
+		{name: "session", options: common.JiraOptions{URL: "https://jira", AuthMethod: "session", Username: "aim", Password: "secret"}},
+		{name: "session without password", options: common.JiraOptions{URL: "https://jira", AuthMethod: "session", Username: "aim"}, want: []string{"Jira credentials are not configured"}},
+		{name: "oauth", options: common.JiraOptions{URL: "https://jira", AuthMethod: "oauth", OAuthClientID: "id", OAuthClientSecret: "secret", OAuthTokenURL: "https://auth/token"}},
+		{name: "nothing", want: []string{"Jira URL is not configured", "Jira credentials are not configured"}},
+		{name: "basic without token", options: common.JiraOptions{URL: "https://jira", Username: "aim"}, want: []string{"Jira credentials are not configured"}},
+		{name: "pat without token", options: common.JiraOptions{URL: " ", AuthMethod: "pat", Username: "aim", Password: "secret"}, want: []string{"Jira URL is not configured", "Jira credentials are not configured"}},
+		{name: "oauth without secret", options: common.JiraOptions{URL: "https://jira", AuthMethod: "oauth", OAuthClientID: "id", OAuthTokenURL: "https://auth/token"}, want: []string{"Jira credentials are not configured"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJiraConfig(tt.options)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("validateJiraConfig() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateJiraConfig() = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validateJiraConfig() = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckJiraConfigTenants(t *testing.T) {
+	savedRoot, savedJira := rootOptions, jiraOptions
+	t.Cleanup(func() { rootOptions, jiraOptions = savedRoot, savedJira })
+	rootOptions.Tenants = []string{"eu", "us"}
+	jiraOptions = common.JiraOptions{Username: "aim", ApiToken: "token"}
+
+	// The shared options have no URL, the tenants set their own
+	t.Setenv("AIM_EU_JIRA_URL", "https://jira.eu.example.com")
+	t.Setenv("AIM_US_JIRA_URL", "https://jira.us.example.com")
+	if err := checkJiraConfig(); err != nil {
+		t.Errorf("checkJiraConfig() = %v, want nil", err)
+	}
+
+	t.Setenv("AIM_US_JIRA_URL", "")
+	err := checkJiraConfig()
+	if err == nil || !strings.Contains(err.Error(), "tenant us: Jira URL is not configured") || strings.Contains(err.Error(), "tenant eu") {
+		t.Errorf("checkJiraConfig() = %v, want the missing URL of tenant us", err)
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type TracingOptions struct {
+	Endpoint    string
+	ServiceName string
+	Headers     map[string]string
+}
+
+// newTracerProvider returns a tracer provider batching spans to the OTLP/HTTP endpoint
+func newTracerProvider(ctx context.Context, options TracingOptions) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(options.Endpoint),
+		otlptracehttp.WithHeaders(options.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", options.ServiceName),
+			attribute.String("service.version", version),
+		)),
+	), nil
+}
+
+// stopTracing exports the spans still batched, within the timeout
+func stopTracing(timeout time.Duration) {
+	if tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		logs.Warn("Failed to export pending spans: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"aim/common"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func newValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration against Jira and print a report",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			failed := 0
+			report := func(check string, err error) {
+				if err != nil {
+					failed++
+					fmt.Fprintf(out, "FAIL %s: %v\n", check, err)
+					return
+				}
+				fmt.Fprintf(out, "OK   %s\n", check)
+			}
+
+			obs := common.NewObservability(logs, metrics, tracer)
+			var jiraClient *common.JiraClient
+			err := validateJiraConfig(jiraOptions)
+			if err == nil {
+				jiraClient, err = common.NewJiraClient(jiraOptions, obs, metrics)
+			}
+			report("configuration", err)
+			if err != nil {
+				return fmt.Errorf("validation failed")
+			}
+
+			ctx := cmd.Context()
+			report("connection and credentials", jiraClient.TestConnection(ctx))
+
+			missing, err := jiraClient.MissingFields(ctx)
+			if err == nil && len(missing) > 0 {
+				names := make([]string, 0, len(missing))
+				for name := range missing {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+
+				var unknown []string
+				for _, name := range names {
+					unknown = append(unknown, fmt.Sprintf("%s=%s", name, strings.Join(missing[name], "|")))
+				}
+				err = fmt.Errorf("unknown custom fields %s", strings.Join(unknown, ", "))
+			}
+			report("custom fields", err)
+
+			report("jql", jiraClient.ValidateJQL(ctx))
+
+			if failed > 0 {
+				return fmt.Errorf("validation failed: %d checks failed", failed)
+			}
+			return nil
+		},
+	}
+}
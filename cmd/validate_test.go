@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newValidateStub serves the Jira APIs used by validate, listing the given field IDs and answering
+// searches with the status
+func newValidateStub(t *testing.T, fields []string, searchStatus int) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/myself", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "aim"})
+	})
+	mux.HandleFunc("/rest/api/2/field", func(w http.ResponseWriter, r *http.Request) {
+		list := make([]map[string]interface{}, 0, len(fields))
+		for _, id := range fields {
+			list = append(list, map[string]interface{}{"id": id, "name": id, "custom": true})
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("/rest/api/2/search", func(w http.ResponseWriter, r *http.Request) {
+		if searchStatus != http.StatusOK {
+			w.WriteHeader(searchStatus)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errorMessages": []string{"Error in the JQL Query"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"issues": []interface{}{}, "total": 0})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestValidateCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       []string
+		searchStatus int
+		wantErr      bool
+		want         []string
+	}{
+		{
+			name:         "all checks pass",
+			fields:       []string{"customfield_1", "customfield_2"},
+			searchStatus: http.StatusOK,
+			want:         []string{"OK   configuration", "OK   connection and credentials", "OK   custom fields", "OK   jql"},
+		},
+		{
+			name:         "unknown field and invalid jql",
+			fields:       []string{"customfield_1"},
+			searchStatus: http.StatusBadRequest,
+			wantErr:      true,
+			want:         []string{"OK   connection and credentials", "FAIL custom fields: unknown custom fields service=customfield_2", "FAIL jql: invalid jql"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newValidateStub(t, tt.fields, tt.searchStatus)
+			stubJiraOptions(t, server.URL)
+			jiraOptions.FieldMapping = map[string]string{"severity": "customfield_1", "service": "customfield_2"}
+			jiraOptions.MaxRetries = 0
+
+			var out bytes.Buffer
+			cmd := newValidateCommand()
+			cmd.SetOut(&out)
+			cmd.SetArgs(nil)
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate error = %v, wantErr %v\n%s", err, tt.wantErr, out.String())
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("report misses %q:\n%s", line, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCommandInvalidConfiguration(t *testing.T) {
+	stubJiraOptions(t, "https://jira.example.com")
+	jiraOptions.MaxResults = -1
+
+	var out bytes.Buffer
+	cmd := newValidateCommand()
+	cmd.SetOut(&out)
+	cmd.SetArgs(nil)
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	if !strings.HasPrefix(out.String(), "FAIL configuration") {
+		t.Errorf("report = %q, want the configuration failure", out.String())
+	}
+}
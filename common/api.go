@@ -0,0 +1,499 @@
+package common
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ApiOptions holds HTTP API server settings
+type ApiOptions struct {
+	Listen   string
+	BasePath string
+	// ReadyStaleness is the age in seconds of the last refresh after which readiness fails, 0 disables
+	ReadyStaleness int
+	// ReloadToken is the bearer token required by POST /reload, the endpoint is disabled when empty
+	ReloadToken string
+	// AccessLog logs every request, as a JSON object when AccessLogFormat is json and as key=value pairs otherwise
+	AccessLog       bool
+	AccessLogFormat string
+}
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+	// defaultRecurrenceLimit is the number of recurring service and root cause pairs listed by default
+	defaultRecurrenceLimit = 10
+)
+
+// issuesPage is a page of the filtered issues along with their total count
+type issuesPage struct {
+	Total int          `json:"total"`
+	Items []*JiraIssue `json:"items"`
+}
+
+// ApiServer exposes the cached Jira data over HTTP, the tenant query parameter selects the Jira instance
+// when several are polled
+type ApiServer struct {
+	options ApiOptions
+	clients *ClientRegistry
+	obs     *Observability
+	server  *http.Server
+	// logAccess writes an access log line, replaced by tests
+	logAccess func(line string)
+}
+
+func NewApiServer(options ApiOptions, clients *ClientRegistry, obs *Observability) *ApiServer {
+	return &ApiServer{
+		options:   options,
+		clients:   clients,
+		obs:       obs,
+		logAccess: func(line string) { obs.Info("%s", line) },
+	}
+}
+
+// Handler returns the API routes under the configured base path
+func (a *ApiServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(a.route("GET", "/issues"), a.issuesHandler)
+	mux.HandleFunc(a.route("GET", "/issues.csv"), a.issuesCSVHandler)
+	mux.HandleFunc(a.route("GET", "/issues/{key}"), a.issueHandler)
+	mux.HandleFunc(a.route("GET", "/issues/{key}/timeline"), a.timelineHandler)
+	mux.HandleFunc(a.route("GET", "/durations"), a.durationsHandler)
+	mux.HandleFunc(a.route("GET", "/dimensions"), a.dimensionsHandler)
+	mux.HandleFunc(a.route("GET", "/recurrence"), a.recurrenceHandler)
+	mux.HandleFunc(a.route("GET", "/readyz"), a.readyHandler)
+	mux.HandleFunc(a.route("GET", "/healthz"), a.healthHandler)
+	if a.options.ReloadToken != "" {
+		mux.HandleFunc(a.route("POST", "/reload"), a.reloadHandler)
+	}
+	if a.options.AccessLog {
+		return a.accessLog(mux)
+	}
+	return mux
+}
+
+// accessEntry is the access log line of a request
+type accessEntry struct {
+	Method   string  `json:"method"`
+	Path     string  `json:"path"`
+	Status   int     `json:"status"`
+	Duration float64 `json:"duration"`
+	Remote   string  `json:"remote"`
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// accessLog logs the method, path, status, duration in seconds and remote address of every request
+func (a *ApiServer) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		entry := accessEntry{
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Status:   rec.status,
+			Duration: time.Since(started).Seconds(),
+			Remote:   r.RemoteAddr,
+		}
+		if a.options.AccessLogFormat != "json" {
+			a.logAccess(fmt.Sprintf("method=%s path=%q status=%d duration=%.6f remote=%s", entry.Method, entry.Path, entry.Status, entry.Duration, entry.Remote))
+			return
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			a.obs.Error("Failed to encode access log entry: %v", err)
+			return
+		}
+		a.logAccess(string(line))
+	})
+}
+
+// StartInWaitGroup starts serving the API in background
+func (a *ApiServer) StartInWaitGroup(wg *sync.WaitGroup) {
+	a.server = &http.Server{
+		Addr:    a.options.Listen,
+		Handler: a.Handler(),
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		a.obs.Info("API server listening on %s%s", a.options.Listen, a.basePath())
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.obs.Error("API server failed: %v", err)
+		}
+	}()
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests until the context ends
+func (a *ApiServer) Shutdown(ctx context.Context) error {
+	if a.server == nil {
+		return nil
+	}
+	return a.server.Shutdown(ctx)
+}
+
+// basePath returns the normalized route prefix, empty or starting with a slash and without a trailing one
+func (a *ApiServer) basePath() string {
+	base := strings.Trim(a.options.BasePath, "/")
+	if base == "" {
+		return ""
+	}
+	return "/" + base
+}
+
+// route builds a mux pattern for the path under the configured base path
+func (a *ApiServer) route(method, path string) string {
+	return fmt.Sprintf("%s %s%s", method, a.basePath(), path)
+}
+
+// issuesHandler serves a page of the cached issues, optionally filtered by project, severity and service
+func (a *ApiServer) issuesHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	if jira.GetLastRefreshTime().IsZero() {
+		http.Error(w, "issues are not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit, err := queryInt(r, "limit", defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit), http.StatusBadRequest)
+		return
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "offset must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	severity := r.URL.Query().Get("severity")
+	service := r.URL.Query().Get("service")
+
+	cached := jira.GetCachedIssues()
+	if project := r.URL.Query().Get("project"); project != "" {
+		cached = jira.GetCachedProjectIssues(project)
+	}
+	cached, err = filterResolution(r, jira, cached)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	issues := make([]*JiraIssue, 0)
+	for _, issue := range cached {
+		if severity != "" && issue.Severity != severity {
+			continue
+		}
+		if service != "" && issue.Service != service {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	start := min(offset, len(issues))
+	end := start + min(limit, len(issues)-start)
+	a.writeJSON(w, http.StatusOK, issuesPage{Total: len(issues), Items: issues[start:end]})
+}
+
+// issuesCSVHandler serves all cached issues as CSV
+func (a *ApiServer) issuesCSVHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	if jira.GetLastRefreshTime().IsZero() {
+		http.Error(w, "issues are not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	issues, err := filterResolution(r, jira, jira.GetCachedIssues())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="issues.csv"`)
+	if err := WriteIssuesCSV(w, issues); err != nil {
+		a.obs.Error("Failed to write CSV response: %v", err)
+	}
+}
+
+// resolutionQuery reads the resolved query parameter, defaulting to the resolution filter of the client.
+// byResolution is false when issues are served regardless of their resolution.
+func resolutionQuery(r *http.Request, jira *JiraClient) (resolved, byResolution bool, err error) {
+	value := r.URL.Query().Get("resolved")
+	if value == "" {
+		resolved, byResolution = jira.ResolutionFilter()
+		return resolved, byResolution, nil
+	}
+	if resolved, err = strconv.ParseBool(value); err != nil {
+		return false, false, fmt.Errorf("resolved must be true or false")
+	}
+	return resolved, true, nil
+}
+
+// filterResolution narrows the issues to the resolution selected by the request, an issue is resolved
+// once done like in the metrics
+func filterResolution(r *http.Request, jira *JiraClient, issues []*JiraIssue) ([]*JiraIssue, error) {
+	resolved, byResolution, err := resolutionQuery(r, jira)
+	if err != nil || !byResolution {
+		return issues, err
+	}
+	return slices.DeleteFunc(slices.Clone(issues), func(issue *JiraIssue) bool {
+		return issue.IsOpen() == resolved
+	}), nil
+}
+
+// queryInt reads an integer query parameter, the default applies when it is missing
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// issueHandler serves a single cached issue without calling Jira
+func (a *ApiServer) issueHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	issue, ok := jira.GetCachedIssue(r.PathValue("key"))
+	if !ok {
+		http.Error(w, "issue not found", http.StatusNotFound)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, issue)
+}
+
+// timelineHandler serves the ordered lifecycle events of a cached issue
+func (a *ApiServer) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	issue, ok := jira.GetCachedIssue(r.PathValue("key"))
+	if !ok {
+		http.Error(w, "issue not found", http.StatusNotFound)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, issue.Timeline())
+}
+
+// durationsHandler serves the lifecycle stage durations of the cached issues
+func (a *ApiServer) durationsHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	if jira.GetLastRefreshTime().IsZero() {
+		http.Error(w, "issues are not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	cached, err := filterResolution(r, jira, jira.GetCachedIssues())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	durations := make([]IssueDurations, 0, len(cached))
+	for _, issue := range cached {
+		stages := issue.StageDurations()
+		for _, stage := range stages {
+			if stage.Clamped {
+				a.obs.Warn("Issue %s has a negative %s duration, reported as zero", issue.Key, stage.Stage)
+			}
+		}
+		durations = append(durations, IssueDurations{Key: issue.Key, Stages: stages})
+	}
+
+	a.writeJSON(w, http.StatusOK, durations)
+}
+
+// dimensionsHandler serves the distinct services, severities, statuses and assignees of the cached issues
+func (a *ApiServer) dimensionsHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	if jira.GetLastRefreshTime().IsZero() {
+		http.Error(w, "issues are not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	issues, err := filterResolution(r, jira, jira.GetCachedIssues())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.writeJSON(w, http.StatusOK, IssueDimensions(issues))
+}
+
+// recurrenceHandler serves the service and root cause pairs shared by more than one cached incident, the
+// most recurring first
+func (a *ApiServer) recurrenceHandler(w http.ResponseWriter, r *http.Request) {
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	if jira.GetLastRefreshTime().IsZero() {
+		http.Error(w, "issues are not loaded yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit, err := queryInt(r, "limit", defaultRecurrenceLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit), http.StatusBadRequest)
+		return
+	}
+
+	issues, err := filterResolution(r, jira, jira.GetCachedIssues())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recurring := make([]Recurrence, 0, limit)
+	for _, group := range IncidentRecurrence(issues) {
+		if group.Count < 2 || len(recurring) == limit {
+			break
+		}
+		recurring = append(recurring, group)
+	}
+	a.writeJSON(w, http.StatusOK, recurring)
+}
+
+// readyHandler reports 503 until data is refreshed and the matched total looks sane,
+// and again once the last refresh is older than the staleness threshold
+func (a *ApiServer) readyHandler(w http.ResponseWriter, r *http.Request) {
+	clients := a.clients.Clients()
+	if r.URL.Query().Has("tenant") {
+		jira, ok := a.client(w, r)
+		if !ok {
+			return
+		}
+		clients = []*JiraClient{jira}
+	}
+
+	for _, jira := range clients {
+		if err := a.ready(jira); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Write([]byte("ok"))
+}
+
+// ready checks the data of a tenant is loaded, sane and fresh
+func (a *ApiServer) ready(jira *JiraClient) error {
+	prefix := ""
+	if tenant := jira.Tenant(); tenant != "" {
+		prefix = tenant + ": "
+	}
+	if !jira.Ready() {
+		return fmt.Errorf("%snot ready", prefix)
+	}
+	if a.options.ReadyStaleness > 0 {
+		staleness := time.Duration(a.options.ReadyStaleness) * time.Second
+		if age := time.Since(jira.GetLastRefreshTime()); age > staleness {
+			return fmt.Errorf("%slast refresh is stale (%s ago)", prefix, age.Truncate(time.Second))
+		}
+	}
+	return nil
+}
+
+// healthHandler only confirms the process is up and serving
+func (a *ApiServer) healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+// reloadResult is the outcome of an on demand refresh
+type reloadResult struct {
+	Issues      int       `json:"issues"`
+	LastRefresh time.Time `json:"last_refresh"`
+}
+
+// reloadHandler refreshes the data immediately, overlapping reloads share a single refresh
+func (a *ApiServer) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.options.ReloadToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	jira, ok := a.client(w, r)
+	if !ok {
+		return
+	}
+
+	count, err := jira.Reload(r.Context())
+	if errors.Is(err, errRefreshStopped) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		a.obs.Error("Reload failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	a.writeJSON(w, http.StatusOK, reloadResult{Issues: count, LastRefresh: jira.GetLastRefreshTime()})
+}
+
+// client returns the Jira client selected by the tenant query parameter, or writes the error response
+func (a *ApiServer) client(w http.ResponseWriter, r *http.Request) (*JiraClient, bool) {
+	jira, err := a.clients.Get(r.URL.Query().Get("tenant"))
+	if errors.Is(err, ErrUnknownTenant) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return jira, true
+}
+
+// writeJSON encodes the value as a JSON response
+func (a *ApiServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.obs.Error("Failed to write API response: %v", err)
+	}
+}
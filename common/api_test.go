@@ -0,0 +1,590 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// newTestApi serves the clients of the tenants, refreshed once from their fake searchers
+func newTestApi(t *testing.T, options ApiOptions, tenants map[string][]jira.Issue) (*ApiServer, *ClientRegistry) {
+	t.Helper()
+	registry := NewClientRegistry()
+	for tenant, issues := range tenants {
+		jiraOptions := testOptions()
+		jiraOptions.Tenant = tenant
+		client := newTestClient(t, jiraOptions, &pageSearcher{issues: issues})
+		client.RefreshData(context.Background())
+		if err := registry.Register(client); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewApiServer(options, registry, NewObservability(nil, nil, nil)), registry
+}
+
+func TestApiTenantSelector(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{
+		"alpha": {testIssue("ALPHA-1", created, nil)},
+		"beta":  {testIssue("BETA-1", created, nil), testIssue("BETA-2", created, nil)},
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		total  int
+	}{
+		{name: "alpha issues", path: "/issues?tenant=alpha", status: http.StatusOK, total: 1},
+		{name: "beta issues", path: "/issues?tenant=beta", status: http.StatusOK, total: 2},
+		{name: "missing tenant", path: "/issues", status: http.StatusBadRequest},
+		{name: "unknown tenant", path: "/issues?tenant=gamma", status: http.StatusNotFound},
+		{name: "timeline of another tenant", path: "/issues/ALPHA-1/timeline?tenant=beta", status: http.StatusNotFound},
+		{name: "timeline", path: "/issues/ALPHA-1/timeline?tenant=alpha", status: http.StatusOK},
+		{name: "readiness of all tenants", path: "/readyz", status: http.StatusOK},
+		{name: "readiness of unknown tenant", path: "/readyz?tenant=gamma", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			api.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("GET %s = %d, want %d: %s", tt.path, rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.total == 0 {
+				return
+			}
+			var page issuesPage
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+			if page.Total != tt.total {
+				t.Errorf("GET %s total = %d, want %d", tt.path, page.Total, tt.total)
+			}
+		})
+	}
+}
+
+// severityIssue builds an issue with the severity and service in the default custom fields
+func severityIssue(key, severity, service string) jira.Issue {
+	return testIssue(key, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), map[string]interface{}{
+		"customfield_18119": map[string]interface{}{"value": severity},
+		"customfield_33803": service,
+	})
+}
+
+// getJSON serves a GET request and decodes the JSON response on the expected status
+func getJSON(t *testing.T, handler http.Handler, path string, status int, v interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != status {
+		t.Fatalf("GET %s = %d, want %d: %s", path, rec.Code, status, rec.Body.String())
+	}
+	if v != nil && status == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestApiIssuesFilters(t *testing.T) {
+	api, _ := newTestApi(t, ApiOptions{BasePath: "/aim/"}, map[string][]jira.Issue{"": {
+		severityIssue("INCI-1", "SEV1", "api"),
+		severityIssue("INCI-2", "SEV2", "api"),
+		severityIssue("INCI-3", "SEV1", "web"),
+		severityIssue("OPS-1", "SEV1", "api"),
+	}})
+
+	tests := []struct {
+		name  string
+		path  string
+		total int
+	}{
+		{"all", "/aim/issues", 4},
+		{"severity", "/aim/issues?severity=SEV1", 3},
+		{"service", "/aim/issues?service=api", 3},
+		{"severity and service", "/aim/issues?severity=SEV1&service=api", 2},
+		{"project", "/aim/issues?project=OPS", 1},
+		{"unknown project", "/aim/issues?project=NONE", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page issuesPage
+			getJSON(t, api.Handler(), tt.path, http.StatusOK, &page)
+			if page.Total != tt.total || len(page.Items) != tt.total {
+				t.Errorf("GET %s = %d (%d items), want %d", tt.path, page.Total, len(page.Items), tt.total)
+			}
+		})
+	}
+
+	getJSON(t, api.Handler(), "/issues", http.StatusNotFound, nil)
+}
+
+func TestApiIssuesNotLoaded(t *testing.T) {
+	registry := NewClientRegistry()
+	if err := registry.Register(newTestClient(t, testOptions(), &pageSearcher{})); err != nil {
+		t.Fatal(err)
+	}
+	api := NewApiServer(ApiOptions{}, registry, NewObservability(nil, nil, nil))
+	for _, path := range []string{"/issues", "/issues.csv", "/durations"} {
+		getJSON(t, api.Handler(), path, http.StatusServiceUnavailable, nil)
+	}
+}
+
+func TestApiReadiness(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   func(*JiraOptions)
+		refresh   bool
+		staleness int
+		age       time.Duration
+		status    int
+	}{
+		{name: "not refreshed yet", status: http.StatusServiceUnavailable},
+		{name: "refreshed", refresh: true, status: http.StatusOK},
+		{name: "matched total below minimum", options: func(o *JiraOptions) { o.MinTotal = 10 }, refresh: true, status: http.StatusServiceUnavailable},
+		{name: "fresh enough", refresh: true, staleness: 60, age: 30 * time.Second, status: http.StatusOK},
+		{name: "stale", refresh: true, staleness: 60, age: 2 * time.Minute, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			if tt.options != nil {
+				tt.options(&options)
+			}
+			client := newTestClient(t, options, &pageSearcher{issues: testIssues(2)})
+			if tt.refresh {
+				client.RefreshData(context.Background())
+			}
+			if tt.age > 0 {
+				client.mu.Lock()
+				client.lastRefresh = time.Now().Add(-tt.age)
+				client.mu.Unlock()
+			}
+
+			registry := NewClientRegistry()
+			if err := registry.Register(client); err != nil {
+				t.Fatal(err)
+			}
+			api := NewApiServer(ApiOptions{ReadyStaleness: tt.staleness}, registry, NewObservability(nil, nil, nil))
+			getJSON(t, api.Handler(), "/readyz", tt.status, nil)
+			getJSON(t, api.Handler(), "/healthz", http.StatusOK, nil)
+		})
+	}
+}
+
+func TestApiIssuesPagination(t *testing.T) {
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{"": testIssues(5)})
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		keys   []string
+	}{
+		{name: "default page", query: "", status: http.StatusOK, keys: []string{"INCI-5", "INCI-4", "INCI-3", "INCI-2", "INCI-1"}},
+		{name: "first page", query: "?limit=2", status: http.StatusOK, keys: []string{"INCI-5", "INCI-4"}},
+		{name: "second page", query: "?limit=2&offset=2", status: http.StatusOK, keys: []string{"INCI-3", "INCI-2"}},
+		{name: "last partial page", query: "?limit=2&offset=4", status: http.StatusOK, keys: []string{"INCI-1"}},
+		{name: "past the end", query: "?offset=10", status: http.StatusOK, keys: []string{}},
+		{name: "zero limit", query: "?limit=0", status: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=1001", status: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", status: http.StatusBadRequest},
+		{name: "invalid limit", query: "?limit=ten", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page issuesPage
+			getJSON(t, api.Handler(), "/issues"+tt.query, tt.status, &page)
+			if tt.status != http.StatusOK {
+				return
+			}
+			keys := make([]string, 0, len(page.Items))
+			for _, issue := range page.Items {
+				keys = append(keys, issue.Key)
+			}
+			if page.Total != 5 || !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("total %d, keys %v, want 5 and %v", page.Total, keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestApiDurations(t *testing.T) {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	complete := testIssue("INCI-1", created, map[string]interface{}{
+		"customfield_20911": "2024-03-01T10:05:00.000+0000",
+		"customfield_18117": "2024-03-01T10:15:00.000+0000",
+		"customfield_20908": "2024-03-01T12:15:00.000+0000",
+	})
+	complete.Fields.Resolutiondate = jira.Time(created.Add(75 * time.Minute))
+	// Detected before created is clamped
+	inverted := testIssue("INCI-2", created, map[string]interface{}{
+		"customfield_20911": "2024-03-01T09:00:00.000+0000",
+	})
+
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{"": {complete, inverted}})
+	var durations []IssueDurations
+	getJSON(t, api.Handler(), "/durations", http.StatusOK, &durations)
+
+	want := map[string][]StageDuration{
+		"INCI-1": {
+			{Stage: "created_to_detected", Seconds: 300},
+			{Stage: "detected_to_started", Seconds: 600},
+			{Stage: "started_to_resolved", Seconds: 3600},
+			{Stage: "resolved_to_closed", Seconds: 3600},
+		},
+		"INCI-2": {{Stage: "created_to_detected", Seconds: 0, Clamped: true}},
+	}
+	if len(durations) != len(want) {
+		t.Fatalf("durations of %d issues, want %d", len(durations), len(want))
+	}
+	for _, d := range durations {
+		if !reflect.DeepEqual(d.Stages, want[d.Key]) {
+			t.Errorf("%s stages = %+v, want %+v", d.Key, d.Stages, want[d.Key])
+		}
+	}
+}
+
+func TestApiIssueByKey(t *testing.T) {
+	var jiraCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jiraCalls.Add(1)
+		http.Error(w, "unexpected call", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	client := newTestClient(t, options, &pageSearcher{issues: []jira.Issue{severityIssue("INCI-1", "SEV1", "api"), severityIssue("INCI-2", "SEV2", "web")}})
+	client.RefreshData(context.Background())
+	registry := NewClientRegistry()
+	if err := registry.Register(client); err != nil {
+		t.Fatal(err)
+	}
+	api := NewApiServer(ApiOptions{}, registry, NewObservability(nil, nil, nil)).Handler()
+
+	var issue JiraIssue
+	getJSON(t, api, "/issues/INCI-2", http.StatusOK, &issue)
+	if issue.Key != "INCI-2" || issue.Severity != "SEV2" || issue.Service != "web" {
+		t.Errorf("GET /issues/INCI-2 = %+v", issue)
+	}
+	getJSON(t, api, "/issues/INCI-9", http.StatusNotFound, nil)
+
+	if calls := jiraCalls.Load(); calls != 0 {
+		t.Errorf("%d calls to Jira, want the cache only", calls)
+	}
+}
+
+func TestApiDimensions(t *testing.T) {
+	issues := []jira.Issue{
+		severityIssue("INCI-1", "SEV2", "web"),
+		severityIssue("INCI-2", "SEV1", "api"),
+		severityIssue("INCI-3", "SEV1", "api"),
+		severityIssue("INCI-4", "", ""),
+	}
+	issues[0].Fields.Assignee = &jira.User{Name: "bob"}
+	issues[1].Fields.Assignee = &jira.User{Name: "alice"}
+	issues[2].Fields.Assignee = &jira.User{Name: "bob"}
+	issues[3].Fields.Status = &jira.Status{Name: "Closed"}
+
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{"": issues})
+	var dimensions Dimensions
+	getJSON(t, api.Handler(), "/dimensions", http.StatusOK, &dimensions)
+
+	want := Dimensions{
+		Services:   []string{"api", "web"},
+		Severities: []string{"SEV1", "SEV2"},
+		Statuses:   []string{"Closed", "Open"},
+		Assignees:  []string{"alice", "bob"},
+	}
+	if !reflect.DeepEqual(dimensions, want) {
+		t.Errorf("dimensions = %+v, want %+v", dimensions, want)
+	}
+}
+
+func TestApiDimensionsEmpty(t *testing.T) {
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{"": nil})
+	var dimensions Dimensions
+	getJSON(t, api.Handler(), "/dimensions", http.StatusOK, &dimensions)
+
+	if dimensions.Services == nil || len(dimensions.Services) != 0 {
+		t.Errorf("services = %#v, want an empty list", dimensions.Services)
+	}
+}
+
+func rootCauseIssue(key, service, rootCause string) jira.Issue {
+	return testIssue(key, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), map[string]interface{}{
+		"customfield_33803": service,
+		"customfield_37238": rootCause,
+	})
+}
+
+func TestApiRecurrence(t *testing.T) {
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{"": {
+		rootCauseIssue("INCI-7", "api", "deploy"),
+		rootCauseIssue("INCI-6", "web", "capacity"),
+		rootCauseIssue("INCI-5", "api", "deploy"),
+		rootCauseIssue("INCI-4", "db", ""),
+		rootCauseIssue("INCI-3", "web", "capacity"),
+		rootCauseIssue("INCI-2", "api", "deploy"),
+		rootCauseIssue("INCI-1", "db", ""),
+	}})
+
+	tests := []struct {
+		path string
+		want []Recurrence
+	}{
+		{"/recurrence", []Recurrence{
+			{Service: "api", RootCause: "deploy", Count: 3, Keys: []string{"INCI-2", "INCI-5", "INCI-7"}},
+			{Service: "db", RootCause: "unknown", Count: 2, Keys: []string{"INCI-1", "INCI-4"}},
+			{Service: "web", RootCause: "capacity", Count: 2, Keys: []string{"INCI-3", "INCI-6"}},
+		}},
+		{"/recurrence?limit=1", []Recurrence{
+			{Service: "api", RootCause: "deploy", Count: 3, Keys: []string{"INCI-2", "INCI-5", "INCI-7"}},
+		}},
+	}
+	for _, tt := range tests {
+		var got []Recurrence
+		getJSON(t, api.Handler(), tt.path, http.StatusOK, &got)
+		for _, group := range got {
+			slices.Sort(group.Keys)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GET %s = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+	getJSON(t, api.Handler(), "/recurrence?limit=0", http.StatusBadRequest, nil)
+}
+
+func TestApiAccessLog(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		parse  func(t *testing.T, line string) accessEntry
+	}{
+		{format: "json", parse: func(t *testing.T, line string) accessEntry {
+			var entry accessEntry
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("access log %q is not JSON: %v", line, err)
+			}
+			return entry
+		}},
+		{format: "text", parse: func(t *testing.T, line string) accessEntry {
+			var entry accessEntry
+			if _, err := fmt.Sscanf(line, "method=%s path=%q status=%d duration=%f remote=%s", &entry.Method, &entry.Path, &entry.Status, &entry.Duration, &entry.Remote); err != nil {
+				t.Fatalf("access log %q is not key=value pairs: %v", line, err)
+			}
+			return entry
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			api, _ := newTestApi(t, ApiOptions{AccessLog: true, AccessLogFormat: tt.format}, map[string][]jira.Issue{"": {testIssue("INCI-1", created, nil)}})
+			var lines []string
+			api.logAccess = func(line string) { lines = append(lines, line) }
+			handler := api.Handler()
+
+			requests := []struct {
+				path   string
+				status int
+			}{
+				{"/issues", http.StatusOK},
+				{"/healthz", http.StatusOK},
+				{"/issues/INCI-9/timeline", http.StatusNotFound},
+			}
+			for _, request := range requests {
+				req := httptest.NewRequest(http.MethodGet, request.path, nil)
+				req.RemoteAddr = "192.0.2.1:4321"
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			if len(lines) != len(requests) {
+				t.Fatalf("%d access log lines for %d requests: %q", len(lines), len(requests), lines)
+			}
+			for n, request := range requests {
+				entry := tt.parse(t, lines[n])
+				if entry.Method != http.MethodGet || entry.Path != request.path || entry.Status != request.status || entry.Remote != "192.0.2.1:4321" || entry.Duration < 0 {
+					t.Errorf("access log entry %+v, want GET %s %d from 192.0.2.1:4321", entry, request.path, request.status)
+				}
+			}
+		})
+	}
+}
+
+func TestApiReload(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		header string
+		status int
+	}{
+		{name: "reload", method: http.MethodPost, token: "secret", header: "Bearer secret", status: http.StatusOK},
+		{name: "missing token", method: http.MethodPost, token: "secret", status: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, token: "secret", header: "Bearer guess", status: http.StatusUnauthorized},
+		{name: "not a bearer token", method: http.MethodPost, token: "secret", header: "secret", status: http.StatusUnauthorized},
+		{name: "get", method: http.MethodGet, token: "secret", header: "Bearer secret", status: http.StatusMethodNotAllowed},
+		{name: "disabled", method: http.MethodPost, header: "Bearer ", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewClientRegistry()
+			client := newTestClient(t, testOptions(), &pageSearcher{issues: []jira.Issue{testIssue("INCI-1", created, nil)}})
+			if err := registry.Register(client); err != nil {
+				t.Fatal(err)
+			}
+			api := NewApiServer(ApiOptions{ReloadToken: tt.token}, registry, NewObservability(nil, nil, nil))
+
+			req := httptest.NewRequest(tt.method, "/reload", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			api.Handler().ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("%s /reload = %d, want %d: %s", tt.method, rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				if !client.GetLastRefreshTime().IsZero() {
+					t.Error("rejected reload refreshed the data")
+				}
+				return
+			}
+
+			var result reloadResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Issues != 1 || result.LastRefresh.IsZero() || !result.LastRefresh.Equal(client.GetLastRefreshTime()) {
+				t.Errorf("reload result = %+v, want 1 issue refreshed at %s", result, client.GetLastRefreshTime())
+			}
+		})
+	}
+}
+
+func TestApiResolutionFilter(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	resolvedIssue := func(key string) jira.Issue {
+		issue := testIssue(key, created, nil)
+		issue.Fields.Resolutiondate = jira.Time(created.Add(time.Hour))
+		return issue
+	}
+	issues := []jira.Issue{testIssue("INCI-1", created, nil), resolvedIssue("INCI-2"), resolvedIssue("INCI-3")}
+
+	tests := []struct {
+		name    string
+		options func(*JiraOptions)
+		query   string
+		status  int
+		want    []string
+	}{
+		{name: "all issues", status: http.StatusOK, want: []string{"INCI-1", "INCI-2", "INCI-3"}},
+		{name: "only unresolved", options: func(o *JiraOptions) { o.OnlyUnresolved = true }, status: http.StatusOK, want: []string{"INCI-1"}},
+		{name: "only resolved", options: func(o *JiraOptions) { o.OnlyResolved = true }, status: http.StatusOK, want: []string{"INCI-2", "INCI-3"}},
+		{name: "unresolved query", query: "?resolved=false", status: http.StatusOK, want: []string{"INCI-1"}},
+		{name: "query overrides the option", options: func(o *JiraOptions) { o.OnlyUnresolved = true }, query: "?resolved=true", status: http.StatusOK, want: []string{"INCI-2", "INCI-3"}},
+		{name: "invalid query", query: "?resolved=maybe", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			if tt.options != nil {
+				tt.options(&options)
+			}
+			client := newTestClient(t, options, &pageSearcher{issues: issues})
+			client.RefreshData(context.Background())
+			registry := NewClientRegistry()
+			if err := registry.Register(client); err != nil {
+				t.Fatal(err)
+			}
+			api := NewApiServer(ApiOptions{}, registry, NewObservability(nil, nil, nil))
+
+			var page issuesPage
+			getJSON(t, api.Handler(), "/issues"+tt.query, tt.status, &page)
+			if tt.status != http.StatusOK {
+				return
+			}
+			var keys []string
+			for _, issue := range page.Items {
+				keys = append(keys, issue.Key)
+			}
+			slices.Sort(keys)
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Errorf("/issues%s = %v, want %v", tt.query, keys, tt.want)
+			}
+
+			rec := httptest.NewRecorder()
+			api.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/issues.csv"+tt.query, nil))
+			if rows := strings.Count(rec.Body.String(), "\n") - 1; rows != len(tt.want) {
+				t.Errorf("/issues.csv%s has %d rows, want %d", tt.query, rows, len(tt.want))
+			}
+
+			// The cache keeps every issue for the metrics
+			if got := len(client.GetCachedIssues()); got != len(issues) {
+				t.Errorf("cached %d issues, want %d", got, len(issues))
+			}
+		})
+	}
+}
+
+func TestApiResolutionFollowsDone(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	// Closed without a resolution date, done for the metrics like for the API
+	closed := testIssue("INCI-2", created, map[string]interface{}{"customfield_33803": "web", "customfield_20908": "2024-03-01T12:15:00.000+0000"})
+	issues := []jira.Issue{testIssue("INCI-1", created, map[string]interface{}{"customfield_33803": "api"}), closed}
+
+	options := testOptions()
+	options.DoneFields = []string{"resolved", "closed"}
+	client := newTestClient(t, options, &pageSearcher{issues: issues})
+	client.RefreshData(context.Background())
+	registry := NewClientRegistry()
+	if err := registry.Register(client); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewApiServer(ApiOptions{}, registry, NewObservability(nil, nil, nil)).Handler()
+
+	for query, want := range map[string]string{"?resolved=true": "INCI-2", "?resolved=false": "INCI-1"} {
+		var page issuesPage
+		getJSON(t, handler, "/issues"+query, http.StatusOK, &page)
+		if len(page.Items) != 1 || page.Items[0].Key != want {
+			t.Errorf("/issues%s = %+v, want %s", query, page.Items, want)
+		}
+
+		var durations []IssueDurations
+		getJSON(t, handler, "/durations"+query, http.StatusOK, &durations)
+		if len(durations) != 1 || durations[0].Key != want {
+			t.Errorf("/durations%s = %+v, want %s", query, durations, want)
+		}
+	}
+
+	var dimensions Dimensions
+	getJSON(t, handler, "/dimensions?resolved=true", http.StatusOK, &dimensions)
+	if !reflect.DeepEqual(dimensions.Services, []string{"web"}) {
+		t.Errorf("/dimensions?resolved=true services = %v, want [web]", dimensions.Services)
+	}
+	for _, path := range []string{"/durations", "/dimensions", "/recurrence"} {
+		getJSON(t, handler, path+"?resolved=maybe", http.StatusBadRequest, nil)
+	}
+}
+
+func TestResolutionFiltersExcludeEachOther(t *testing.T) {
+	options := testOptions()
+	options.OnlyUnresolved = true
+	options.OnlyResolved = true
+	if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+		t.Error("both resolution filters accepted")
+	}
+}
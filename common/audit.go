@@ -0,0 +1,196 @@
+package common
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	sre "github.com/devopsext/sre/common"
+)
+
+// AuditOptions holds settings for storing raw Jira issues on disk
+type AuditOptions struct {
+	Dir         string
+	Compress    bool
+	Consolidate bool
+	MaxAgeHours int
+	MaxSizeMB   int
+	// Tenant labels the audit metrics when several Jira instances are polled
+	Tenant string
+}
+
+// AuditWriter stores raw Jira issues of every refresh for later inspection
+type AuditWriter struct {
+	options AuditOptions
+	obs     *Observability
+	metrics *sre.Metrics
+	// busy is held while writing, so that Flush can wait for the files of a refresh to be complete
+	busy chan struct{}
+}
+
+type auditFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func NewAuditWriter(options AuditOptions, obs *Observability, metrics *sre.Metrics) (*AuditWriter, error) {
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating audit directory: %w", err)
+	}
+
+	return &AuditWriter{
+		options: options,
+		obs:     obs,
+		metrics: metrics,
+		busy:    make(chan struct{}, 1),
+	}, nil
+}
+
+// Write stores the issues either as one file per issue or as a single NDJSON file per refresh,
+// then prunes files exceeding the retention policy
+func (a *AuditWriter) Write(issues []*jira.Issue) error {
+	a.busy <- struct{}{}
+	defer func() { <-a.busy }()
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	written := 0
+
+	if a.options.Consolidate {
+		err := a.writeFile(filepath.Join(a.options.Dir, stamp+".ndjson"), func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			for _, issue := range issues {
+				if err := encoder.Encode(issue); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		written++
+	} else {
+		dir := filepath.Join(a.options.Dir, stamp)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("error creating audit directory: %w", err)
+		}
+		for _, issue := range issues {
+			err := a.writeFile(filepath.Join(dir, issue.Key+".json"), func(w io.Writer) error {
+				return json.NewEncoder(w).Encode(issue)
+			})
+			if err != nil {
+				return err
+			}
+			written++
+		}
+	}
+
+	if a.metrics != nil {
+		a.metrics.Counter(metricsGroup, "audit_files_written_total", "Count of audit files written", tenantLabels(a.options.Tenant, nil)).Add(written)
+	}
+	a.obs.Debug("Wrote %d audit files for %d issues", written, len(issues))
+
+	return a.prune()
+}
+
+// Flush waits for the write in progress, if any, so that no half written audit file is left behind
+func (a *AuditWriter) Flush(ctx context.Context) error {
+	select {
+	case a.busy <- struct{}{}:
+		<-a.busy
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit files of the current refresh not complete: %w", ctx.Err())
+	}
+}
+
+// writeFile creates the file, gzip compressed when enabled, and fills it with the writer function
+func (a *AuditWriter) writeFile(path string, write func(w io.Writer) error) error {
+	if a.options.Compress {
+		path += ".gz"
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating audit file: %w", err)
+	}
+	defer f.Close()
+
+	if !a.options.Compress {
+		return write(f)
+	}
+
+	gz := gzip.NewWriter(f)
+	if err := write(gz); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+// prune removes audit files older than the max age, then the oldest ones until the total size fits
+func (a *AuditWriter) prune() error {
+	var files []auditFile
+	err := filepath.WalkDir(a.options.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, auditFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error scanning audit directory: %w", err)
+	}
+
+	sort.Slice(files, func(i, k int) bool {
+		return files[i].modTime.Before(files[k].modTime)
+	})
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+
+	maxAge := time.Duration(a.options.MaxAgeHours) * time.Hour
+	maxSize := int64(a.options.MaxSizeMB) * 1024 * 1024
+	removed := 0
+	for _, file := range files {
+		expired := maxAge > 0 && time.Since(file.modTime) > maxAge
+		oversized := maxSize > 0 && total > maxSize
+		if !expired && !oversized {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil {
+			a.obs.Warn("Failed to remove audit file %s: %v", file.path, err)
+			continue
+		}
+		total -= file.size
+		removed++
+
+		// Drop per-refresh directories once emptied, fails harmlessly otherwise
+		if dir := filepath.Dir(file.path); dir != filepath.Clean(a.options.Dir) {
+			os.Remove(dir)
+		}
+	}
+
+	if removed > 0 {
+		a.obs.Info("Pruned %d audit files", removed)
+	}
+	if a.metrics != nil {
+		cachedGauge(a.metrics, "audit_disk_usage_bytes", "Current disk usage of audit files", tenantLabels(a.options.Tenant, nil)).Set(float64(total))
+	}
+	return nil
+}
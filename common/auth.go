@@ -0,0 +1,241 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/andygrunwald/go-jira"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// baseTransport returns the transport sending Jira requests, through the proxy and with the TLS settings when set
+func baseTransport(options JiraOptions) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.CACertPath != "" || options.InsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+		if options.CACertPath != "" {
+			pem, err := os.ReadFile(options.CACertPath)
+			if err != nil {
+				return nil, fmt.Errorf("error reading ca certificate: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", options.CACertPath)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if options.Proxy == "" {
+		return transport, nil
+	}
+
+	proxyURL, err := url.Parse(options.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url %q: %w", options.Proxy, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy url %q, expected an http, https or socks5 scheme", options.Proxy)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url %q, missing host", options.Proxy)
+	}
+
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return transport, nil
+}
+
+// authTransport builds the round tripper authenticating Jira requests with the configured method
+func authTransport(options JiraOptions, base http.RoundTripper) (http.RoundTripper, error) {
+	switch options.AuthMethod {
+	case "", "basic":
+		// API tokens replace passwords on Jira Cloud, the password is kept for server installations
+		password := options.ApiToken
+		if password == "" {
+			password = options.Password
+		}
+		if options.Username == "" || password == "" {
+			return nil, fmt.Errorf("basic auth requires a username and an api token or password")
+		}
+		return &jira.BasicAuthTransport{
+			Username:  options.Username,
+			Password:  password,
+			Transport: base,
+		}, nil
+	case "pat":
+		if options.ApiToken == "" {
+			return nil, fmt.Errorf("pat auth requires a personal access token in the api token")
+		}
+		return &jira.PATAuthTransport{
+			Token:     options.ApiToken,
+			Transport: base,
+		}, nil
+	case "oauth":
+		if options.OAuthClientID == "" || options.OAuthClientSecret == "" || options.OAuthTokenURL == "" {
+			return nil, fmt.Errorf("oauth auth requires a client id, a client secret and a token url")
+		}
+		var scopes []string
+		for _, scope := range options.OAuthScopes {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+		config := &clientcredentials.Config{
+			ClientID:     options.OAuthClientID,
+			ClientSecret: options.OAuthClientSecret,
+			TokenURL:     options.OAuthTokenURL,
+			Scopes:       scopes,
+		}
+		// Token requests go through the same base transport as Jira requests
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: base})
+		return &oauth2.Transport{
+			Source: config.TokenSource(ctx),
+			Base:   base,
+		}, nil
+	case "session":
+		password := options.Password
+		if password == "" {
+			password = options.ApiToken
+		}
+		if options.Username == "" || password == "" {
+			return nil, fmt.Errorf("session auth requires a username and a password")
+		}
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		return &sessionTransport{
+			loginURL: strings.TrimRight(options.URL, "/") + "/rest/auth/1/session",
+			username: options.Username,
+			password: password,
+			jar:      jar,
+			base:     base,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid auth method %q, expected basic, pat, oauth or session", options.AuthMethod)
+	}
+}
+
+// sessionTransport authenticates Jira requests with the session cookie of a username and password login,
+// for servers allowing neither basic auth nor personal access tokens. The session is opened on the first
+// request and opened again when Jira answers 401 because it expired.
+type sessionTransport struct {
+	loginURL string
+	username string
+	password string
+	jar      http.CookieJar
+	base     http.RoundTripper
+
+	mu sync.Mutex
+	// session counts the logins, a 401 only triggers a login when no other request opened a newer session
+	session int
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	session, err := t.ensureSession(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// A consumed body can't be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if err := t.login(req.Context(), session); err != nil {
+		return nil, err
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.send(retry)
+}
+
+// ensureSession logs in when no session was opened yet and returns the current session
+func (t *sessionTransport) ensureSession(ctx context.Context) (int, error) {
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session > 0 {
+		return session, nil
+	}
+	if err := t.login(ctx, 0); err != nil {
+		return 0, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.session, nil
+}
+
+// login opens a new session unless one newer than stale was opened meanwhile
+func (t *sessionTransport) login(ctx context.Context, stale int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.session != stale {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{"username": t.username, "password": t.password})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.loginURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating session login request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.send(req)
+	if err != nil {
+		return fmt.Errorf("error logging in to Jira: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jira session login responded with status %d", resp.StatusCode)
+	}
+	t.session++
+	return nil
+}
+
+// send adds the session cookies to a copy of the request and keeps the cookies Jira sets
+func (t *sessionTransport) send(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	for _, cookie := range t.jar.Cookies(req.URL) {
+		out.AddCookie(cookie)
+	}
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		t.jar.SetCookies(req.URL, cookies)
+	}
+	return resp, nil
+}
@@ -0,0 +1,274 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAuthTransport(t *testing.T) {
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+			http.Error(w, "bad client", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"issued","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokens.Close()
+
+	basic := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+
+	tests := []struct {
+		name    string
+		options JiraOptions
+		want    string
+		wantErr bool
+	}{
+		{name: "basic with api token", options: JiraOptions{Username: "aim", ApiToken: "token", Password: "password"}, want: basic("aim", "token")},
+		{name: "basic with password", options: JiraOptions{AuthMethod: "basic", Username: "aim", Password: "password"}, want: basic("aim", "password")},
+		{name: "basic without username", options: JiraOptions{ApiToken: "token"}, wantErr: true},
+		{name: "personal access token", options: JiraOptions{AuthMethod: "pat", ApiToken: "pat-token"}, want: "Bearer pat-token"},
+		{name: "pat without token", options: JiraOptions{AuthMethod: "pat"}, wantErr: true},
+		{
+			name:    "oauth client credentials",
+			options: JiraOptions{AuthMethod: "oauth", OAuthClientID: "client", OAuthClientSecret: "secret", OAuthTokenURL: tokens.URL},
+			want:    "Bearer issued",
+		},
+		{name: "oauth without token url", options: JiraOptions{AuthMethod: "oauth", OAuthClientID: "client", OAuthClientSecret: "secret"}, wantErr: true},
+		{name: "unknown method", options: JiraOptions{AuthMethod: "kerberos"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			jira := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+			}))
+			defer jira.Close()
+
+			transport, err := authTransport(tt.options, http.DefaultTransport)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authTransport() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(jira.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseTransportProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	transport, err := baseTransport(JiraOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://jira.internal.example.com/rest/api/2/myself")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if proxied != "http://jira.internal.example.com/rest/api/2/myself" {
+		t.Errorf("proxy received %q", proxied)
+	}
+}
+
+func TestBaseTransportInvalidProxy(t *testing.T) {
+	for _, proxy := range []string{"ftp://proxy.example.com", "http://", "://bad"} {
+		if _, err := baseTransport(JiraOptions{Proxy: proxy}); err == nil {
+			t.Errorf("proxy %q accepted", proxy)
+		}
+	}
+}
+
+func TestBaseTransportTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, pemData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyPath := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(emptyPath, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		options    JiraOptions
+		wantErr    bool
+		wantReqErr bool
+	}{
+		{name: "system roots reject the server", wantReqErr: true},
+		{name: "custom ca", options: JiraOptions{CACertPath: caPath}},
+		{name: "insecure skip verify", options: JiraOptions{InsecureSkipVerify: true}},
+		{name: "missing ca file", options: JiraOptions{CACertPath: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: true},
+		{name: "ca file without certificates", options: JiraOptions{CACertPath: emptyPath}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := baseTransport(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("baseTransport() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+			if (err != nil) != tt.wantReqErr {
+				t.Fatalf("request error = %v, want error %v", err, tt.wantReqErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+func TestCustomHeaders(t *testing.T) {
+	var gateway, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gateway = r.Header.Get("X-Gateway-Token")
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"name":"aim"}`))
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	options.CustomHeaders = map[string]string{"X-Gateway-Token": "gateway-secret", "Authorization": "Bearer proxy"}
+	client := newTestClient(t, options, nil)
+
+	if err := client.TestConnection(context.Background()); err != nil {
+		t.Fatalf("TestConnection: %v", err)
+	}
+	if gateway != "gateway-secret" {
+		t.Errorf("X-Gateway-Token = %q, want the custom header", gateway)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("aim:token")); authorization != want {
+		t.Errorf("Authorization = %q, want the basic auth %q kept", authorization, want)
+	}
+}
+
+// sessionJira is a Jira server only accepting requests carrying the cookie of a session login
+type sessionJira struct {
+	mu       sync.Mutex
+	logins   int
+	searches int
+	session  string
+}
+
+func (s *sessionJira) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/rest/auth/1/session" {
+		var credentials struct{ Username, Password string }
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&credentials) != nil ||
+			credentials.Username != "aim" || credentials.Password != "secret" {
+			http.Error(w, "login failed", http.StatusUnauthorized)
+			return
+		}
+		s.logins++
+		s.session = fmt.Sprintf("session-%d", s.logins)
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: s.session, Path: "/"})
+		fmt.Fprintf(w, `{"session":{"name":"JSESSIONID","value":%q}}`, s.session)
+		return
+	}
+
+	if cookie, err := r.Cookie("JSESSIONID"); err != nil || s.session == "" || cookie.Value != s.session {
+		http.Error(w, "session expired", http.StatusUnauthorized)
+		return
+	}
+	s.searches++
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"startAt":0,"maxResults":50,"total":1,"issues":[{"id":"1","key":"INCI-1","fields":{"summary":"Incident INCI-1","created":"2024-03-01T00:00:00.000+0000"}}]}`))
+}
+
+// expire drops the session as Jira does after its timeout
+func (s *sessionJira) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = ""
+}
+
+func TestSessionAuth(t *testing.T) {
+	server := &sessionJira{}
+	jira := httptest.NewServer(server)
+	defer jira.Close()
+
+	options := testOptions()
+	options.URL = jira.URL
+	options.AuthMethod = "session"
+	options.ApiToken = ""
+	options.Password = "secret"
+	client := newTestClient(t, options, nil)
+
+	client.RefreshData(context.Background())
+	if got := len(client.GetCachedIssues()); got != 1 {
+		t.Fatalf("%d issues cached after the first refresh, want 1", got)
+	}
+	if server.logins != 1 {
+		t.Errorf("%d logins for the first refresh, want 1", server.logins)
+	}
+
+	client.RefreshData(context.Background())
+	if server.logins != 1 {
+		t.Errorf("%d logins after a refresh with an open session, want 1", server.logins)
+	}
+
+	server.expire()
+	searches := server.searches
+	client.RefreshData(context.Background())
+	if server.logins != 2 {
+		t.Errorf("%d logins after the session expired, want 2", server.logins)
+	}
+	if server.searches <= searches {
+		t.Errorf("no search after logging in again")
+	}
+}
+
+func TestSessionAuthLoginFailure(t *testing.T) {
+	jira := httptest.NewServer(&sessionJira{})
+	defer jira.Close()
+
+	transport, err := authTransport(JiraOptions{URL: jira.URL, AuthMethod: "session", Username: "aim", Password: "wrong"}, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&http.Client{Transport: transport}).Get(jira.URL + "/rest/api/2/myself"); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("request with a rejected login = %v, want the login status", err)
+	}
+
+	if _, err := authTransport(JiraOptions{URL: jira.URL, AuthMethod: "session", Username: "aim"}, http.DefaultTransport); err == nil {
+		t.Error("session auth without a password accepted")
+	}
+}
@@ -0,0 +1,218 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// BackfillOptions holds settings for the first load of the whole incident history
+type BackfillOptions struct {
+	// From is the creation date issues are loaded from
+	From time.Time
+	// CheckpointPath is the file the progress is saved to after every page, an interrupted backfill
+	// resumes from it. The fetched issues are spooled to the same path with an .ndjson extension
+	CheckpointPath string
+	// PageDelay spaces the page requests on top of the rate limit of the client, to spare Jira
+	PageDelay time.Duration
+}
+
+// backfillCheckpoint is the progress of a backfill: its query, the offset of the next page and the size
+// of the spool holding the issues fetched so far
+type backfillCheckpoint struct {
+	JQL     string `json:"jql"`
+	StartAt int    `json:"start_at"`
+	Total   int    `json:"total"`
+	Spooled int64  `json:"spooled"`
+}
+
+// Backfill loads the issues created since From page by page in creation order. Every page is appended
+// to a spool next to the checkpoint, which is saved after it, and a backfill of the same query resumes
+// from the checkpoint. Once every page is fetched the spooled issues are written to the sinks like a
+// refresh would, and the checkpoint and the spool are removed.
+func (j *JiraClient) Backfill(ctx context.Context, options BackfillOptions) ([]*JiraIssue, error) {
+	if options.From.IsZero() {
+		return nil, fmt.Errorf("backfill requires a start date")
+	}
+	if options.CheckpointPath == "" {
+		return nil, fmt.Errorf("backfill requires a checkpoint file")
+	}
+	if strings.TrimSpace(j.options.JQL) != "" {
+		return nil, fmt.Errorf("backfill can't be combined with a custom jql")
+	}
+
+	obs := j.obs.WithContext(ctx)
+	path := options.CheckpointPath
+	spoolPath := backfillSpoolPath(path)
+
+	// Ascending creation order keeps the offsets of the fetched pages while new incidents are created
+	created := fmt.Sprintf(`created >= "%s"`, options.From.Format(jqlTimeLayout))
+	jql := j.scopedJQL([]string{created}, false, time.Time{}) + " ORDER BY created ASC, key ASC"
+
+	checkpoint, err := loadCheckpoint(path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case checkpoint == nil:
+		checkpoint = &backfillCheckpoint{JQL: jql}
+		obs.Info("Starting backfill with JQL: %s", jql)
+	case checkpoint.JQL != jql:
+		return nil, fmt.Errorf("checkpoint %s belongs to the query %q, remove it to start another backfill", path, checkpoint.JQL)
+	default:
+		obs.Info("Resuming backfill at %d of %d issues from checkpoint %s", checkpoint.StartAt, checkpoint.Total, path)
+	}
+
+	// Issues appended after the last checkpoint were not counted in it, they are fetched again
+	spool, err := os.OpenFile(spoolPath, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening backfill spool: %w", err)
+	}
+	defer spool.Close()
+	if err := spool.Truncate(checkpoint.Spooled); err != nil {
+		return nil, fmt.Errorf("error truncating backfill spool %s: %w", spoolPath, err)
+	}
+	if _, err := spool.Seek(checkpoint.Spooled, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("error truncating backfill spool %s: %w", spoolPath, err)
+	}
+
+	pageSize := j.options.MaxResults
+	if pageSize == 0 {
+		pageSize = defaultMaxResults
+	}
+
+	for {
+		issues, total, err := j.searchPage(ctx, obs, jql, checkpoint.StartAt, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("backfill stopped at %d, run it again to resume from %s: %w", checkpoint.StartAt, path, err)
+		}
+		converted, err := j.ConvertToCustomIssues(issues)
+		if err != nil {
+			return nil, err
+		}
+
+		written, err := appendSpool(spool, converted)
+		if err != nil {
+			return nil, fmt.Errorf("error writing backfill spool %s: %w", spoolPath, err)
+		}
+		checkpoint.Spooled += written
+		checkpoint.StartAt += len(issues)
+		checkpoint.Total = total
+		if err := saveCheckpoint(path, checkpoint); err != nil {
+			return nil, err
+		}
+		obs.Info("Backfilled %d of %d issues", checkpoint.StartAt, total)
+
+		if len(issues) == 0 || checkpoint.StartAt >= total {
+			break
+		}
+		if err := sleepContext(ctx, options.PageDelay); err != nil {
+			return nil, fmt.Errorf("backfill stopped at %d, run it again to resume from %s: %w", checkpoint.StartAt, path, err)
+		}
+	}
+
+	spooled, err := readSpool(spoolPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Issues deleted during the backfill shift the next pages, the same issue may be fetched twice
+	seen := make(map[string]bool, len(spooled))
+	issues := make([]*JiraIssue, 0, len(spooled))
+	for _, issue := range spooled {
+		if !seen[issue.Key] {
+			seen[issue.Key] = true
+			issues = append(issues, issue)
+		}
+	}
+	issues = j.filterIssues(issues)
+
+	// The checkpoint is kept when a sink fails, running again only writes the issues
+	for _, sink := range j.sinks {
+		if err := sink.Write(ctx, issues); err != nil {
+			return nil, fmt.Errorf("backfill fetched %d issues but writing them failed, run it again to retry from %s: %w", len(issues), path, err)
+		}
+	}
+	for _, done := range []string{path, spoolPath} {
+		if err := os.Remove(done); err != nil {
+			obs.Warn("Failed to remove backfill file %s: %v", done, err)
+		}
+	}
+	obs.Info("Backfill of %d issues created since %s done", len(issues), options.From.Format(time.DateOnly))
+	return issues, nil
+}
+
+// backfillSpoolPath is the file next to the checkpoint the fetched issues are spooled to
+func backfillSpoolPath(checkpointPath string) string {
+	return checkpointPath + ".ndjson"
+}
+
+// appendSpool appends the issues to the spool, one JSON document per line, and returns the bytes written
+func appendSpool(spool *os.File, issues []*JiraIssue) (int64, error) {
+	var page bytes.Buffer
+	encoder := json.NewEncoder(&page)
+	for _, issue := range issues {
+		if err := encoder.Encode(issue); err != nil {
+			return 0, err
+		}
+	}
+	written, err := spool.Write(page.Bytes())
+	return int64(written), err
+}
+
+// readSpool reads the issues spooled by a backfill
+func readSpool(path string) ([]*JiraIssue, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backfill spool: %w", err)
+	}
+	defer file.Close()
+
+	var issues []*JiraIssue
+	decoder := json.NewDecoder(file)
+	for {
+		var issue JiraIssue
+		err := decoder.Decode(&issue)
+		if errors.Is(err, io.EOF) {
+			return issues, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("backfill spool %s is corrupt, remove it and the checkpoint to start over: %w", path, err)
+		}
+		issues = append(issues, &issue)
+	}
+}
+
+// loadCheckpoint reads the checkpoint of an interrupted backfill, nil when there is none
+func loadCheckpoint(path string) (*backfillCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading backfill checkpoint: %w", err)
+	}
+
+	var checkpoint backfillCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("backfill checkpoint %s is corrupt, remove it to start over: %w", path, err)
+	}
+	return &checkpoint, nil
+}
+
+// saveCheckpoint writes the backfill progress, replacing the checkpoint atomically
+func saveCheckpoint(path string, checkpoint *backfillCheckpoint) error {
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(checkpoint)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing backfill checkpoint: %w", err)
+	}
+	return nil
+}
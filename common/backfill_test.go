@@ -0,0 +1,127 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackfillResumesFromCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	searcher := &pageSearcher{issues: testIssues(7), pageSize: 3, fail: map[int]error{3: errors.New("jira is down")}}
+	client := newTestClient(t, testOptions(), searcher)
+	sink := &recordingSink{}
+	client.AddSink(sink)
+
+	if _, err := client.Backfill(context.Background(), BackfillOptions{From: from, CheckpointPath: path}); err == nil {
+		t.Fatal("backfill succeeded with a failing page")
+	}
+	checkpoint, err := loadCheckpoint(path)
+	if err != nil || checkpoint == nil {
+		t.Fatalf("checkpoint after the failure = %v, %v", checkpoint, err)
+	}
+	if checkpoint.StartAt != 3 || checkpoint.Total != 7 {
+		t.Errorf("checkpoint at %d of %d, want 3 of 7", checkpoint.StartAt, checkpoint.Total)
+	}
+	if spooled, err := readSpool(backfillSpoolPath(path)); err != nil || len(spooled) != 3 {
+		t.Errorf("spool after the failure holds %d issues (%v), want 3", len(spooled), err)
+	}
+	if len(sink.writes) != 0 {
+		t.Errorf("sink written %d times by an interrupted backfill", len(sink.writes))
+	}
+	for _, clause := range []string{`created >= "2019/01/01 00:00"`, "ORDER BY created ASC, key ASC"} {
+		if !strings.Contains(checkpoint.JQL, clause) {
+			t.Errorf("JQL %q lacks %s", checkpoint.JQL, clause)
+		}
+	}
+
+	// A page appended to the spool but not to the checkpoint is dropped and fetched again
+	spool, err := os.OpenFile(backfillSpoolPath(path), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spool.WriteString(`{"key": "INCI-torn`); err != nil {
+		t.Fatal(err)
+	}
+	spool.Close()
+
+	searcher.fail = nil
+	searches := len(searcher.calls)
+	issues, err := client.Backfill(context.Background(), BackfillOptions{From: from, CheckpointPath: path})
+	if err != nil {
+		t.Fatalf("resumed backfill: %v", err)
+	}
+	if got := searcher.calls[searches].StartAt; got != 3 {
+		t.Errorf("resumed at %d, want 3", got)
+	}
+	if len(issues) != 7 {
+		t.Errorf("backfilled %d issues, want 7", len(issues))
+	}
+	if len(sink.writes) != 1 || len(sink.writes[0]) != 7 {
+		t.Errorf("sink writes = %v, want one write of the 7 issues", sink.writes)
+	}
+	if got := len(client.GetCachedIssues()); got != 7 {
+		t.Errorf("cache holds %d issues, want 7", got)
+	}
+	for _, done := range []string{path, backfillSpoolPath(path)} {
+		if _, err := os.Stat(done); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s kept after a complete backfill: %v", done, err)
+		}
+	}
+}
+
+func TestBackfillKeepsCheckpointWhenSinkFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	options := BackfillOptions{From: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), CheckpointPath: path}
+	searcher := &pageSearcher{issues: testIssues(4), pageSize: 2}
+	client := newTestClient(t, testOptions(), searcher)
+	sink := &recordingSink{err: errors.New("index unavailable")}
+	client.AddSink(sink)
+
+	if _, err := client.Backfill(context.Background(), options); err == nil {
+		t.Fatal("backfill succeeded with a failing sink")
+	}
+	if checkpoint, err := loadCheckpoint(path); err != nil || checkpoint == nil || checkpoint.StartAt != 4 {
+		t.Fatalf("checkpoint after the sink failure = %+v, %v, want one at 4", checkpoint, err)
+	}
+
+	sink.err = nil
+	searches := len(searcher.calls)
+	if _, err := client.Backfill(context.Background(), options); err != nil {
+		t.Fatalf("backfill retry: %v", err)
+	}
+	if got := len(searcher.calls) - searches; got != 1 {
+		t.Errorf("%d searches to retry the sink, want only the final empty page", got)
+	}
+	if last := sink.writes[len(sink.writes)-1]; len(last) != 4 {
+		t.Errorf("retried write of %d issues, want 4", len(last))
+	}
+}
+
+func TestBackfillRejectsOtherCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backfill.json")
+	if err := saveCheckpoint(path, &backfillCheckpoint{JQL: "project = OPS", StartAt: 10}); err != nil {
+		t.Fatal(err)
+	}
+	searcher := &pageSearcher{issues: testIssues(2)}
+	client := newTestClient(t, testOptions(), searcher)
+
+	if _, err := client.Backfill(context.Background(), BackfillOptions{From: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), CheckpointPath: path}); err == nil {
+		t.Error("checkpoint of another query resumed")
+	}
+	if len(searcher.calls) != 0 {
+		t.Errorf("%d searches with a mismatching checkpoint", len(searcher.calls))
+	}
+
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Backfill(context.Background(), BackfillOptions{From: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), CheckpointPath: path}); err == nil {
+		t.Error("corrupt checkpoint accepted")
+	}
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+// circuitState is the state of the Jira circuit breaker, published as the jira_circuit_state gauge
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops calling Jira for a cool-down after consecutive failed refreshes, then lets a
+// single refresh through to probe whether Jira recovered
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	obs       *Observability
+	metrics   *sre.Metrics
+	labels    map[string]string
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, obs *Observability, metrics *sre.Metrics, labels map[string]string) *circuitBreaker {
+	b := &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		obs:       obs,
+		metrics:   metrics,
+		labels:    labels,
+	}
+	b.publish()
+	return b
+}
+
+// allow reports whether Jira may be called at now, an open circuit turns half-open once the cool-down
+// elapsed. wait is the cool-down left when the call is refused.
+func (b *circuitBreaker) allow(now time.Time) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != circuitOpen {
+		return 0, true
+	}
+	if wait = b.openedAt.Add(b.cooldown).Sub(now); wait > 0 {
+		return wait, false
+	}
+	b.transition(circuitHalfOpen)
+	return 0, true
+}
+
+// record counts the outcome of a call, opening the circuit after threshold consecutive failures or a
+// failed probe and closing it on success
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.failures = 0
+		if b.state != circuitClosed {
+			b.transition(circuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.transition(circuitOpen)
+	}
+}
+
+// transition moves the circuit to the state, called with mu held
+func (b *circuitBreaker) transition(state circuitState) {
+	if state != b.state {
+		b.obs.Warn("Jira circuit breaker is %s after %d consecutive failures", state, b.failures)
+	}
+	b.state = state
+	b.publish()
+}
+
+// publish sets the circuit state gauge: 0 closed, 1 open, 2 half-open
+func (b *circuitBreaker) publish() {
+	if b.metrics == nil {
+		return
+	}
+	cachedGauge(b.metrics, "jira_circuit_state", "State of the Jira circuit breaker, 0 closed, 1 open, 2 half-open", b.labels).Set(float64(b.state))
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	searcher := &pageSearcher{issues: testIssues(2), fail: map[int]error{0: errors.New("jira is down")}}
+	options := testOptions()
+	options.CircuitFailures = 2
+	options.CircuitCooldown = 60
+	client, meter := newMeteredClient(t, options, searcher)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client.now = func() time.Time { return now }
+
+	steps := []struct {
+		name      string
+		advance   time.Duration
+		jiraUp    bool
+		wantCalls int
+		wantState circuitState
+	}{
+		{name: "first failure keeps the circuit closed", wantCalls: 1, wantState: circuitClosed},
+		{name: "threshold opens the circuit", wantCalls: 2, wantState: circuitOpen},
+		{name: "open circuit skips Jira", advance: 30 * time.Second, wantCalls: 2, wantState: circuitOpen},
+		{name: "failed probe reopens the circuit", advance: 31 * time.Second, wantCalls: 3, wantState: circuitOpen},
+		{name: "cool-down restarts after the probe", advance: 59 * time.Second, jiraUp: true, wantCalls: 3, wantState: circuitOpen},
+		{name: "successful probe closes the circuit", advance: time.Second, jiraUp: true, wantCalls: 4, wantState: circuitClosed},
+		{name: "closed circuit calls Jira", jiraUp: true, wantCalls: 5, wantState: circuitClosed},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if step.jiraUp {
+			searcher.fail = nil
+		}
+		client.RefreshData(context.Background())
+
+		if got := len(searcher.calls); got != step.wantCalls {
+			t.Errorf("%s: %d searches, want %d", step.name, got, step.wantCalls)
+		}
+		if got, _ := meter.value("jira_circuit_state", nil); got != float64(step.wantState) {
+			t.Errorf("%s: jira_circuit_state = %v, want %v", step.name, got, float64(step.wantState))
+		}
+	}
+	if got, _ := meter.value("refresh_total", map[string]string{"result": "skipped"}); got != 2 {
+		t.Errorf("refresh_total{result=skipped} = %v, want 2", got)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	breaker := newCircuitBreaker(1, time.Minute, NewObservability(nil, nil, nil), nil, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	breaker.record(false, now)
+	if wait, ok := breaker.allow(now.Add(20 * time.Second)); ok || wait != 40*time.Second {
+		t.Errorf("allow() during the cool-down = %s, %v, want 40s, false", wait, ok)
+	}
+	if _, ok := breaker.allow(now.Add(time.Minute)); !ok || breaker.state != circuitHalfOpen {
+		t.Errorf("allow() after the cool-down = %v in state %s, want true in half-open", ok, breaker.state)
+	}
+	breaker.record(true, now.Add(time.Minute))
+	if breaker.state != circuitClosed || breaker.failures != 0 {
+		t.Errorf("state after a successful probe = %s with %d failures, want closed with 0", breaker.state, breaker.failures)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	searcher := &pageSearcher{fail: map[int]error{0: errors.New("jira is down")}}
+	client := newTestClient(t, testOptions(), searcher)
+	for range 5 {
+		client.RefreshData(context.Background())
+	}
+	if len(searcher.calls) != 5 {
+		t.Errorf("%d searches without a circuit breaker, want 5", len(searcher.calls))
+	}
+}
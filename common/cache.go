@@ -0,0 +1,79 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LoadCacheFile seeds the cache with the issues saved by a previous run, so that they are served before
+// the first live refresh. A missing or corrupt file leaves the cache empty.
+func (j *JiraClient) LoadCacheFile() {
+	path := j.options.CacheFilePath
+	if path == "" {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		j.obs.Warn("Failed to read cache file %s, starting empty: %v", path, err)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		j.obs.Warn("Failed to read cache file %s, starting empty: %v", path, err)
+		return
+	}
+
+	var issues []*JiraIssue
+	if err := json.Unmarshal(data, &issues); err != nil {
+		j.obs.Warn("Cache file %s is corrupt, starting empty: %v", path, err)
+		return
+	}
+
+	// Raw issues are not persisted, the first live refresh is always a full one and rebuilds them
+	j.storeIssues(issues)
+
+	j.mu.Lock()
+	j.lastRefresh = info.ModTime()
+	j.mu.Unlock()
+
+	j.obs.Info("Loaded %d issues from cache file %s saved at %s", len(issues), path, info.ModTime().Format("2006-01-02T15:04:05Z07:00"))
+}
+
+// saveCacheFile writes the converted issues to the cache file, replacing it atomically
+func (j *JiraClient) saveCacheFile(issues []*JiraIssue) error {
+	err := writeFileAtomic(j.options.CacheFilePath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(issues)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes a file through a temporary one renamed over it, so that an interrupted write
+// leaves the previous content
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCacheFileRoundTrip(t *testing.T) {
+	options := testOptions()
+	options.CacheFilePath = filepath.Join(t.TempDir(), "issues.json")
+	client := newTestClient(t, options, &pageSearcher{issues: testIssues(3)})
+	client.RefreshData(context.Background())
+
+	restarted := newTestClient(t, options, &pageSearcher{issues: testIssues(1)})
+	restarted.LoadCacheFile()
+
+	if got, want := restarted.GetCachedIssues(), client.GetCachedIssues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded issues = %v, want %v", got, want)
+	}
+	if restarted.GetLastRefreshTime().IsZero() {
+		t.Error("last refresh time not set from the cache file")
+	}
+
+	// The first live refresh replaces the loaded issues and the file
+	restarted.RefreshData(context.Background())
+	if got := len(restarted.GetCachedIssues()); got != 1 {
+		t.Errorf("cached %d issues after the live refresh, want 1", got)
+	}
+	reloaded := newTestClient(t, options, nil)
+	reloaded.LoadCacheFile()
+	if got := len(reloaded.GetCachedIssues()); got != 1 {
+		t.Errorf("cache file holds %d issues after the live refresh, want 1", got)
+	}
+}
+
+func TestLoadCacheFileStartsEmpty(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("[{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "disabled", path: ""},
+		{name: "missing", path: filepath.Join(dir, "missing.json")},
+		{name: "corrupt", path: corrupt},
+		{name: "directory", path: dir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.CacheFilePath = tt.path
+			client := newTestClient(t, options, nil)
+			client.LoadCacheFile()
+			if issues := client.GetCachedIssues(); issues != nil {
+				t.Errorf("loaded %d issues, want an empty cache", len(issues))
+			}
+			if !client.GetLastRefreshTime().IsZero() {
+				t.Error("last refresh time set without a loaded cache")
+			}
+		})
+	}
+}
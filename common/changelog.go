@@ -0,0 +1,113 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// changelogStages lists the lifecycle stages derivable from status transitions, true when the first
+// transition into one of the stage statuses counts and false when the last one does
+var changelogStages = map[string]bool{
+	"detected":     true,
+	"started":      true,
+	"escalated":    true,
+	"firefighting": true,
+	"fixed":        false,
+	"resolved":     false,
+	"closed":       false,
+}
+
+// validateStatusStages checks that statuses map to known lifecycle stages
+func validateStatusStages(stages map[string]string) error {
+	if len(stages) == 0 {
+		return fmt.Errorf("changelog extraction requires a status to stage mapping")
+	}
+	for status, stage := range stages {
+		if _, ok := changelogStages[stage]; !ok {
+			return fmt.Errorf("invalid stage %q of status %q, expected one of detected, started, escalated, firefighting, fixed, resolved, closed", stage, status)
+		}
+	}
+	return nil
+}
+
+// statusTransition is a change of the issue status at a point in time
+type statusTransition struct {
+	status string
+	at     time.Time
+}
+
+// statusTransitions returns the status changes of the issue changelog in chronological order
+func statusTransitions(changelog *jira.Changelog) []statusTransition {
+	if changelog == nil {
+		return nil
+	}
+
+	var transitions []statusTransition
+	for _, history := range changelog.Histories {
+		at, err := history.CreatedTime()
+		if err != nil {
+			continue
+		}
+		for _, item := range history.Items {
+			if item.Field == "status" {
+				transitions = append(transitions, statusTransition{status: item.ToString, at: at})
+			}
+		}
+	}
+
+	sort.SliceStable(transitions, func(a, b int) bool {
+		return transitions[a].at.Before(transitions[b].at)
+	})
+	return transitions
+}
+
+// applyChangelog sets the lifecycle timestamps from the status transitions, overriding the custom fields
+// or, with the changelog fallback, only filling the timestamps they left empty
+func (j *JiraClient) applyChangelog(issue *jira.Issue, customIssue *JiraIssue) {
+	stages := make(map[string]time.Time)
+	for _, transition := range statusTransitions(issue.Changelog) {
+		var stage string
+		for status, s := range j.options.StatusStageMap {
+			if strings.EqualFold(status, transition.status) {
+				stage = s
+				break
+			}
+		}
+		if stage == "" {
+			continue
+		}
+
+		if _, seen := stages[stage]; seen && changelogStages[stage] {
+			continue
+		}
+		stages[stage] = transition.at
+	}
+
+	set := func(field *time.Time, at time.Time) {
+		if !j.options.ChangelogFallback || field.IsZero() {
+			*field = at
+		}
+	}
+	for stage, at := range stages {
+		switch stage {
+		case "detected":
+			set(&customIssue.Detected, at)
+		case "started":
+			set(&customIssue.Started, at)
+		case "escalated":
+			set(&customIssue.Escalated, at)
+		case "firefighting":
+			set(&customIssue.Firefighting, at)
+		case "fixed":
+			set(&customIssue.Fixed, at)
+		case "resolved":
+			set(&customIssue.Resolved, at)
+		case "closed":
+			set(&customIssue.Closed, at)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// sampleChangelog moves the incident back and forth before closing it, histories are not in order
+const sampleChangelog = `{"histories": [
+	{"created": "2024-03-01T12:00:00.000+0000", "items": [{"field": "status", "fromString": "Reopened", "toString": "Resolved"}]},
+	{"created": "2024-03-01T10:05:00.000+0000", "items": [{"field": "status", "fromString": "Open", "toString": "Investigating"}]},
+	{"created": "2024-03-01T10:15:00.000+0000", "items": [
+		{"field": "assignee", "fromString": "", "toString": "jdoe"},
+		{"field": "status", "fromString": "Investigating", "toString": "In Progress"}
+	]},
+	{"created": "2024-03-01T10:30:00.000+0000", "items": [{"field": "status", "fromString": "In Progress", "toString": "Investigating"}]},
+	{"created": "2024-03-01T10:45:00.000+0000", "items": [{"field": "status", "fromString": "Investigating", "toString": "in progress"}]},
+	{"created": "2024-03-01T11:00:00.000+0000", "items": [{"field": "status", "fromString": "In Progress", "toString": "Resolved"}]},
+	{"created": "2024-03-01T11:30:00.000+0000", "items": [{"field": "status", "fromString": "Resolved", "toString": "Reopened"}]},
+	{"created": "not a time", "items": [{"field": "status", "fromString": "Resolved", "toString": "Closed"}]},
+	{"created": "2024-03-01T13:00:00.000+0000", "items": [{"field": "status", "fromString": "Resolved", "toString": "Closed"}]}
+]}`
+
+func TestApplyChangelog(t *testing.T) {
+	var changelog jira.Changelog
+	if err := json.Unmarshal([]byte(sampleChangelog), &changelog); err != nil {
+		t.Fatal(err)
+	}
+
+	options := testOptions()
+	options.UseChangelog = true
+	options.StatusStageMap = map[string]string{
+		"Investigating": "detected",
+		"In Progress":   "started",
+		"Resolved":      "resolved",
+		"Closed":        "closed",
+	}
+	searcher := &pageSearcher{}
+	client := newTestClient(t, options, searcher)
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	issue := testIssue("INCI-1", created, map[string]interface{}{"customfield_18117": "2024-03-01T09:00:00.000+0000"})
+	issue.Changelog = &changelog
+	got := convertOne(t, client, issue)
+
+	at := func(hour, minute int) time.Time { return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC) }
+	want := map[string][2]time.Time{
+		"detected": {got.Detected, at(10, 5)},
+		"started":  {got.Started, at(10, 15)},
+		"resolved": {got.Resolved, at(12, 0)},
+		"closed":   {got.Closed, at(13, 0)},
+	}
+	for stage, times := range want {
+		if !times[0].Equal(times[1]) {
+			t.Errorf("%s = %s, want %s", stage, times[0], times[1])
+		}
+	}
+
+	searcher.issues = []jira.Issue{issue}
+	if _, err := client.GetIssues(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expand := searcher.calls[0].Expand; expand != "changelog" {
+		t.Errorf("search expand = %q, want changelog", expand)
+	}
+}
+
+func TestStatusStagesValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		stages  map[string]string
+		wantErr bool
+	}{
+		{name: "valid", stages: map[string]string{"In Progress": "started", "Done": "resolved"}},
+		{name: "missing", wantErr: true},
+		{name: "unknown stage", stages: map[string]string{"Done": "finished"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.UseChangelog = true
+			options.StatusStageMap = tt.stages
+			_, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewJiraClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChangelogFallback(t *testing.T) {
+	var changelog jira.Changelog
+	if err := json.Unmarshal([]byte(sampleChangelog), &changelog); err != nil {
+		t.Fatal(err)
+	}
+
+	options := testOptions()
+	options.UseChangelog = true
+	options.ChangelogFallback = true
+	options.StatusStageMap = map[string]string{
+		"Investigating": "detected",
+		"In Progress":   "started",
+		"Resolved":      "resolved",
+	}
+	searcher := &pageSearcher{}
+	client := newTestClient(t, options, searcher)
+
+	at := func(hour, minute int) time.Time { return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC) }
+	created := at(10, 0)
+	fields := map[string]interface{}{"customfield_18117": "2024-03-01T09:00:00.000+0000"}
+
+	withBoth := testIssue("INCI-1", created, fields)
+	withBoth.Changelog = &changelog
+	transitionsOnly := testIssue("INCI-2", created, nil)
+	transitionsOnly.Changelog = &changelog
+	fieldsOnly := testIssue("INCI-3", created, fields)
+
+	tests := []struct {
+		name         string
+		issue        jira.Issue
+		wantStarted  time.Time
+		wantDetected time.Time
+		wantResolved time.Time
+	}{
+		{name: "custom field wins over the changelog", issue: withBoth, wantStarted: at(9, 0), wantDetected: at(10, 5), wantResolved: at(12, 0)},
+		{name: "changelog fills empty fields", issue: transitionsOnly, wantStarted: at(10, 15), wantDetected: at(10, 5), wantResolved: at(12, 0)},
+		{name: "custom fields without changelog", issue: fieldsOnly, wantStarted: at(9, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertOne(t, client, tt.issue)
+			for stage, times := range map[string][2]time.Time{
+				"started":  {got.Started, tt.wantStarted},
+				"detected": {got.Detected, tt.wantDetected},
+				"resolved": {got.Resolved, tt.wantResolved},
+			} {
+				if !times[0].Equal(times[1]) {
+					t.Errorf("%s = %s, want %s", stage, times[0], times[1])
+				}
+			}
+		})
+	}
+
+	searcher.issues = []jira.Issue{withBoth}
+	if _, err := client.GetIssues(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expand := searcher.calls[0].Expand; expand != "changelog" {
+		t.Errorf("search expand = %q, want changelog", expand)
+	}
+}
+
+func TestChangelogFallbackValidation(t *testing.T) {
+	options := testOptions()
+	options.ChangelogFallback = true
+	options.StatusStageMap = map[string]string{"Done": "resolved"}
+	if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+		t.Error("changelog fallback accepted without the changelog")
+	}
+}
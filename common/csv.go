@@ -0,0 +1,61 @@
+package common
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// WriteIssuesCSV writes every JiraIssue field as a CSV column named after its JSON key, timestamps
+// as RFC3339 with zero ones blank and lists joined by commas, attachments by their file names
+func WriteIssuesCSV(w io.Writer, issues []*JiraIssue) error {
+	t := reflect.TypeOf(JiraIssue{})
+
+	header := make([]string, t.NumField())
+	for n := range header {
+		name, _, _ := strings.Cut(t.Field(n).Tag.Get("json"), ",")
+		header[n] = name
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for _, issue := range issues {
+		v := reflect.ValueOf(issue).Elem()
+		for n := range record {
+			record[n] = csvValue(v.Field(n).Interface())
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, ",")
+	case []AttachmentInfo:
+		names := make([]string, 0, len(v))
+		for _, attachment := range v {
+			names = append(names, attachment.Filename)
+		}
+		return strings.Join(names, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
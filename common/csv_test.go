@@ -0,0 +1,79 @@
+package common
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+func csvFixture() []*JiraIssue {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	return []*JiraIssue{
+		{
+			Key:        "INCI-1",
+			Summary:    "Checkout down, payments failing",
+			Project:    "INCI",
+			Status:     "Closed",
+			Components: []string{"api", "web"},
+			Labels:     []string{"incident"},
+			Created:    created,
+			Updated:    created.Add(2 * time.Hour),
+			Resolved:   created.Add(time.Hour),
+			Severity:   "SEV1",
+			Service:    "checkout",
+			RootCause:  `"Bad" deploy`,
+			Impact:     3,
+			Done:       created.Add(time.Hour),
+		},
+		{
+			Key:     "INCI-2",
+			Summary: "Slow search",
+			Project: "INCI",
+			Status:  "Open",
+			Created: created.Add(24 * time.Hour),
+			Updated: created.Add(24 * time.Hour),
+		},
+	}
+}
+
+func TestWriteIssuesCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteIssuesCSV(&out, csvFixture()); err != nil {
+		t.Fatal(err)
+	}
+
+	golden, err := os.ReadFile("testdata/issues.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(golden) {
+		t.Errorf("CSV differs from testdata/issues.csv:\n%s", out.String())
+	}
+}
+
+func TestApiIssuesCSV(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	api, _ := newTestApi(t, ApiOptions{}, map[string][]jira.Issue{"": {
+		testIssue("INCI-1", created, nil),
+		testIssue("INCI-2", created, nil),
+	}})
+
+	rec := httptest.NewRecorder()
+	api.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/issues.csv", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /issues.csv = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type = %q, want text/csv", got)
+	}
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "key,summary,") {
+		t.Errorf("want a header and 2 rows, got:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// Helpers below read custom field values deserialized by go-jira into interface{}.
+// The same field may come as a string, a number, an option object or an array
+// depending on the Jira version, so every helper tries each of these shapes.
+
+// optionKeys lists the object attributes holding a printable value, in order of preference
+var optionKeys = []string{"value", "name", "displayName", "key"}
+
+// asString returns a textual value from a string, number, option object or the first array element
+func asString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, t != ""
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(t), true
+	case json.Number:
+		return t.String(), true
+	case bool:
+		return strconv.FormatBool(t), true
+	case map[string]interface{}:
+		return asOptionValue(t)
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := asString(item); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// asOptionValue returns the value of an option or user object such as {"value": "..."} or {"name": "..."}
+func asOptionValue(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, key := range optionKeys {
+			if s, ok := t[key].(string); ok && s != "" {
+				return s, true
+			}
+		}
+		// Cascading selects keep the selected value in a nested child object
+		if child, ok := t["child"]; ok {
+			return asOptionValue(child)
+		}
+	case string:
+		return t, t != ""
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := asOptionValue(item); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// asLink returns a link from a plain string or a URL object such as {"url": "...", "title": "..."}
+func asLink(v interface{}) (string, bool) {
+	if t, ok := v.(map[string]interface{}); ok {
+		for _, key := range []string{"url", "href", "link"} {
+			if s, ok := t[key].(string); ok && s != "" {
+				return s, true
+			}
+		}
+	}
+	return asString(v)
+}
+
+// asStringSlice returns all textual values from an array, a single value or a comma-separated string
+func asStringSlice(v interface{}) ([]string, bool) {
+	var values []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := asString(item); ok {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		for _, s := range t {
+			if s != "" {
+				values = append(values, s)
+			}
+		}
+	case string:
+		for _, s := range strings.Split(t, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	default:
+		if s, ok := asString(t); ok {
+			values = append(values, s)
+		}
+	}
+	return values, len(values) > 0
+}
+
+// asFloat returns a numeric value from a number, a numeric string or an option object
+func asFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+		return f, err == nil
+	case map[string]interface{}:
+		if s, ok := asOptionValue(t); ok {
+			return asFloat(s)
+		}
+	case []interface{}:
+		if len(t) > 0 {
+			return asFloat(t[0])
+		}
+	}
+	return 0, false
+}
+
+// asTime returns a timestamp from a string in the first matching layout, epoch milliseconds or an option object
+func asTime(v interface{}, layouts []string, location *time.Location) (time.Time, bool) {
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return time.Time{}, false
+		}
+		for _, layout := range layouts {
+			if parsed, err := time.ParseInLocation(layout, t, location); err == nil {
+				return parsed, true
+			}
+		}
+		return time.Time{}, false
+	case float64:
+		return time.UnixMilli(int64(t)).In(location), true
+	case json.Number:
+		ms, err := t.Int64()
+		return time.UnixMilli(ms).In(location), err == nil
+	case map[string]interface{}:
+		if s, ok := asOptionValue(t); ok {
+			return asTime(s, layouts, location)
+		}
+	}
+	return time.Time{}, false
+}
+
+// fromSources returns the first value found in the ordered sources, normalized through the synonyms.
+// A source is a custom field ID, "label:<prefix>" or "component:<prefix>", the prefix being stripped
+// from the matching label or component name.
+func fromSources(issue *jira.Issue, sources []string, synonyms map[string]string) (string, bool) {
+	for _, source := range sources {
+		kind, prefix, _ := strings.Cut(strings.TrimSpace(source), ":")
+
+		var value string
+		switch kind {
+		case "":
+			continue
+		case "label":
+			for _, label := range issue.Fields.Labels {
+				if strings.HasPrefix(label, prefix) {
+					value = strings.TrimPrefix(label, prefix)
+					break
+				}
+			}
+		case "component":
+			for _, component := range issue.Fields.Components {
+				if component != nil && strings.HasPrefix(component.Name, prefix) {
+					value = strings.TrimPrefix(component.Name, prefix)
+					break
+				}
+			}
+		default:
+			value, _ = asString(issue.Fields.Unknowns[kind])
+		}
+
+		if value = strings.TrimSpace(value); value != "" {
+			return normalize(value, synonyms), true
+		}
+	}
+	return "", false
+}
+
+// normalize maps a value to its canonical form through case-insensitive synonyms
+func normalize(value string, synonyms map[string]string) string {
+	for synonym, canonical := range synonyms {
+		if strings.EqualFold(synonym, value) {
+			return canonical
+		}
+	}
+	return value
+}
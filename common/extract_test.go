@@ -0,0 +1,174 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+func TestAsString(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   string
+		wantOK bool
+	}{
+		{"string", "SEV1", "SEV1", true},
+		{"empty string", "", "", false},
+		{"float", 2.5, "2.5", true},
+		{"int", 3, "3", true},
+		{"json number", json.Number("42"), "42", true},
+		{"bool", true, "true", true},
+		{"option value", map[string]interface{}{"value": "SEV2", "id": "10"}, "SEV2", true},
+		{"user name", map[string]interface{}{"name": "jdoe"}, "jdoe", true},
+		{"cascading child", map[string]interface{}{"child": map[string]interface{}{"value": "eu-west"}}, "eu-west", true},
+		{"first array element", []interface{}{"", map[string]interface{}{"value": "a"}, "b"}, "a", true},
+		{"empty array", []interface{}{}, "", false},
+		{"nil", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := asString(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("asString(%#v) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAsStringSlice(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   []string
+		wantOK bool
+	}{
+		{"array of options", []interface{}{map[string]interface{}{"value": "eu"}, "us", ""}, []string{"eu", "us"}, true},
+		{"string slice", []string{"eu", "", "us"}, []string{"eu", "us"}, true},
+		{"comma-separated", " eu, us ,,", []string{"eu", "us"}, true},
+		{"single option", map[string]interface{}{"value": "eu"}, []string{"eu"}, true},
+		{"number", 1.0, []string{"1"}, true},
+		{"empty", "", nil, false},
+		{"nil", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := asStringSlice(tt.value)
+			if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOK {
+				t.Errorf("asStringSlice(%#v) = %q, %v, want %q, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAsFloat(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   float64
+		wantOK bool
+	}{
+		{"float", 7.5, 7.5, true},
+		{"int", 3, 3, true},
+		{"json number", json.Number("1.25"), 1.25, true},
+		{"numeric string", " 12 ", 12, true},
+		{"text", "high", 0, false},
+		{"option", map[string]interface{}{"value": "4"}, 4, true},
+		{"array", []interface{}{"9"}, 9, true},
+		{"empty array", []interface{}{}, 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := asFloat(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("asFloat(%#v) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAsTime(t *testing.T) {
+	at := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   interface{}
+		layouts []string
+		want    time.Time
+		wantOK  bool
+	}{
+		{"date-time", "2024-03-05T10:30:00.000+0000", []string{jiraDateTimeLayout}, at, true},
+		{"date", "2024-03-05", []string{jiraDateLayout}, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"wrong layout", "2024-03-05", []string{jiraDateTimeLayout}, time.Time{}, false},
+		{"second layout", "2024-03-05", []string{jiraDateTimeLayout, jiraDateLayout}, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), true},
+		{"rfc3339", "2024-03-05T10:30:00Z", defaultTimeFormats, at, true},
+		{"no matching layout", "05/03/2024", defaultTimeFormats, time.Time{}, false},
+		{"empty", "", []string{jiraDateTimeLayout}, time.Time{}, false},
+		{"epoch milliseconds", float64(at.UnixMilli()), nil, at, true},
+		{"epoch json number", json.Number("1709634600000"), nil, at, true},
+		{"option", map[string]interface{}{"value": "2024-03-05T10:30:00.000+0000"}, []string{jiraDateTimeLayout}, at, true},
+		{"nil", nil, []string{jiraDateTimeLayout}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := asTime(tt.value, tt.layouts, time.UTC)
+			if !got.Equal(tt.want) || ok != tt.wantOK {
+				t.Errorf("asTime(%#v) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFromSources(t *testing.T) {
+	issue := testIssue("INCI-1", time.Now(), map[string]interface{}{
+		"customfield_1": map[string]interface{}{"value": "Production"},
+		"customfield_2": "",
+	})
+	issue.Fields.Labels = []string{"team:sre", "env:staging"}
+	issue.Fields.Components = []*jira.Component{nil, {Name: "env-dev"}}
+	synonyms := map[string]string{"production": "prod", "staging": "stage"}
+
+	tests := []struct {
+		name    string
+		sources []string
+		want    string
+		wantOK  bool
+	}{
+		{"custom field first", []string{"customfield_1", "label:env:", "component:env-"}, "prod", true},
+		{"empty field falls through to the label", []string{"customfield_2", "label:env:"}, "stage", true},
+		{"label before component", []string{"label:env:", "component:env-"}, "stage", true},
+		{"component skips null entries", []string{"component:env-"}, "dev", true},
+		{"missing sources", []string{"customfield_3", "label:region:"}, "", false},
+		{"blank sources are ignored", []string{"", " ", "component:env-"}, "dev", true},
+		{"no sources", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := fromSources(&issue, tt.sources, synonyms)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("fromSources(%q) = %q, %v, want %q, %v", tt.sources, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	synonyms := map[string]string{"Production": "prod", "prd": "prod"}
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"production", "prod"},
+		{"PRD", "prod"},
+		{"prod", "prod"},
+		{"staging", "staging"},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.value, synonyms); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
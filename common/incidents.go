@@ -0,0 +1,281 @@
+package common
+
+import (
+	"sort"
+	"time"
+)
+
+// HandoffRatio returns the share of resolved issues whose reporter differs from the assignee.
+// Issues without a reporter or assignee are excluded from the denominator.
+func HandoffRatio(issues []*JiraIssue) float64 {
+	total, handoffs := 0, 0
+	for _, issue := range issues {
+		if issue.IsOpen() || issue.Reporter == "" || issue.Assignee == "" {
+			continue
+		}
+		total++
+		if issue.Reporter != issue.Assignee {
+			handoffs++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(handoffs) / float64(total)
+}
+
+// Dimensions are the distinct values issues can be filtered or grouped by
+type Dimensions struct {
+	Services   []string `json:"services"`
+	Severities []string `json:"severities"`
+	Statuses   []string `json:"statuses"`
+	Assignees  []string `json:"assignees"`
+}
+
+// IssueDimensions returns the sorted distinct non-empty dimension values of the issues
+func IssueDimensions(issues []*JiraIssue) Dimensions {
+	services := make(map[string]bool)
+	severities := make(map[string]bool)
+	statuses := make(map[string]bool)
+	assignees := make(map[string]bool)
+	for _, issue := range issues {
+		services[issue.Service] = true
+		severities[issue.Severity] = true
+		statuses[issue.Status] = true
+		assignees[issue.Assignee] = true
+	}
+
+	return Dimensions{
+		Services:   distinctValues(services),
+		Severities: distinctValues(severities),
+		Statuses:   distinctValues(statuses),
+		Assignees:  distinctValues(assignees),
+	}
+}
+
+// distinctValues returns the sorted keys of the set without the empty value
+func distinctValues(set map[string]bool) []string {
+	values := make([]string, 0, len(set))
+	for value := range set {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// Recurrence counts the incidents of a service sharing a root cause
+type Recurrence struct {
+	Service   string   `json:"service"`
+	RootCause string   `json:"root_cause"`
+	Count     int      `json:"count"`
+	Keys      []string `json:"keys"`
+}
+
+// IncidentRecurrence groups issues by service and root cause, an empty root cause counting as unknown,
+// and returns the groups with the most incidents first
+func IncidentRecurrence(issues []*JiraIssue) []Recurrence {
+	groups := make(map[[2]string]*Recurrence)
+	for _, issue := range issues {
+		rootCause := issue.RootCause
+		if rootCause == "" {
+			rootCause = "unknown"
+		}
+		key := [2]string{labelValue(issue.Service), rootCause}
+		group, ok := groups[key]
+		if !ok {
+			group = &Recurrence{Service: key[0], RootCause: key[1]}
+			groups[key] = group
+		}
+		group.Count++
+		group.Keys = append(group.Keys, issue.Key)
+	}
+
+	recurrences := make([]Recurrence, 0, len(groups))
+	for _, group := range groups {
+		recurrences = append(recurrences, *group)
+	}
+	sort.Slice(recurrences, func(a, b int) bool {
+		if recurrences[a].Count != recurrences[b].Count {
+			return recurrences[a].Count > recurrences[b].Count
+		}
+		if recurrences[a].Service != recurrences[b].Service {
+			return recurrences[a].Service < recurrences[b].Service
+		}
+		return recurrences[a].RootCause < recurrences[b].RootCause
+	})
+	return recurrences
+}
+
+// Workload buckets of AssigneeWorkload
+const (
+	workloadUnassigned = "unassigned"
+	workloadOther      = "other"
+)
+
+// AssigneeWorkload counts the open incidents of every assignee, incidents without one as unassigned.
+// Assignees with threshold open incidents or less are summed as other to bound the number of series,
+// both buckets are always present.
+func AssigneeWorkload(issues []*JiraIssue, threshold int) map[string]int {
+	counts := make(map[string]int)
+	unassigned := 0
+	for _, issue := range issues {
+		if !issue.IsOpen() {
+			continue
+		}
+		if issue.Assignee == "" {
+			unassigned++
+			continue
+		}
+		counts[issue.Assignee]++
+	}
+
+	workload := map[string]int{workloadUnassigned: unassigned, workloadOther: 0}
+	for assignee, count := range counts {
+		if count <= threshold || assignee == workloadUnassigned || assignee == workloadOther {
+			workload[workloadOther] += count
+			continue
+		}
+		workload[assignee] = count
+	}
+	return workload
+}
+
+// TimelineEvent is a single lifecycle step of an incident
+type TimelineEvent struct {
+	Event     string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	// Gap is the time elapsed since the previous event
+	Gap string `json:"gap,omitempty"`
+}
+
+// Timeline returns the incident lifecycle events in chronological order, omitting missing timestamps
+func (i *JiraIssue) Timeline() []TimelineEvent {
+	candidates := []TimelineEvent{
+		{Event: "created", Timestamp: i.Created},
+		{Event: "detected", Timestamp: i.Detected},
+		{Event: "started", Timestamp: i.Started},
+		{Event: "escalated", Timestamp: i.Escalated},
+		{Event: "firefighting", Timestamp: i.Firefighting},
+		{Event: "fixed", Timestamp: i.Fixed},
+		{Event: "resolved", Timestamp: i.Resolved},
+		{Event: "closed", Timestamp: i.Closed},
+	}
+
+	events := make([]TimelineEvent, 0, len(candidates))
+	for _, event := range candidates {
+		if !event.Timestamp.IsZero() {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].Timestamp.Before(events[b].Timestamp)
+	})
+
+	for n := 1; n < len(events); n++ {
+		events[n].Gap = events[n].Timestamp.Sub(events[n-1].Timestamp).String()
+	}
+	return events
+}
+
+// StageDuration is the time an incident spent between two lifecycle stages
+type StageDuration struct {
+	Stage   string  `json:"stage"`
+	Seconds float64 `json:"seconds"`
+	// Clamped marks a stage whose end precedes its start, reported as zero
+	Clamped bool `json:"clamped,omitempty"`
+}
+
+// IssueDurations holds the stage durations of one incident
+type IssueDurations struct {
+	Key    string          `json:"key"`
+	Stages []StageDuration `json:"stages"`
+}
+
+// StageDurations returns the durations of created→detected, detected→started, started→resolved and
+// resolved→closed, skipping stages with a missing timestamp and clamping negative ones to zero
+func (i *JiraIssue) StageDurations() []StageDuration {
+	stages := []struct {
+		name     string
+		from, to time.Time
+	}{
+		{"created_to_detected", i.Created, i.Detected},
+		{"detected_to_started", i.Detected, i.Started},
+		{"started_to_resolved", i.Started, i.Resolved},
+		{"resolved_to_closed", i.Resolved, i.Closed},
+	}
+
+	durations := make([]StageDuration, 0, len(stages))
+	for _, stage := range stages {
+		if stage.from.IsZero() || stage.to.IsZero() {
+			continue
+		}
+		d := StageDuration{Stage: stage.name, Seconds: stage.to.Sub(stage.from).Seconds()}
+		if d.Seconds < 0 {
+			d.Seconds = 0
+			d.Clamped = true
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
+
+// IncidentStats holds mean incident durations for one severity
+type IncidentStats struct {
+	MTTD     time.Duration
+	MTTR     time.Duration
+	Detected int
+	Resolved int
+}
+
+// ComputeIncidentMetrics returns per-severity mean time to detect (Detected - Created) and
+// mean time to resolve (Resolved - Started), skipping issues missing the timestamps involved
+func ComputeIncidentMetrics(issues []*JiraIssue) map[string]IncidentStats {
+	type totals struct {
+		detect, resolve    time.Duration
+		detected, resolved int
+	}
+
+	bySeverity := make(map[string]*totals)
+	for _, issue := range issues {
+		severity := labelValue(issue.Severity)
+		t, ok := bySeverity[severity]
+		if !ok {
+			t = &totals{}
+			bySeverity[severity] = t
+		}
+
+		if !issue.Created.IsZero() && !issue.Detected.IsZero() && !issue.Detected.Before(issue.Created) {
+			t.detect += issue.Detected.Sub(issue.Created)
+			t.detected++
+		}
+		if !issue.Started.IsZero() && !issue.Resolved.IsZero() && !issue.Resolved.Before(issue.Started) {
+			t.resolve += issue.Resolved.Sub(issue.Started)
+			t.resolved++
+		}
+	}
+
+	stats := make(map[string]IncidentStats, len(bySeverity))
+	for severity, t := range bySeverity {
+		s := IncidentStats{Detected: t.detected, Resolved: t.resolved}
+		if t.detected > 0 {
+			s.MTTD = t.detect / time.Duration(t.detected)
+		}
+		if t.resolved > 0 {
+			s.MTTR = t.resolve / time.Duration(t.resolved)
+		}
+		stats[severity] = s
+	}
+	return stats
+}
+
+// labelValue returns the value to use as a metric label, "none" when empty
+func labelValue(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,174 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputeIncidentMetrics(t *testing.T) {
+	base := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	tests := []struct {
+		name   string
+		issues []*JiraIssue
+		want   map[string]IncidentStats
+	}{
+		{
+			name: "means per severity",
+			issues: []*JiraIssue{
+				{Severity: "SEV1", Created: at(0), Detected: at(10), Started: at(10), Resolved: at(70)},
+				{Severity: "SEV1", Created: at(0), Detected: at(20), Started: at(20), Resolved: at(140)},
+				{Severity: "SEV2", Created: at(0), Detected: at(5)},
+			},
+			want: map[string]IncidentStats{
+				"SEV1": {MTTD: 15 * time.Minute, MTTR: 90 * time.Minute, Detected: 2, Resolved: 2},
+				"SEV2": {MTTD: 5 * time.Minute, Detected: 1},
+			},
+		},
+		{
+			name: "missing and inverted timestamps skipped",
+			issues: []*JiraIssue{
+				{Severity: "SEV1", Created: at(30), Detected: at(10)},
+				{Severity: "SEV1", Created: at(0), Started: at(60), Resolved: at(30)},
+				{Severity: "SEV1", Created: at(0), Resolved: at(30)},
+			},
+			want: map[string]IncidentStats{"SEV1": {}},
+		},
+		{
+			name:   "missing severity",
+			issues: []*JiraIssue{{Created: at(0), Detected: at(1)}},
+			want:   map[string]IncidentStats{"none": {MTTD: time.Minute, Detected: 1}},
+		},
+		{
+			name: "no issues",
+			want: map[string]IncidentStats{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputeIncidentMetrics(tt.issues); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputeIncidentMetrics() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStageDurations(t *testing.T) {
+	base := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+
+	tests := []struct {
+		name  string
+		issue *JiraIssue
+		want  []StageDuration
+	}{
+		{
+			name:  "fully populated",
+			issue: &JiraIssue{Created: at(0), Detected: at(5), Started: at(15), Resolved: at(75), Closed: at(135)},
+			want: []StageDuration{
+				{Stage: "created_to_detected", Seconds: 300},
+				{Stage: "detected_to_started", Seconds: 600},
+				{Stage: "started_to_resolved", Seconds: 3600},
+				{Stage: "resolved_to_closed", Seconds: 3600},
+			},
+		},
+		{
+			name:  "gaps skipped",
+			issue: &JiraIssue{Created: at(0), Started: at(15), Resolved: at(75)},
+			want:  []StageDuration{{Stage: "started_to_resolved", Seconds: 3600}},
+		},
+		{
+			name:  "negative clamped",
+			issue: &JiraIssue{Created: at(10), Detected: at(0)},
+			want:  []StageDuration{{Stage: "created_to_detected", Seconds: 0, Clamped: true}},
+		},
+		{
+			name:  "no timestamps",
+			issue: &JiraIssue{},
+			want:  []StageDuration{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.issue.StageDurations(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StageDurations() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	base := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	issue := &JiraIssue{
+		Created:  base,
+		Started:  base.Add(30 * time.Minute),
+		Detected: base.Add(10 * time.Minute),
+		Closed:   base.Add(2 * time.Hour),
+	}
+
+	want := []TimelineEvent{
+		{Event: "created", Timestamp: base},
+		{Event: "detected", Timestamp: base.Add(10 * time.Minute), Gap: "10m0s"},
+		{Event: "started", Timestamp: base.Add(30 * time.Minute), Gap: "20m0s"},
+		{Event: "closed", Timestamp: base.Add(2 * time.Hour), Gap: "1h30m0s"},
+	}
+	if got := issue.Timeline(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Timeline() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIncidentRecurrence(t *testing.T) {
+	issues := []*JiraIssue{
+		{Key: "INCI-6", Service: "api", RootCause: "deploy"},
+		{Key: "INCI-5", Service: "web", RootCause: "capacity"},
+		{Key: "INCI-4", Service: "api", RootCause: "deploy"},
+		{Key: "INCI-3", Service: "api"},
+		{Key: "INCI-2", Service: "api", RootCause: "deploy"},
+		{Key: "INCI-1", Service: "api"},
+	}
+
+	want := []Recurrence{
+		{Service: "api", RootCause: "deploy", Count: 3, Keys: []string{"INCI-6", "INCI-4", "INCI-2"}},
+		{Service: "api", RootCause: "unknown", Count: 2, Keys: []string{"INCI-3", "INCI-1"}},
+		{Service: "web", RootCause: "capacity", Count: 1, Keys: []string{"INCI-5"}},
+	}
+	if got := IncidentRecurrence(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("IncidentRecurrence() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAssigneeWorkload(t *testing.T) {
+	resolved := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	issues := []*JiraIssue{
+		{Key: "INCI-1", Assignee: "alice"},
+		{Key: "INCI-2", Assignee: "alice"},
+		{Key: "INCI-3", Assignee: "alice"},
+		{Key: "INCI-4", Assignee: "bob"},
+		{Key: "INCI-5", Assignee: "bob"},
+		{Key: "INCI-6", Assignee: "carol"},
+		{Key: "INCI-7"},
+		{Key: "INCI-8"},
+		{Key: "INCI-9", Assignee: "alice", Done: resolved},
+		{Key: "INCI-10", Done: resolved},
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		want      map[string]int
+	}{
+		{name: "every assignee", threshold: 0, want: map[string]int{"alice": 3, "bob": 2, "carol": 1, "unassigned": 2, "other": 0}},
+		{name: "single incidents grouped", threshold: 1, want: map[string]int{"alice": 3, "bob": 2, "unassigned": 2, "other": 1}},
+		{name: "only the busiest", threshold: 2, want: map[string]int{"alice": 3, "unassigned": 2, "other": 3}},
+		{name: "nobody above", threshold: 5, want: map[string]int{"unassigned": 2, "other": 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AssigneeWorkload(issues, tt.threshold); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AssigneeWorkload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
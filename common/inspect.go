@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// maxSampleLength truncates sample values in inspection reports, in characters
+const maxSampleLength = 80
+
+// FieldInspection describes a custom field of an issue to help finding the field mapping
+type FieldInspection struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Mapped string `json:"mapped,omitempty"`
+	Shape  string `json:"shape"`
+	Sample string `json:"sample"`
+}
+
+// InspectIssue fetches the issue with all fields and describes every custom field it holds
+func (j *JiraClient) InspectIssue(ctx context.Context, key string) ([]FieldInspection, error) {
+	issue, resp, err := j.client.Issue.GetWithContext(ctx, key, &jira.GetQueryOptions{Fields: "*all"})
+	if err = j.scrubError(err); err != nil {
+		j.reportHttpError(j.obs.WithContext(ctx), httpResponse(resp), err)
+		return nil, fmt.Errorf("error getting issue %s: %w", key, err)
+	}
+
+	// Field names are a nicety, the report is still useful without them
+	names := make(map[string]string)
+	if list, _, err := j.client.Field.GetListWithContext(ctx); err == nil {
+		for _, field := range list {
+			names[field.ID] = field.Name
+		}
+	}
+
+	return j.inspectFields(issue.Fields.Unknowns, names), nil
+}
+
+func (j *JiraClient) inspectFields(unknowns map[string]interface{}, names map[string]string) []FieldInspection {
+	mapped := make(map[string]string)
+	for name, ids := range j.fields {
+		for _, id := range ids {
+			mapped[id] = name
+		}
+	}
+
+	var fields []FieldInspection
+	for id, value := range unknowns {
+		if !strings.HasPrefix(id, "customfield_") {
+			continue
+		}
+		fields = append(fields, FieldInspection{
+			ID:     id,
+			Name:   names[id],
+			Mapped: mapped[id],
+			Shape:  valueShape(value),
+			Sample: sampleValue(value),
+		})
+	}
+
+	sort.Slice(fields, func(a, b int) bool {
+		return fields[a].ID < fields[b].ID
+	})
+	return fields
+}
+
+// valueShape describes the JSON structure of a value, e.g. object{id,value} or array[string]
+func valueShape(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case bool:
+		return "bool"
+	case []interface{}:
+		if len(v) == 0 {
+			return "array[]"
+		}
+		return "array[" + valueShape(v[0]) + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return "object{" + strings.Join(keys, ",") + "}"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// sampleValue renders a value as compact JSON, truncated for display
+func sampleValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	// Cut between characters, multi-byte values stay valid UTF-8
+	if sample := []rune(string(data)); len(sample) > maxSampleLength {
+		return string(sample[:maxSampleLength]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestInspectFields(t *testing.T) {
+	var unknowns map[string]interface{}
+	payload := `{
+		"customfield_18119": {"self": "https://jira/option/1", "value": "SEV1", "id": "1"},
+		"customfield_21501": [{"value": "eu-west-1"}, {"value": "us-east-1"}],
+		"customfield_31207": null,
+		"customfield_9": 42,
+		"customfield_10": "` + strings.Repeat("x", 100) + `",
+		"customfield_11": [],
+		"customfield_12": "` + strings.Repeat("инцидент🔥", 20) + `",
+		"summary": "not a custom field"
+	}`
+	if err := json.Unmarshal([]byte(payload), &unknowns); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newTestClient(t, testOptions(), nil)
+	got := client.inspectFields(unknowns, map[string]string{"customfield_18119": "Severity"})
+
+	want := []FieldInspection{
+		{ID: "customfield_10", Shape: "string", Sample: `"` + strings.Repeat("x", maxSampleLength-1) + "..."},
+		{ID: "customfield_11", Shape: "array[]", Sample: "[]"},
+		{ID: "customfield_12", Shape: "string", Sample: `"` + string([]rune(strings.Repeat("инцидент🔥", 20))[:maxSampleLength-1]) + "..."},
+		{ID: "customfield_18119", Name: "Severity", Mapped: "severity", Shape: "object{id,self,value}",
+			Sample: `{"id":"1","self":"https://jira/option/1","value":"SEV1"}`},
+		{ID: "customfield_21501", Mapped: "regions", Shape: "array[object{value}]", Sample: `[{"value":"eu-west-1"},{"value":"us-east-1"}]`},
+		{ID: "customfield_31207", Mapped: "metrics", Shape: "null", Sample: "null"},
+		{ID: "customfield_9", Shape: "number", Sample: "42"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("inspectFields() =\n%+v\nwant\n%+v", got, want)
+	}
+	for _, field := range got {
+		if !utf8.ValidString(field.Sample) {
+			t.Errorf("%s sample %q is not valid UTF-8", field.ID, field.Sample)
+		}
+	}
+}
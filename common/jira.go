@@ -2,343 +2,2442 @@ package common
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
 	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/andygrunwald/go-jira"
 	sre "github.com/devopsext/sre/common"
+	"github.com/google/uuid"
 )
 
 // JiraOptions holds Jira connection settings
 type JiraOptions struct {
-	URL             string
-	Username        string
-	ApiToken        string
-	Password        string
-	ProjectKey      string
-	QueryFilter     string
+	URL      string
+	Username string
+	ApiToken string
+	Password string
+	// AuthMethod is one of basic, pat or oauth, basic when empty
+	AuthMethod        string
+	OAuthClientID     string
+	OAuthClientSecret string
+	OAuthTokenURL     string
+	OAuthScopes       []string
+	ProjectKey        string
+	QueryFilter       string
+	// JQL replaces the generated query entirely when set
+	JQL string
+	// LookbackJQL is the JQL date or function issues are fetched from, LookbackDays overrides it when set
+	LookbackJQL     string
+	LookbackDays    int
 	RefreshInterval int
+	// MaxResults is the search page size, servers may return fewer issues per page
+	MaxResults int
+	// MaxIssues stops the search once that many issues are fetched, 0 is unlimited
+	MaxIssues int
+	// FetchConcurrency bounds the pages fetched in parallel once the matched total is known, 1 fetches sequentially
+	FetchConcurrency int
+	// ConvertWorkers bounds the issues converted in parallel, the number of CPUs when 0
+	ConvertWorkers int
+	// RequestsPerSecond limits outbound Jira requests, 0 is unlimited
+	RequestsPerSecond float64
+	// RateLimitWarnBelow is the number of requests left in the Jira rate limit window below which a warning is logged
+	RateLimitWarnBelow int
+	// HTTPTimeout limits every Jira request in seconds, including reading the response
+	HTTPTimeout int
+	// ConnectTimeout limits every attempt of the startup connection test in seconds
+	ConnectTimeout int
+	// Proxy is the outbound proxy URL for Jira requests, HTTPS_PROXY and NO_PROXY are honored when empty
+	Proxy string
+	// CustomHeaders are added to every Jira request, e.g. a token expected by an auth proxy
+	CustomHeaders map[string]string
+	// CACertPath is a PEM bundle trusted in addition to the system certificates
+	CACertPath         string
+	InsecureSkipVerify bool
+	DateOnlyFields     []string
+	// TimeFormats are the layouts tried in order to parse custom timestamp fields
+	TimeFormats    []string
+	Timezone       string
+	ServiceMap     map[string]string
+	ServiceMapFile string
+	DoneFields     []string
+	DoneSelect     string
+	PriorityMap    map[string]string
+	// CacheFilePath persists the converted issues of every refresh to seed the cache on restart
+	CacheFilePath  string
+	RecordDir      string
+	ReplayDir      string
+	UserFields     []string
+	MinTotal       int
+	MaxTotal       int
+	MaxTotalChange float64
+	RefreshScope   string
+	// OnlyUnresolved and OnlyResolved narrow the issues served by the API to the open or done ones, the
+	// cache and the metrics keep all issues
+	OnlyUnresolved bool
+	OnlyResolved   bool
+	// SeverityOrder lists severities from the most to the least severe
+	SeverityOrder []string
+	// SeverityAliases map raw severity values to canonical ones, case-insensitive
+	SeverityAliases      map[string]string
+	MinSeverityForAlerts string
+	// LabelFilter keeps only issues carrying all of the labels in the cache
+	LabelFilter []string
+	// OnlyEnvironments keeps only issues of these environments in the cache, after normalization
+	OnlyEnvironments []string
+	// SeverityWeights multiply the business impact into the issue score, unknown severities weigh 0
+	SeverityWeights     map[string]int
+	EnvironmentSources  []string
+	EnvironmentSynonyms map[string]string
+	// FieldMapping maps logical field names to custom field IDs, alternatives separated by |
+	FieldMapping map[string]string
+	// RequestFields replaces the fields requested from Jira, derived from the field mapping when empty
+	RequestFields []string
+	MaxRetries    int
+	// RetryBackoff is the initial delay between retries in milliseconds, doubled on every attempt
+	RetryBackoff       int
+	IncrementalRefresh bool
+	// CircuitFailures is the number of consecutive failed refreshes after which Jira is not called for
+	// CircuitCooldown seconds, 0 disables the circuit breaker
+	CircuitFailures int
+	CircuitCooldown int
+	// AcceptPartialRefresh caches the pages fetched before a search failed, merged over the cached issues
+	AcceptPartialRefresh bool
+	// UseChangelog derives lifecycle timestamps from status transitions mapped to stages by StatusStageMap,
+	// overriding the custom fields, or with ChangelogFallback only filling the timestamps they leave empty
+	UseChangelog      bool
+	ChangelogFallback bool
+	StatusStageMap    map[string]string
+	// IncludeCommentCounts requests the comments of every issue to count them, it inflates the search payload
+	IncludeCommentCounts bool
+	// IncludeAttachments requests the attachment metadata of every issue, attachment contents are never downloaded
+	IncludeAttachments bool
+	// WorkloadThreshold is the open incident count an assignee needs to exceed to get an own workload series
+	WorkloadThreshold int
+	// Tenant names the Jira instance among several polled by one process, it labels the metrics and logs
+	Tenant string
 }
 
+const metricsGroup = "aim"
+
+// defaultHTTPTimeout keeps a hung Jira connection from blocking a refresh forever
+const defaultHTTPTimeout = 30 * time.Second
+
+// defaultConnectTimeout keeps a hung Jira from blocking the startup
+const defaultConnectTimeout = 10 * time.Second
+
+// defaultCircuitCooldown is the time refreshes are skipped once the circuit to Jira opened
+const defaultCircuitCooldown = 5 * time.Minute
+
+// defaultFetchConcurrency is the number of pages fetched in parallel
+const defaultFetchConcurrency = 4
+
+// defaultMaxResults is the search page size requested by the original implementation, servers capping
+// it at a lower value are paged with the size they actually serve
+const defaultMaxResults = 1000
+
+// defaultFieldMapping holds the custom field IDs of the original Jira instance
+var defaultFieldMapping = map[string]string{
+	"head":            "customfield_22501",
+	"started":         "customfield_18117",
+	"firefighting":    "customfield_21200",
+	"closed":          "customfield_20908",
+	"severity":        "customfield_18119",
+	"service":         "customfield_33803",
+	"root_cause":      "customfield_37238",
+	"metrics":         "customfield_31207|customfield_31208",
+	"fixed":           "customfield_20905",
+	"regions":         "customfield_21501",
+	"recovery":        "customfield_24800",
+	"detected":        "customfield_20911",
+	"escalated":       "customfield_21201",
+	"environment":     "customfield_29800",
+	"application":     "customfield_28222",
+	"businessprocess": "customfield_32112",
+	"score":           "customfield_30304",
+}
+
+// excludedStatuses are never fetched nor kept in the cache
+var excludedStatuses = []string{"Cancelled", "Rejected"}
+
+// incrementalSafetyWindow is subtracted from the last search time to tolerate clock skew with Jira
+const incrementalSafetyWindow = 5 * time.Minute
+
+const (
+	jiraDateTimeLayout = "2006-01-02T15:04:05.999-0700"
+	jiraDateLayout     = "2006-01-02"
+)
+
+// defaultTimeFormats parse the Jira timestamp format, RFC3339 and plain dates
+var defaultTimeFormats = []string{jiraDateTimeLayout, time.RFC3339Nano, jiraDateLayout}
+
 // JiraClient represents a wrapper around go-jira client with metrics and logging
 type JiraClient struct {
-	client          *jira.Client
-	baseURL         string
-	username        string
-	projectKey      string
-	queryFilter     string
-	refreshInterval int
-	obs             *Observability
-	metrics         *sre.Metrics
-	mu              sync.RWMutex
-	lastRefresh     time.Time
-	issueCache      map[string]*jira.Issue
-}
-
-// JiraIssue represents an issue with custom fields
+	client      *jira.Client
+	searcher    IssueSearcher
+	options     JiraOptions
+	location    *time.Location
+	dateOnly    map[string]bool
+	timeFormats []string
+	fields      map[string][]string
+	requested   []string
+	serviceMap  map[string]string
+	doneFields  []string
+	userFields  []string
+	severities  map[string]int
+	labelFilter []string
+	envFilter   []string
+	unmapped    map[string]bool
+	obs         *Observability
+	metrics     *sre.Metrics
+	mu          sync.RWMutex
+	lastRefresh time.Time
+	lastSearch  time.Time
+	issueCache  map[string]*jira.Issue
+	issues      []*JiraIssue
+	issuesByKey map[string]*JiraIssue
+	byProject   map[string][]*JiraIssue
+	started     time.Time
+	matched     int
+	saneTotal   int
+	totalOK     bool
+	audit       *AuditWriter
+	notifier    *Notifier
+	sinks       []Sink
+	breaker     *circuitBreaker
+	// alerted holds the alert eligible keys of the last refresh, nil until the first one, only used under refreshing
+	alerted map[string]bool
+	gauges  map[string]map[string]map[string]string
+	// durations sums the seconds observed by each duration histogram, for its _sum series
+	durations map[string]float64
+	// now is the clock of refreshes, replaced by tests
+	now func() time.Time
+	// connectTimeout bounds every attempt of the connection test
+	connectTimeout time.Duration
+	// refreshing is held during a refresh, so that refreshes run one at a time and Flush waits for the running one
+	refreshing chan struct{}
+	// reload is the on demand refresh in progress shared by overlapping Reload calls. Once the refresh loop
+	// started, reloads run with its refresh context and are tracked by its wait group until it stops.
+	reloadMu     sync.Mutex
+	reload       *reloadCall
+	reloadCtx    context.Context
+	reloadWG     *sync.WaitGroup
+	reloadClosed bool
+}
+
+// errRefreshStopped is returned by reloads requested after the refresh loop stopped
+var errRefreshStopped = errors.New("refresh loop is stopped")
+
+// reloadCall is an on demand refresh, done is closed once count and err are set
+type reloadCall struct {
+	done  chan struct{}
+	count int
+	err   error
+}
+
+// requestIDTransport sends the context correlation ID as X-Request-Id to correlate with Jira access logs
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := RequestID(req.Context()); id != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Request-Id", id)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// timeoutTransport limits a single request to the timeout, including reading the response body
+type timeoutTransport struct {
+	timeout time.Duration
+	base    http.RoundTripper
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the request context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// headerTransport adds custom headers to every request, leaving headers already set such as the
+// authorization of the auth transport untouched
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// JiraIssue represents an issue with custom fields,
+// zero timestamps are left out of JSON through omitzero
 type JiraIssue struct {
 	Key             string    `json:"key"`
+	Summary         string    `json:"summary,omitempty"`
+	Project         string    `json:"project,omitempty"`
+	Status          string    `json:"status,omitempty"`
+	Priority        string    `json:"priority,omitempty"`
+	Components      []string  `json:"components,omitempty"`
+	Labels          []string  `json:"labels,omitempty"`
 	Created         time.Time `json:"created"`
 	Updated         time.Time `json:"updated"`
-	Resolved        time.Time `json:"resolved,omitezero"`
+	Resolved        time.Time `json:"resolved,omitzero"`
 	Assignee        string    `json:"assignee,omitempty"`
-	Closed          time.Time `json:"closed,omitezero"`
+	AssigneeDisplay string    `json:"assignee_display,omitempty"`
+	AssigneeEmail   string    `json:"assignee_email,omitempty"`
+	Closed          time.Time `json:"closed,omitzero"`
 	Head            string    `json:"head,omitempty"`
-	Started         time.Time `json:"started,omitezero"`
-	Firefighting    time.Time `json:"firefighting,omitezero"`
-	Fixed           time.Time `json:"fixed,omitezero"`
+	Started         time.Time `json:"started,omitzero"`
+	Firefighting    time.Time `json:"firefighting,omitzero"`
+	Fixed           time.Time `json:"fixed,omitzero"`
 	Severity        string    `json:"severity,omitempty"`
 	Service         string    `json:"service,omitempty"`
 	RootCause       string    `json:"root_cause,omitempty"`
 	Regions         string    `json:"regions,omitempty"`
 	Recovery        string    `json:"recovery,omitempty"`
 	Reporter        string    `json:"reporter,omitempty"`
-	Detected        time.Time `json:"detected,omitezero"`
-	Escalated       time.Time `json:"escalated,omitezero"`
+	ReporterDisplay string    `json:"reporter_display,omitempty"`
+	Detected        time.Time `json:"detected,omitzero"`
+	Escalated       time.Time `json:"escalated,omitzero"`
 	Metrics         string    `json:"metrics,omitempty"`
 	IssueType       string    `json:"issuetype,omitempty"`
 	Environment     string    `json:"environment,omitempty"`
 	Application     string    `json:"application,omitempty"`
 	BusinessProcess string    `json:"businessprocess,omitempty"`
+	Impact          int       `json:"impact,omitempty"`
 	Score           int       `json:"score,omitempty"`
+	CommentCount    int       `json:"comment_count,omitempty"`
+	Done            time.Time `json:"done,omitzero"`
+
+	// Attachments is only filled when IncludeAttachments is set
+	Attachments []AttachmentInfo `json:"attachments,omitempty"`
 }
 
-func NewJiraClient(baseURL, username, apiToken, projectKey, queryFilter string, refreshInterval int, obs *Observability, metrics *sre.Metrics) (*JiraClient, error) {
-	tp := jira.BasicAuthTransport{
-		Username: username,
-		Password: apiToken,
-	}
+// AttachmentInfo is the metadata of an issue attachment, such as the logs or screenshots of a postmortem
+type AttachmentInfo struct {
+	Filename string    `json:"filename"`
+	Size     int       `json:"size"`
+	Created  time.Time `json:"created,omitzero"`
+	Author   string    `json:"author,omitempty"`
+}
 
-	client, err := jira.NewClient(tp.Client(), baseURL)
-	if err != nil {
-		return nil, fmt.Errorf("error creating jira client: %w", err)
+// HasLabels reports whether the issue carries all of the labels
+func (i *JiraIssue) HasLabels(labels []string) bool {
+	for _, label := range labels {
+		if !slices.Contains(i.Labels, label) {
+			return false
+		}
 	}
+	return true
+}
 
-	return &JiraClient{
-		client:          client,
-		baseURL:         baseURL,
-		username:        username,
-		projectKey:      projectKey,
-		queryFilter:     queryFilter,
-		refreshInterval: refreshInterval,
-		obs:             obs,
-		metrics:         metrics,
-		issueCache:      make(map[string]*jira.Issue),
-	}, nil
+// IsOpen reports whether none of the configured terminal timestamps is set
+func (i *JiraIssue) IsOpen() bool {
+	return i.Done.IsZero()
 }
 
-// GetIssues retrieves issues from Jira based on project key and filters similar to the old implementation
-func (j *JiraClient) GetIssues(ctx context.Context) ([]*jira.Issue, error) {
-	startTime := time.Now()
+func NewJiraClient(options JiraOptions, obs *Observability, metrics *sre.Metrics) (*JiraClient, error) {
+	obs = obs.WithTenant(options.Tenant)
 
-	// Default JQL similar to the old implementation
-	jql := fmt.Sprintf("project = %s AND status not in (Cancelled,Rejected) AND created>=startOfYear(-1y) ORDER BY created DESC", j.projectKey)
+	// Refuse to run without any scoping, it would query the whole Jira instance
+	if projectClause(options.ProjectKey) == "" && strings.TrimSpace(options.QueryFilter) == "" && strings.TrimSpace(options.JQL) == "" {
+		return nil, fmt.Errorf("jira query is not scoped: set a project key, a query filter or a custom jql")
+	}
 
-	// Apply additional filter if specified
-	if j.queryFilter != "" {
-		jql = fmt.Sprintf("%s AND %s", jql, j.queryFilter)
-	}
-
-	j.obs.Info("Querying Jira with JQL: %s", jql)
-
-	// Use pagination to get all issues, but try to get a larger batch size like the old implementation
-	var allIssues []*jira.Issue
-	startAt := 0
-	maxResults := 1000 // Trying to match the old value of 100000 is unrealistic, most APIs cap at lower values
-
-	for {
-		options := &jira.SearchOptions{
-			StartAt:    startAt,
-			MaxResults: maxResults,
-			Fields: []string{
-				"key", "created", "updated", "resolutiondate", "assignee",
-				"customfield_22501", "customfield_18117", "customfield_21200",
-				"customfield_20908", "customfield_20905", "customfield_18119",
-				"customfield_33803", "customfield_21501", "customfield_24800",
-				"customfield_20911", "customfield_21201", "reporter",
-				"customfield_31207", "customfield_31208", "issuetype",
-				"customfield_29800", "customfield_28222", "customfield_32112",
-				"customfield_30304", "customfield_37238",
-			},
-		}
-
-		chunk, _, err := j.client.Issue.Search(jql, options)
-		if err != nil {
-			j.obs.Error("HTTP request failed: %v", err)
-			return nil, fmt.Errorf("error searching issues: %w", err)
+	if options.UseChangelog {
+		if err := validateStatusStages(options.StatusStageMap); err != nil {
+			return nil, err
 		}
+	}
+	if options.ChangelogFallback && !options.UseChangelog {
+		return nil, fmt.Errorf("changelog fallback requires the changelog to be used")
+	}
 
-		if len(chunk) == 0 {
-			break
-		}
+	if err := validateLookback(options); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(options.JQL) == "" {
+		obs.Info("Fetching issues created since %s", lookback(options))
+	}
 
-		// Convert []jira.Issue to []*jira.Issue
-		for i := range chunk {
-			allIssues = append(allIssues, &chunk[i])
-			j.updateIssueCache(&chunk[i])
-		}
+	if options.MaxResults < 0 || options.MaxResults > 1000 {
+		return nil, fmt.Errorf("invalid max results %d, expected 1-1000", options.MaxResults)
+	}
+	if options.MaxIssues < 0 {
+		return nil, fmt.Errorf("invalid max issues %d", options.MaxIssues)
+	}
+	if options.WorkloadThreshold < 0 {
+		return nil, fmt.Errorf("invalid workload threshold %d", options.WorkloadThreshold)
+	}
+	if options.ConvertWorkers < 0 {
+		return nil, fmt.Errorf("invalid convert workers %d", options.ConvertWorkers)
+	}
+	if options.CircuitFailures < 0 {
+		return nil, fmt.Errorf("invalid circuit breaker failures %d", options.CircuitFailures)
+	}
 
-		if len(chunk) < maxResults {
-			break
-		}
+	base, err := baseTransport(options)
+	if err != nil {
+		return nil, err
+	}
+	if options.InsecureSkipVerify {
+		obs.Warn("TLS certificate verification of Jira is DISABLED, connections can be intercepted")
+	}
 
-		startAt += len(chunk)
+	if len(options.CustomHeaders) > 0 {
+		base = &headerTransport{headers: options.CustomHeaders, base: base}
 	}
-	// Record metric for API call duration
-	if j.metrics != nil {
-		if j.metrics != nil {
-			j.obs.Info("API call duration: %f seconds", time.Since(startTime).Seconds())
-		}
+	// The timeout bounds every attempt, so that waiting for the rate limiter or a Retry-After is not cut off
+	timeout := time.Duration(options.HTTPTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+	attempt := &timeoutTransport{timeout: timeout, base: &requestIDTransport{base: base}}
+	limited := newRateLimitTransport(options.RequestsPerSecond, options.RateLimitWarnBelow, obs, metrics, tenantLabels(options.Tenant, nil), attempt)
+	transport, err := authTransport(options, limited)
+	if err != nil {
+		return nil, err
 	}
 
-	j.obs.Info("Retrieved %d issues from Jira", len(allIssues))
-	return allIssues, nil
-}
+	client, err := jira.NewClient(&http.Client{Transport: transport}, options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error creating jira client: %w", err)
+	}
 
-// ConvertToCustomIssues transforms jira.Issue objects into our custom JiraIssue format with the fields we care about
-func (j *JiraClient) ConvertToCustomIssues(issues []*jira.Issue) ([]*JiraIssue, error) {
-	customIssues := make([]*JiraIssue, 0, len(issues))
+	timezone := options.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
 
-	for _, issue := range issues {
-		customIssue := &JiraIssue{
-			Key: issue.Key,
+	mapping := options.FieldMapping
+	if len(mapping) == 0 {
+		mapping = defaultFieldMapping
+	}
+	fields := make(map[string][]string, len(mapping))
+	for name, ids := range mapping {
+		for _, id := range strings.Split(ids, "|") {
+			if id = strings.TrimSpace(id); id != "" {
+				fields[name] = append(fields[name], id)
+			}
 		}
+	}
 
-		// Extract standard fields that are already in a usable format
-		if issue.Fields.Assignee != nil {
-			customIssue.Assignee = issue.Fields.Assignee.Name
+	// Date-only fields may be given by logical name or by custom field ID
+	dateOnly := make(map[string]bool)
+	for _, field := range options.DateOnlyFields {
+		field = strings.TrimSpace(field)
+		if ids, ok := fields[field]; ok {
+			for _, id := range ids {
+				dateOnly[id] = true
+			}
+		} else if field != "" {
+			dateOnly[field] = true
 		}
+	}
 
-		if issue.Fields.Reporter != nil {
-			customIssue.Reporter = issue.Fields.Reporter.Name
+	var timeFormats []string
+	for _, format := range options.TimeFormats {
+		if format = strings.TrimSpace(format); format != "" {
+			timeFormats = append(timeFormats, format)
 		}
+	}
+	if len(timeFormats) == 0 {
+		timeFormats = defaultTimeFormats
+	}
 
-		// Jira time fields come as jira.Time type which is already a time.Time
-		customIssue.Created = time.Time(issue.Fields.Created)
-		customIssue.Updated = time.Time(issue.Fields.Updated)
-
-		if !time.Time(issue.Fields.Resolutiondate).IsZero() {
-			customIssue.Resolved = time.Time(issue.Fields.Resolutiondate)
+	var requested []string
+	for _, field := range options.RequestFields {
+		if field = strings.TrimSpace(field); field != "" {
+			requested = append(requested, field)
 		}
+	}
 
-		if issue.Fields.Type.Name != "" {
-			customIssue.IssueType = issue.Fields.Type.Name
+	var userFields []string
+	for _, field := range options.UserFields {
+		switch field = strings.TrimSpace(field); field {
+		case "":
+		case "name", "key", "accountId", "displayName":
+			userFields = append(userFields, field)
+		default:
+			return nil, fmt.Errorf("unsupported user field %q, use name, key, accountId or displayName", field)
 		}
+	}
+	if len(userFields) == 0 {
+		userFields = []string{"name", "key", "accountId", "displayName"}
+	}
 
-		// Extract custom fields based on the old implementation
-		// These will need adjustments based on your actual Jira instance
-		// customfield_20908 (closed)
-		if val, ok := issue.Fields.Unknowns["customfield_20908"].(string); ok && val != "" {
-			if t, err := time.Parse("2006-01-02T15:04:05.999-0700", val); err == nil {
-				customIssue.Closed = t
-			}
+	var doneFields []string
+	for _, field := range options.DoneFields {
+		switch field = strings.TrimSpace(field); field {
+		case "":
+		case "resolved", "closed", "fixed":
+			doneFields = append(doneFields, field)
+		default:
+			return nil, fmt.Errorf("unsupported done field %q, use resolved, closed or fixed", field)
 		}
-
-		// customfield_22501 (head)
-		if val, ok := issue.Fields.Unknowns["customfield_22501"].(map[string]interface{}); ok {
-			if name, ok := val["name"].(string); ok {
-				customIssue.Head = name
+	}
+	if len(doneFields) == 0 {
+		doneFields = []string{"resolved"}
+	}
+	severities := make(map[string]int)
+	for _, severity := range options.SeverityOrder {
+		if severity = strings.TrimSpace(severity); severity != "" {
+			if _, ok := severities[severity]; !ok {
+				severities[severity] = len(severities)
 			}
 		}
-
-		// customfield_18117 (started)
-		if val, ok := issue.Fields.Unknowns["customfield_18117"].(string); ok && val != "" {
-			if t, err := time.Parse("2006-01-02T15:04:05.999-0700", val); err == nil {
-				customIssue.Started = t
-			}
+	}
+	var labelFilter []string
+	for _, label := range options.LabelFilter {
+		if label = strings.TrimSpace(label); label != "" {
+			labelFilter = append(labelFilter, label)
+		}
+	}
+	// Environments are compared with the issue values, which went through the synonyms
+	var envFilter []string
+	for _, environment := range options.OnlyEnvironments {
+		if environment = strings.TrimSpace(environment); environment != "" {
+			envFilter = append(envFilter, normalize(environment, options.EnvironmentSynonyms))
+		}
+	}
+	if options.MinSeverityForAlerts != "" {
+		if _, ok := severities[options.MinSeverityForAlerts]; !ok {
+			return nil, fmt.Errorf("minimum alert severity %q is not in the severity order", options.MinSeverityForAlerts)
 		}
+	}
 
-		// customfield_21200 (firefighting)
-		if val, ok := issue.Fields.Unknowns["customfield_21200"].(string); ok && val != "" {
-			if t, err := time.Parse("2006-01-02T15:04:05.999-0700", val); err == nil {
-				customIssue.Firefighting = t
-			}
+	switch options.RefreshScope {
+	case "", "full", "open":
+	default:
+		return nil, fmt.Errorf("unsupported refresh scope %q, use full or open", options.RefreshScope)
+	}
+	if options.OnlyUnresolved && options.OnlyResolved {
+		return nil, fmt.Errorf("only unresolved and only resolved issues exclude each other")
+	}
+
+	switch options.DoneSelect {
+	case "", "first", "earliest", "latest":
+	default:
+		return nil, fmt.Errorf("unsupported done selection %q, use first, earliest or latest", options.DoneSelect)
+	}
+
+	serviceMap, err := loadServiceMap(options.ServiceMapFile, options.ServiceMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var searcher IssueSearcher = &jiraSearcher{client: client}
+	switch {
+	case options.ReplayDir != "":
+		obs.Warn("Replaying Jira search responses from %s", options.ReplayDir)
+		searcher = NewReplaySearcher(options.ReplayDir)
+	case options.RecordDir != "":
+		obs.Info("Recording Jira search responses to %s", options.RecordDir)
+		if searcher, err = NewRecordingSearcher(searcher, options.RecordDir); err != nil {
+			return nil, err
 		}
+	}
 
-		// More custom fields based on the old implementation
-		// Severity
-		if val, ok := issue.Fields.Unknowns["customfield_18119"].(map[string]interface{}); ok {
-			if value, ok := val["value"].(string); ok {
-				customIssue.Severity = value
-			}
+	connectTimeout := time.Duration(options.ConnectTimeout) * time.Second
+	if connectTimeout <= 0 {
+		connectTimeout = defaultConnectTimeout
+	}
+
+	var breaker *circuitBreaker
+	if options.CircuitFailures > 0 {
+		cooldown := time.Duration(options.CircuitCooldown) * time.Second
+		if cooldown <= 0 {
+			cooldown = defaultCircuitCooldown
 		}
+		breaker = newCircuitBreaker(options.CircuitFailures, cooldown, obs, metrics, tenantLabels(options.Tenant, nil))
+	}
 
-		// Service
-		if val, ok := issue.Fields.Unknowns["customfield_33803"].([]interface{}); ok && len(val) > 0 {
-			if serviceVal, ok := val[0].(string); ok {
-				customIssue.Service = serviceVal
-			}
+	j := &JiraClient{
+		client:         client,
+		searcher:       searcher,
+		options:        options,
+		location:       location,
+		dateOnly:       dateOnly,
+		timeFormats:    timeFormats,
+		fields:         fields,
+		requested:      requested,
+		serviceMap:     serviceMap,
+		doneFields:     doneFields,
+		userFields:     userFields,
+		severities:     severities,
+		labelFilter:    labelFilter,
+		envFilter:      envFilter,
+		breaker:        breaker,
+		unmapped:       make(map[string]bool),
+		obs:            obs,
+		metrics:        metrics,
+		issueCache:     make(map[string]*jira.Issue),
+		started:        time.Now(),
+		gauges:         make(map[string]map[string]map[string]string),
+		durations:      make(map[string]float64),
+		now:            time.Now,
+		refreshing:     make(chan struct{}, 1),
+		connectTimeout: connectTimeout,
+	}
+	j.sinks = []Sink{memorySink{j}}
+	if options.CacheFilePath != "" {
+		j.sinks = append(j.sinks, cacheFileSink{j})
+	}
+	return j, nil
+}
+
+// ErrPartial marks a search which failed after some pages were fetched, the issues of those pages are
+// returned along with the error
+var ErrPartial = errors.New("partial results")
+
+// ErrAuth marks a request Jira refused with 401 or 403, the credentials or their permissions are wrong and
+// repeating the request can't help
+var ErrAuth = errors.New("jira rejected the credentials")
+
+// GetIssues retrieves the full history of issues from Jira based on project key and filters similar to the old implementation.
+// An error wrapping ErrPartial comes with the issues fetched before the failure.
+func (j *JiraClient) GetIssues(ctx context.Context) ([]*jira.Issue, error) {
+	return j.searchIssues(ctx, j.buildJQL(false, time.Time{}))
+}
+
+// GetIssuesCreatedBetween runs the full historical query for issues created within the window instead of
+// the lookback, a zero bound leaves that side open. The bounds are JQL dates in the Jira user timezone.
+func (j *JiraClient) GetIssuesCreatedBetween(ctx context.Context, since, until time.Time) ([]*jira.Issue, error) {
+	if strings.TrimSpace(j.options.JQL) != "" {
+		return nil, fmt.Errorf("a created window can't be combined with a custom jql")
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return nil, fmt.Errorf("created window ends at %s before it starts at %s", until.Format(jqlTimeLayout), since.Format(jqlTimeLayout))
+	}
+	return j.searchIssues(ctx, j.windowJQL(since, until))
+}
+
+// searchIssues pages through all issues matching the JQL
+func (j *JiraClient) searchIssues(ctx context.Context, jql string) ([]*jira.Issue, error) {
+	obs := j.obs.WithContext(ctx)
+	startTime := time.Now()
+
+	j.setTimestampGauge("last_search_start_timestamp", "Unix time the last Jira search started", startTime)
+
+	obs.Info("Querying Jira with JQL: %s", jql)
+
+	maxResults := j.options.MaxResults
+	if maxResults == 0 {
+		maxResults = defaultMaxResults
+	}
+	capped := j.options.MaxIssues
+	if capped > 0 {
+		maxResults = min(maxResults, capped)
+	}
+	concurrency := j.options.FetchConcurrency
+	if concurrency == 0 {
+		concurrency = defaultFetchConcurrency
+	}
+
+	// The first page tells the matched total and the page size the server actually serves
+	allIssues, matched, err := j.searchPage(ctx, obs, jql, 0, maxResults)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := len(allIssues)
+
+	// Pages beyond the issue cap are not fetched
+	wanted := matched
+	if capped > 0 && matched > capped {
+		obs.Info("Fetching %d of %d matched issues, capped by the max issues", capped, matched)
+		wanted = capped
+	}
+
+	pages := 1
+	if wanted > pageSize && pageSize > 0 && concurrency > 1 {
+		rest, fetched, err := j.searchPagesConcurrently(ctx, obs, jql, pageSize, wanted, concurrency)
+		allIssues = append(allIssues, rest...)
+		pages += fetched
+		if err != nil {
+			return j.partialSearch(ctx, obs, allIssues, pages, (matched+pageSize-1)/pageSize, err)
 		}
+	} else {
+		startAt := pageSize
+		for last := pageSize; last > 0 && morePages(startAt, last, wanted, maxResults); startAt += last {
+			if capped > 0 && len(allIssues) >= capped {
+				break
+			}
+			// Stop paging promptly once the refresh is cancelled
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 
-		// Root Cause
-		if val, ok := issue.Fields.Unknowns["customfield_37238"].([]interface{}); ok && len(val) > 0 {
-			if causeVal, ok := val[0].(string); ok {
-				customIssue.RootCause = causeVal
+			chunk, _, err := j.searchPage(ctx, obs, jql, startAt, maxResults)
+			if err != nil {
+				total := 0
+				if matched > 0 {
+					total = (matched + pageSize - 1) / pageSize
+				}
+				return j.partialSearch(ctx, obs, allIssues, pages, total, err)
 			}
+			allIssues = append(allIssues, chunk...)
+			last = len(chunk)
+			pages++
 		}
+	}
 
-		customIssues = append(customIssues, customIssue)
+	// Pages of a dataset changing during the search may overlap or shift
+	allIssues = dedupeIssues(allIssues)
+	if capped > 0 && len(allIssues) > capped {
+		allIssues = allIssues[:capped]
 	}
 
-	return customIssues, nil
-}
+	// Record metrics for API call duration and fetched issues
+	if j.metrics != nil {
+		j.observeDuration("jira_query_duration_seconds", "Duration of a full paginated Jira query", time.Since(startTime))
+		j.metrics.Counter(metricsGroup, "jira_issues_fetched_total", "Count of issues fetched from Jira", j.labels(nil)).Add(len(allIssues))
+	}
+	obs.Debug("API call duration: %f seconds", time.Since(startTime).Seconds())
 
-// updateIssueCache updates the local issue cache
-func (j *JiraClient) updateIssueCache(issue *jira.Issue) {
+	j.setTimestampGauge("last_search_end_timestamp", "Unix time the last successful Jira search finished", time.Now())
+
+	if matched == 0 {
+		matched = len(allIssues)
+	}
 	j.mu.Lock()
-	defer j.mu.Unlock()
-	j.issueCache[issue.ID] = issue
+	j.matched = matched
+	j.mu.Unlock()
+
+	obs.Info("Retrieved %d issues in %d pages from Jira", len(allIssues), pages)
+	return allIssues, nil
 }
 
-// StartRefreshLoop begins a loop to periodically refresh Jira data
-func (j *JiraClient) StartRefreshLoop(ctx context.Context, wg *sync.WaitGroup) {
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+// partialSearch returns the issues fetched before a page failed with an error wrapping ErrPartial,
+// total is the number of pages expected or 0 when unknown. A cancelled search has no partial results.
+func (j *JiraClient) partialSearch(ctx context.Context, obs *Observability, issues []*jira.Issue, pages, total int, err error) ([]*jira.Issue, error) {
+	if ctx.Err() != nil {
+		return nil, err
+	}
 
-		ticker := time.NewTicker(time.Duration(j.refreshInterval) * time.Second)
-		defer ticker.Stop()
+	expected := "unknown"
+	if total > 0 {
+		expected = strconv.Itoa(total)
+	}
+	obs.Warn("Jira search failed after %d of %s pages with %d issues", pages, expected, len(issues))
+	return dedupeIssues(issues), fmt.Errorf("%w: %d of %s pages fetched: %w", ErrPartial, pages, expected, err)
+}
 
-		// Initial load
-		j.RefreshData(ctx)
+// dedupeIssues keeps the most recently updated copy of every issue and sorts them by creation time,
+// newest first, then by key for a deterministic order
+func dedupeIssues(issues []*jira.Issue) []*jira.Issue {
+	index := make(map[string]int, len(issues))
+	deduped := make([]*jira.Issue, 0, len(issues))
+	for _, issue := range issues {
+		n, ok := index[issue.Key]
+		if !ok {
+			index[issue.Key] = len(deduped)
+			deduped = append(deduped, issue)
+			continue
+		}
+		if time.Time(issue.Fields.Updated).After(time.Time(deduped[n].Fields.Updated)) {
+			deduped[n] = issue
+		}
+	}
 
-		// Periodic refresh
-		for {
-			select {
-			case <-ctx.Done():
-				j.obs.Info("Stopping Jira refresh loop due to context cancellation")
-				return
-			case <-ticker.C:
-				j.RefreshData(ctx)
-			}
+	sort.SliceStable(deduped, func(a, b int) bool {
+		created, other := time.Time(deduped[a].Fields.Created), time.Time(deduped[b].Fields.Created)
+		if !created.Equal(other) {
+			return created.After(other)
 		}
-	}()
+		return deduped[a].Key < deduped[b].Key
+	})
+	return deduped
 }
 
-// RefreshData fetches the latest data from Jira
-func (j *JiraClient) RefreshData(ctx context.Context) {
-	j.obs.Info("Refreshing Jira data...")
+// morePages reports whether pages are left after the last one. Servers may cap the page size below
+// the requested one, so a short page only ends the search when the matched total is unknown.
+func morePages(startAt, last, matched, maxResults int) bool {
+	if matched > 0 {
+		return startAt < matched
+	}
+	return last >= maxResults
+}
 
-	issues, err := j.GetIssues(ctx)
-	if err != nil {
-		j.obs.Error("Failed to refresh Jira data: %v", err)
-		return
+// searchPage fetches a single page of issues along with the matched total reported by Jira
+func (j *JiraClient) searchPage(ctx context.Context, obs *Observability, jql string, startAt, maxResults int) ([]*jira.Issue, int, error) {
+	options := &jira.SearchOptions{
+		StartAt:    startAt,
+		MaxResults: maxResults,
+		Fields:     j.requestFields(),
+	}
+	if j.options.UseChangelog {
+		options.Expand = "changelog"
 	}
 
-	// Convert to custom issues with the fields we care about
-	customIssues, err := j.ConvertToCustomIssues(issues)
+	ctx, span := obs.StartSpan(ctx, "jira.search")
+	defer span.Finish()
+	span.SetTag("jql", jql)
+	span.SetTag("start_at", startAt)
+	span.SetTag("page", startAt/maxResults+1)
+
+	chunk, resp, err := j.searchWithRetry(ctx, obs, jql, options)
 	if err != nil {
-		j.obs.Error("Failed to process Jira issues: %v", err)
-		return
+		span.Error(err)
+		// Cancelled requests are not Jira failures
+		if ctx.Err() == nil {
+			j.reportHttpError(obs, httpResponse(resp), err)
+		}
+		return nil, 0, fmt.Errorf("error searching issues: %w", err)
 	}
 
-	j.mu.Lock()
-	j.lastRefresh = time.Now()
-	j.mu.Unlock()
-
-	j.obs.Info("Jira data refreshed successfully. Total issues: %d", len(customIssues))
+	// Convert []jira.Issue to []*jira.Issue
+	issues := make([]*jira.Issue, 0, len(chunk))
+	for i := range chunk {
+		issues = append(issues, &chunk[i])
+	}
 
-	// Display some issue details for debugging
-	if len(customIssues) > 0 {
-		j.obs.Info("Latest issue: %s, created: %s",
-			customIssues[0].Key,
-			customIssues[0].Created.Format(time.RFC3339))
+	matched := 0
+	if resp != nil {
+		matched = resp.Total
 	}
+	span.SetTag("results", len(issues))
+	return issues, matched, nil
 }
 
-// GetLastRefreshTime returns the timestamp of the last successful data refresh
-func (j *JiraClient) GetLastRefreshTime() time.Time {
-	j.mu.RLock()
-	defer j.mu.RUnlock()
-	return j.lastRefresh
+// searchPagesConcurrently fetches the pages following the first one with a bounded number of workers,
+// returning the issues of the fetched pages in page order and their count. The first failed page cancels
+// the remaining ones.
+func (j *JiraClient) searchPagesConcurrently(ctx context.Context, obs *Observability, jql string, pageSize, matched, concurrency int) ([]*jira.Issue, int, error) {
+	var offsets []int
+	for startAt := pageSize; startAt < matched; startAt += pageSize {
+		offsets = append(offsets, startAt)
+	}
+	pages := make([][]*jira.Issue, len(offsets))
+
+	pageCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	next := make(chan int)
+	for w := 0; w < min(concurrency, len(offsets)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				chunk, _, err := j.searchPage(pageCtx, obs, jql, offsets[i], pageSize)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				pages[i] = chunk
+			}
+		}()
+	}
+
+feed:
+	for i := range offsets {
+		select {
+		case next <- i:
+		case <-pageCtx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	var (
+		issues  []*jira.Issue
+		fetched int
+	)
+	for _, page := range pages {
+		if page != nil {
+			fetched++
+		}
+		issues = append(issues, page...)
+	}
+
+	if firstErr != nil {
+		return issues, fetched, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	return issues, fetched, nil
 }
 
-// TestConnection verifies connection to Jira
-func (j *JiraClient) TestConnection() error {
-	// The go-jira library doesnt have a Myself method, use the Current User API instead
-	user, _, err := j.client.User.GetSelf()
-	if err != nil {
-		j.obs.Error("HTTP request failed: %v", err)
-		return fmt.Errorf("jira connection test failed: %w", err)
+// defaultLookback keeps the two year window of the original implementation
+const defaultLookback = "startOfYear(-1y)"
+
+// lookback returns the JQL value issues are fetched from
+func lookback(options JiraOptions) string {
+	if options.LookbackDays > 0 {
+		return fmt.Sprintf("-%dd", options.LookbackDays)
+	}
+	if value := strings.TrimSpace(options.LookbackJQL); value != "" {
+		return value
 	}
+	return defaultLookback
+}
 
-	j.obs.Info("Successfully connected to Jira as %s", user.Name)
+// validateLookback rejects values that would break the generated query
+func validateLookback(options JiraOptions) error {
+	if options.LookbackDays < 0 {
+		return fmt.Errorf("invalid lookback days %d", options.LookbackDays)
+	}
+	value := strings.TrimSpace(options.LookbackJQL)
+	if strings.Count(value, "(") != strings.Count(value, ")") || strings.Contains(strings.ToLower(value), "order by") {
+		return fmt.Errorf("invalid lookback %q, expected a JQL date or function like startOfYear(-1y) or \"2024/01/01\"", value)
+	}
 	return nil
 }
 
-// reportHttpError logs HTTP response details on error
-func (j *JiraClient) reportHttpError(resp *http.Response, err error) {
-	if resp == nil {
-		j.obs.Error("HTTP request failed with no response: %v", err)
-		return
+// projectClause builds the JQL project selection from a comma-separated list of project keys
+func projectClause(projectKey string) string {
+	var keys []string
+	for _, key := range strings.Split(projectKey, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	switch len(keys) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("project = %s", keys[0])
+	default:
+		return fmt.Sprintf("project in (%s)", strings.Join(keys, ","))
 	}
+}
 
-	j.obs.Error("HTTP request failed - Status: %d, Error: %v", resp.StatusCode, err)
+// jqlTimeLayout is the JQL date format with minute precision
+const jqlTimeLayout = "2006/01/02 15:04"
 
-	// Record metric for API errors
-	if j.metrics != nil {
-		j.obs.Info("API error with status code: %d", resp.StatusCode)
+// buildJQL assembles the search query from the project clause, default filters and the additional query filter,
+// limited to issues not in a done status category when openOnly is set. With updatedSince set it selects issues
+// changed since then regardless of status, so that issues leaving the scope can be dropped from the cache.
+// A custom JQL option overrides all of it.
+func (j *JiraClient) buildJQL(openOnly bool, updatedSince time.Time) string {
+	// A custom query is used as is, nothing is appended to it
+	if jql := strings.TrimSpace(j.options.JQL); jql != "" {
+		return jql
+	}
+
+	// Lookback window, two years by default like the original implementation
+	return j.scopedJQL([]string{"created>=" + lookback(j.options)}, openOnly, updatedSince) + " ORDER BY created DESC"
+}
+
+// windowJQL is the full historical query limited to issues created within the window, ignoring the lookback
+func (j *JiraClient) windowJQL(since, until time.Time) string {
+	var created []string
+	if !since.IsZero() {
+		created = append(created, fmt.Sprintf(`created >= "%s"`, since.In(j.location).Format(jqlTimeLayout)))
+	}
+	if !until.IsZero() {
+		created = append(created, fmt.Sprintf(`created <= "%s"`, until.In(j.location).Format(jqlTimeLayout)))
+	}
+	return j.scopedJQL(created, false, time.Time{}) + " ORDER BY created DESC"
+}
+
+// scopedJQL joins the project clause, the created clauses, the status or update selection and the query
+// filter, the caller appends the order
+func (j *JiraClient) scopedJQL(created []string, openOnly bool, updatedSince time.Time) string {
+	var clauses []string
+	if project := projectClause(j.options.ProjectKey); project != "" {
+		clauses = append(clauses, project)
+	}
+
+	clauses = append(clauses, created...)
+	if updatedSince.IsZero() {
+		clauses = append(clauses, fmt.Sprintf("status not in (%s)", strings.Join(excludedStatuses, ",")))
+		if openOnly {
+			clauses = append(clauses, "statusCategory != Done")
+		}
+	} else {
+		// JQL dates have minute precision and are interpreted in the Jira user timezone
+		clauses = append(clauses, fmt.Sprintf(`updated >= "%s"`, updatedSince.In(j.location).Format(jqlTimeLayout)))
+	}
+
+	// Apply additional filter if specified
+	if filter := strings.TrimSpace(j.options.QueryFilter); filter != "" {
+		clauses = append(clauses, fmt.Sprintf("(%s)", filter))
+	}
+
+	return strings.Join(clauses, " AND ")
+}
+
+// ConvertToCustomIssues transforms jira.Issue objects into our custom JiraIssue format with the fields we care about
+func (j *JiraClient) ConvertToCustomIssues(issues []*jira.Issue) ([]*JiraIssue, error) {
+	customIssues := make([]*JiraIssue, len(issues))
+
+	workers := j.options.ConvertWorkers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, len(issues))
+	if workers <= 1 {
+		for n, issue := range issues {
+			customIssues[n] = j.convertIssue(issue)
+		}
+		return customIssues, nil
+	}
+
+	// Issues are converted independently, every worker fills the positions of its share to keep the order
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := worker; n < len(issues); n += workers {
+				customIssues[n] = j.convertIssue(issues[n])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return customIssues, nil
+}
+
+// convertIssue extracts the fields we care about from a Jira issue, it only reads the client settings
+// so that issues can be converted concurrently
+func (j *JiraClient) convertIssue(issue *jira.Issue) *JiraIssue {
+	customIssue := &JiraIssue{
+		Key:     issue.Key,
+		Summary: issue.Fields.Summary,
+		Project: issue.Fields.Project.Key,
+	}
+	if customIssue.Project == "" {
+		customIssue.Project, _, _ = strings.Cut(issue.Key, "-")
+	}
+
+	// Extract standard fields that are already in a usable format
+	customIssue.Assignee = j.userName(issue.Fields.Assignee)
+	customIssue.AssigneeDisplay = j.userDisplay(issue.Fields.Assignee)
+	customIssue.Reporter = j.userName(issue.Fields.Reporter)
+	customIssue.ReporterDisplay = j.userDisplay(issue.Fields.Reporter)
+	if issue.Fields.Assignee != nil {
+		customIssue.AssigneeEmail = issue.Fields.Assignee.EmailAddress
+	}
+
+	// Jira time fields come as jira.Time type which is already a time.Time
+	customIssue.Created = time.Time(issue.Fields.Created)
+	customIssue.Updated = time.Time(issue.Fields.Updated)
+
+	if !time.Time(issue.Fields.Resolutiondate).IsZero() {
+		customIssue.Resolved = time.Time(issue.Fields.Resolutiondate)
+	}
+
+	if issue.Fields.Type.Name != "" {
+		customIssue.IssueType = issue.Fields.Type.Name
+	}
+
+	if issue.Fields.Status != nil {
+		customIssue.Status = issue.Fields.Status.Name
+	}
+
+	if issue.Fields.Priority != nil {
+		customIssue.Priority = issue.Fields.Priority.Name
+	}
+
+	customIssue.Labels = issue.Fields.Labels
+
+	for _, component := range issue.Fields.Components {
+		if component != nil && component.Name != "" {
+			customIssue.Components = append(customIssue.Components, component.Name)
+		}
+	}
+
+	// Extract custom fields through the field mapping, unmapped fields stay empty
+	unknowns := issue.Fields.Unknowns
+
+	if t, ok := j.fieldTime(unknowns, "closed"); ok {
+		customIssue.Closed = t
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "head"); ok {
+		if name, ok := j.userValue(value); ok {
+			customIssue.Head = name
+		}
+	}
+
+	if t, ok := j.fieldTime(unknowns, "started"); ok {
+		customIssue.Started = t
+	}
+
+	if t, ok := j.fieldTime(unknowns, "firefighting"); ok {
+		customIssue.Firefighting = t
+	}
+
+	if t, ok := j.fieldTime(unknowns, "fixed"); ok {
+		customIssue.Fixed = t
+	}
+
+	if t, ok := j.fieldTime(unknowns, "detected"); ok {
+		customIssue.Detected = t
+	}
+
+	if t, ok := j.fieldTime(unknowns, "escalated"); ok {
+		customIssue.Escalated = t
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "regions"); ok {
+		if regions, ok := asStringSlice(value); ok {
+			customIssue.Regions = strings.Join(regions, ",")
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "recovery"); ok {
+		if recovery, ok := asString(value); ok {
+			customIssue.Recovery = recovery
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "environment"); ok {
+		if environment, ok := asString(value); ok {
+			customIssue.Environment = normalize(environment, j.options.EnvironmentSynonyms)
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "application"); ok {
+		if application, ok := asString(value); ok {
+			customIssue.Application = application
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "businessprocess"); ok {
+		if process, ok := asString(value); ok {
+			customIssue.BusinessProcess = process
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "score"); ok {
+		if impact, ok := asFloat(value); ok {
+			customIssue.Impact = int(impact)
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "severity"); ok {
+		if severity, ok := asOptionValue(value); ok {
+			customIssue.Severity = j.normalizeSeverity(issue.Key, severity)
+		}
+	}
+
+	// Fall back to the standard priority for projects without the severity field
+	if customIssue.Severity == "" && issue.Fields.Priority != nil {
+		if severity, ok := j.options.PriorityMap[issue.Fields.Priority.Name]; ok {
+			j.obs.Debug("Issue %s has no severity, using %s from priority %s", issue.Key, severity, issue.Fields.Priority.Name)
+			customIssue.Severity = severity
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "service"); ok {
+		if service, ok := asString(value); ok {
+			customIssue.Service = service
+		}
+	}
+
+	// Metrics, a link or identifier of the related dashboard
+	if value, _, ok := j.fieldValue(unknowns, "metrics"); ok {
+		if link, ok := asLink(value); ok {
+			customIssue.Metrics = link
+		}
+	}
+
+	// Environment sources, when configured, take precedence over the mapped field
+	if value, ok := fromSources(issue, j.options.EnvironmentSources, j.options.EnvironmentSynonyms); ok {
+		customIssue.Environment = value
+	}
+
+	// Components mapped through the service table take precedence over the custom field
+	if service, ok := j.componentService(issue.Fields.Components); ok {
+		customIssue.Service = service
+	} else if customIssue.Service == "" && len(j.serviceMap) > 0 {
+		if name, ok := firstComponent(issue.Fields.Components); ok {
+			customIssue.Service = name
+		}
+	}
+
+	if value, _, ok := j.fieldValue(unknowns, "root_cause"); ok {
+		if cause, ok := asString(value); ok {
+			customIssue.RootCause = cause
+		}
+	}
+
+	if j.options.UseChangelog {
+		j.applyChangelog(issue, customIssue)
+	}
+
+	if j.options.IncludeCommentCounts && issue.Fields.Comments != nil {
+		customIssue.CommentCount = len(issue.Fields.Comments.Comments)
+	}
+	if j.options.IncludeAttachments {
+		customIssue.Attachments = j.attachments(issue.Fields.Attachments)
+	}
+
+	customIssue.Done = j.doneTime(customIssue)
+	customIssue.Score = j.ScoreIssue(customIssue)
+
+	return customIssue
+}
+
+// attachments converts the attachment metadata of an issue
+func (j *JiraClient) attachments(attachments []*jira.Attachment) []AttachmentInfo {
+	var infos []AttachmentInfo
+	for _, attachment := range attachments {
+		if attachment == nil {
+			continue
+		}
+		created, _ := asTime(attachment.Created, j.timeFormats, j.location)
+		infos = append(infos, AttachmentInfo{
+			Filename: attachment.Filename,
+			Size:     attachment.Size,
+			Created:  created,
+			Author:   j.userDisplay(attachment.Author),
+		})
+	}
+	return infos
+}
+
+// searchWithRetry runs a search page, repeating it with exponential backoff and jitter on transient failures
+func (j *JiraClient) searchWithRetry(ctx context.Context, obs *Observability, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	backoff := time.Duration(j.options.RetryBackoff) * time.Millisecond
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		pageStart := time.Now()
+		chunk, resp, err := j.searcher.Search(ctx, jql, options)
+		j.observeDuration("jira_page_duration_seconds", "Duration of a single Jira search page request", time.Since(pageStart))
+		err = j.scrubError(err)
+		if err == nil || attempt > j.options.MaxRetries || !retryable(resp, err) {
+			return chunk, resp, classifyError(resp, err)
+		}
+
+		delay := backoff << (attempt - 1)
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		obs.Warn("Jira search at %d failed (attempt %d of %d), retrying in %s: %v", options.StartAt, attempt, j.options.MaxRetries+1, delay, err)
+		if j.metrics != nil {
+			j.metrics.Counter(metricsGroup, "jira_retries_total", "Count of retried Jira API calls", j.labels(nil)).Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, resp, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable reports whether a failed call may succeed when repeated: server errors and network failures,
+// but not client errors such as a bad query or wrong credentials
+func retryable(resp *jira.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if resp == nil || resp.Response == nil {
+		return true
+	}
+	if isAuthError(resp.Response) {
+		return false
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// isAuthError reports whether Jira refused the request for its credentials or their permissions
+func isAuthError(resp *http.Response) bool {
+	return resp != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden)
+}
+
+// classifyError wraps the error of a request refused for its credentials with ErrAuth
+func classifyError(resp *jira.Response, err error) error {
+	if err == nil || !isAuthError(httpResponse(resp)) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrAuth, err)
+}
+
+// requestFields returns the configured request fields, or the standard fields plus every mapped custom field ID
+func (j *JiraClient) requestFields() []string {
+	if len(j.requested) > 0 {
+		return j.requested
+	}
+
+	fields := []string{
+		"key", "summary", "created", "updated", "resolutiondate", "assignee", "reporter",
+		"issuetype", "components", "priority", "labels", "project", "status",
+	}
+	if j.options.IncludeCommentCounts {
+		fields = append(fields, "comment")
+	}
+	if j.options.IncludeAttachments {
+		fields = append(fields, "attachment")
+	}
+
+	var custom []string
+	for _, ids := range j.fields {
+		custom = append(custom, ids...)
+	}
+	sort.Strings(custom)
+
+	return append(fields, custom...)
+}
+
+// fieldValue returns the raw value of a logical field from the first mapped custom field holding one
+func (j *JiraClient) fieldValue(unknowns map[string]interface{}, name string) (interface{}, string, bool) {
+	for _, id := range j.fields[name] {
+		if val, ok := unknowns[id]; ok && val != nil {
+			return val, id, true
+		}
+	}
+	return nil, "", false
+}
+
+// fieldTime reads a logical timestamp field trying the time formats in order, using the date-only
+// layout at start of day in the configured timezone for fields declared as date-only
+func (j *JiraClient) fieldTime(unknowns map[string]interface{}, name string) (time.Time, bool) {
+	val, id, ok := j.fieldValue(unknowns, name)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	formats := j.timeFormats
+	if j.dateOnly[id] {
+		formats = []string{jiraDateLayout}
+	}
+
+	t, ok := asTime(val, formats, j.location)
+	if !ok {
+		j.obs.Debug("Failed to parse %s (%s) value %v", name, id, val)
+		if j.metrics != nil {
+			j.metrics.Counter(metricsGroup, "jira_timeparse_failures_total", "Count of timestamp field values matching none of the time formats", j.labels(map[string]string{"field": name})).Inc()
+		}
+	}
+	return t, ok
+}
+
+// userDisplay returns the human readable user name, falling back to the configured user attributes
+// when the display name is hidden
+func (j *JiraClient) userDisplay(user *jira.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return j.userName(user)
+}
+
+// userName picks the first non-empty user attribute in the configured order,
+// Name is empty on Jira Cloud where AccountID and DisplayName are set instead
+func (j *JiraClient) userName(user *jira.User) string {
+	if user == nil {
+		return ""
+	}
+
+	for _, field := range j.userFields {
+		var value string
+		switch field {
+		case "name":
+			value = user.Name
+		case "key":
+			value = user.Key
+		case "accountId":
+			value = user.AccountID
+		case "displayName":
+			value = user.DisplayName
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// userValue applies the user attribute order to a user custom field
+func (j *JiraClient) userValue(v interface{}) (string, bool) {
+	user, ok := v.(map[string]interface{})
+	if !ok {
+		return asString(v)
+	}
+
+	for _, field := range j.userFields {
+		if value, ok := user[field].(string); ok && value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// SeverityRank returns the position of the severity in the configured order, 0 being the most severe
+func (j *JiraClient) SeverityRank(severity string) (int, bool) {
+	rank, ok := j.severities[severity]
+	return rank, ok
+}
+
+// AlertEligible reports whether the issue is severe enough for notifications and SLA tracking,
+// counts and metrics are not affected by the threshold
+func (j *JiraClient) AlertEligible(issue *JiraIssue) bool {
+	if j.options.MinSeverityForAlerts == "" {
+		return true
+	}
+
+	rank, ok := j.SeverityRank(issue.Severity)
+	if !ok {
+		return false
+	}
+	return rank <= j.severities[j.options.MinSeverityForAlerts]
+}
+
+// normalizeSeverity maps a raw severity value to its canonical form. Values neither aliased nor canonical
+// pass through unchanged and are counted, as long as aliases or a severity order define what is known.
+func (j *JiraClient) normalizeSeverity(key, severity string) string {
+	severity = strings.TrimSpace(severity)
+	canonical := normalize(severity, j.options.SeverityAliases)
+	if len(j.options.SeverityAliases) == 0 && len(j.severities) == 0 {
+		return canonical
+	}
+
+	known := canonical != severity
+	if _, ok := j.severities[canonical]; ok {
+		known = true
+	}
+	for _, alias := range j.options.SeverityAliases {
+		if alias == canonical {
+			known = true
+		}
+	}
+
+	if !known {
+		j.obs.Debug("Issue %s has unknown severity %q", key, severity)
+		if j.metrics != nil {
+			j.metrics.Counter(metricsGroup, "jira_unknown_severity_total", "Count of converted issues with a severity neither aliased nor canonical", j.labels(nil)).Inc()
+		}
+	}
+	return canonical
+}
+
+// SeverityWeight returns the configured weight of the severity, 0 when it is unknown
+func (j *JiraClient) SeverityWeight(severity string) int {
+	return j.options.SeverityWeights[severity]
+}
+
+// ScoreIssue weights the business impact of the issue by its severity. Without configured weights the
+// score is the impact itself, an issue without impact counts as an impact of 1 so that severity alone scores.
+func (j *JiraClient) ScoreIssue(issue *JiraIssue) int {
+	if len(j.options.SeverityWeights) == 0 {
+		return issue.Impact
+	}
+
+	impact := issue.Impact
+	if impact == 0 {
+		impact = 1
+	}
+	return j.SeverityWeight(issue.Severity) * impact
+}
+
+// doneTime picks the timestamp marking the issue as done from the configured terminal fields,
+// either the first set one in precedence order or the earliest/latest of them
+func (j *JiraClient) doneTime(issue *JiraIssue) time.Time {
+	var done time.Time
+	for _, field := range j.doneFields {
+		var t time.Time
+		switch field {
+		case "resolved":
+			t = issue.Resolved
+		case "closed":
+			t = issue.Closed
+		case "fixed":
+			t = issue.Fixed
+		}
+		if t.IsZero() {
+			continue
+		}
+
+		switch j.options.DoneSelect {
+		case "earliest":
+			if done.IsZero() || t.Before(done) {
+				done = t
+			}
+		case "latest":
+			if t.After(done) {
+				done = t
+			}
+		default:
+			return t
+		}
+	}
+	return done
+}
+
+// loadServiceMap merges the component to service mapping from a JSON file with inline entries, inline wins
+func loadServiceMap(path string, inline map[string]string) (map[string]string, error) {
+	serviceMap := make(map[string]string)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading service map file: %w", err)
+		}
+		if err := json.Unmarshal(data, &serviceMap); err != nil {
+			return nil, fmt.Errorf("error parsing service map file %s: %w", path, err)
+		}
+	}
+
+	for component, service := range inline {
+		serviceMap[component] = service
+	}
+	return serviceMap, nil
+}
+
+// firstComponent returns the name of the first named component, Jira may send null entries
+func firstComponent(components []*jira.Component) (string, bool) {
+	for _, component := range components {
+		if component != nil && component.Name != "" {
+			return component.Name, true
+		}
+	}
+	return "", false
+}
+
+// componentService looks up the service of the first mapped component, logging unmapped ones once each
+func (j *JiraClient) componentService(components []*jira.Component) (string, bool) {
+	if len(j.serviceMap) == 0 {
+		return "", false
+	}
+
+	for _, component := range components {
+		if component == nil || component.Name == "" {
+			continue
+		}
+		if service, ok := j.serviceMap[component.Name]; ok {
+			return service, true
+		}
+
+		j.mu.Lock()
+		if !j.unmapped[component.Name] {
+			j.unmapped[component.Name] = true
+			j.obs.Warn("Component %s has no service mapping", component.Name)
+		}
+		j.mu.Unlock()
+	}
+	return "", false
+}
+
+// rebuildIssueCache returns the raw issue cache holding only the issues still present after the refresh,
+// so issues which dropped out of the result are purged instead of accumulating forever
+func (j *JiraClient) rebuildIssueCache(fetched []*jira.Issue, current []*JiraIssue, incremental bool) map[string]*jira.Issue {
+	keep := make(map[string]bool, len(current))
+	for _, issue := range current {
+		keep[issue.Key] = true
+	}
+
+	cache := make(map[string]*jira.Issue, len(current))
+	if incremental {
+		j.mu.RLock()
+		for id, issue := range j.issueCache {
+			if keep[issue.Key] {
+				cache[id] = issue
+			}
+		}
+		j.mu.RUnlock()
+	}
+	for _, issue := range fetched {
+		if keep[issue.Key] {
+			cache[issue.ID] = issue
+		}
+	}
+	return cache
+}
+
+// StartRefreshLoop begins a loop to periodically refresh Jira data. Cancelling ctx stops scheduling new
+// refreshes without interrupting the running one, which is only aborted once refreshCtx is cancelled.
+func (j *JiraClient) StartRefreshLoop(ctx, refreshCtx context.Context, wg *sync.WaitGroup) {
+	j.reloadMu.Lock()
+	j.reloadCtx, j.reloadWG = refreshCtx, wg
+	j.reloadMu.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Reloads are refused from now on, the ones running keep the wait group busy until they finish
+		defer func() {
+			j.reloadMu.Lock()
+			j.reloadClosed = true
+			j.reloadMu.Unlock()
+		}()
+
+		ticker := time.NewTicker(time.Duration(j.options.RefreshInterval) * time.Second)
+		defer ticker.Stop()
+
+		// Initial load
+		j.RefreshData(refreshCtx)
+
+		// Periodic refresh
+		for {
+			select {
+			case <-ctx.Done():
+				j.obs.Info("Stopping Jira refresh loop due to context cancellation")
+				return
+			case <-ticker.C:
+				j.RefreshData(refreshCtx)
+			}
+		}
+	}()
+}
+
+// StartHeartbeatLoop periodically logs a one-line status so log-only deployments can tell the process is alive
+func (j *JiraClient) StartHeartbeatLoop(ctx context.Context, wg *sync.WaitGroup, interval time.Duration) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.logHeartbeat()
+			}
+		}
+	}()
+}
+
+// logHeartbeat logs uptime, last refresh age, cache size and open issue count
+func (j *JiraClient) logHeartbeat() {
+	j.mu.RLock()
+	lastRefresh := j.lastRefresh
+	cached := len(j.issues)
+	open := 0
+	for _, issue := range j.issues {
+		if issue.IsOpen() {
+			open++
+		}
+	}
+	j.mu.RUnlock()
+
+	refreshAge := "never"
+	if !lastRefresh.IsZero() {
+		refreshAge = time.Since(lastRefresh).Round(time.Second).String()
+	}
+
+	j.obs.Info("Heartbeat: uptime %s, last refresh %s ago, cached issues %d, open issues %d",
+		time.Since(j.started).Round(time.Second), refreshAge, cached, open)
+}
+
+// RefreshData fetches the latest data from Jira
+func (j *JiraClient) RefreshData(ctx context.Context) {
+	select {
+	case j.refreshing <- struct{}{}:
+		defer func() { <-j.refreshing }()
+	case <-ctx.Done():
+		return
+	}
+
+	ctx = WithRequestID(ctx, uuid.NewString())
+	obs := j.obs.WithContext(ctx)
+
+	ctx, span := obs.StartSpan(ctx, "refresh")
+	defer span.Finish()
+
+	started := j.now()
+	if j.breaker != nil {
+		if wait, ok := j.breaker.allow(started); !ok {
+			obs.Warn("Skipping the refresh, the Jira circuit breaker is open for %s", wait.Truncate(time.Second))
+			j.countRefresh("skipped")
+			return
+		}
+	}
+
+	obs.Info("Refreshing Jira data...")
+
+	j.mu.RLock()
+	cached := j.issues
+	lastSearch := j.lastSearch
+	j.mu.RUnlock()
+
+	// After a first full load only fetch what changed, falling back to a full refresh with an empty cache
+	var since time.Time
+	if j.options.IncrementalRefresh && j.options.JQL == "" && len(cached) > 0 && !lastSearch.IsZero() {
+		since = lastSearch.Add(-incrementalSafetyWindow)
+	}
+
+	openOnly := j.options.RefreshScope == "open"
+	issues, err := j.searchIssues(ctx, j.buildJQL(openOnly, since))
+	// A cancelled refresh says nothing about the health of Jira
+	if j.breaker != nil && ctx.Err() == nil {
+		j.breaker.record(err == nil, j.now())
+	}
+	partial := errors.Is(err, ErrPartial) && j.options.AcceptPartialRefresh
+	if err != nil && !partial {
+		obs.Error("Failed to refresh Jira data: %v", err)
+		span.Error(err)
+		j.countRefresh("error")
+		return
+	}
+	if partial {
+		obs.Warn("Caching partial Jira data: %v", err)
+		span.SetTag("partial", true)
+	}
+
+	// The matched total of an incremental query says nothing about the query health
+	if since.IsZero() && !partial {
+		j.checkMatchedTotal()
+	}
+
+	if j.audit != nil {
+		if err := j.audit.Write(issues); err != nil {
+			obs.Error("Failed to write audit files: %v", err)
+		}
+	}
+
+	// Convert to custom issues with the fields we care about
+	customIssues, err := j.ConvertToCustomIssues(issues)
+	if err != nil {
+		obs.Error("Failed to process Jira issues: %v", err)
+		span.Error(err)
+		j.countRefresh("error")
+		return
+	}
+
+	// Issues of the failed pages are kept from the cache, so partial results are merged like incremental ones
+	merge := !since.IsZero() || partial
+	if !since.IsZero() {
+		obs.Info("Incremental refresh fetched %d issues updated since %s", len(customIssues), since.Format(time.RFC3339))
+	}
+	if merge {
+		customIssues = mergeIssues(cached, customIssues, openOnly)
+	}
+	customIssues = j.filterIssues(customIssues)
+
+	j.setIssueCache(j.rebuildIssueCache(issues, customIssues, merge))
+	j.writeSinks(ctx, obs, customIssues)
+
+	refreshed := j.now()
+	j.mu.Lock()
+	j.lastRefresh = refreshed
+	// Changes on the failed pages must still be fetched by the next incremental refresh
+	if !partial {
+		j.lastSearch = started
+	}
+	j.mu.Unlock()
+
+	j.updateIncidentMetrics(customIssues, refreshed)
+
+	if j.notifier != nil {
+		if fresh := j.newAlertIssues(customIssues); len(fresh) > 0 {
+			if err := j.notifier.Post(ctx, fresh, j.options.URL); err != nil {
+				obs.Error("Failed to notify about %d new incidents: %v", len(fresh), err)
+			} else {
+				obs.Info("Notified about %d new incidents", len(fresh))
+			}
+		}
+	}
+	j.setTimestampGauge("last_refresh_timestamp_seconds", "Unix time of the last successful refresh", j.now())
+	if partial {
+		j.countRefresh("partial")
+	} else {
+		j.countRefresh("success")
+	}
+	if j.metrics != nil {
+		cachedGauge(j.metrics, "refresh_duration_seconds", "Duration of the last successful refresh including fetch and conversion", j.labels(nil)).Set(j.now().Sub(started).Seconds())
+		cachedGauge(j.metrics, "refresh_issue_count", "Count of issues cached by the last successful refresh", j.labels(nil)).Set(float64(len(customIssues)))
+	}
+	span.SetTag("issues", len(customIssues))
+
+	obs.Info("Jira data refreshed successfully. Total issues: %d", len(customIssues))
+
+	// Display some issue details for debugging
+	if len(customIssues) > 0 {
+		obs.Info("Latest issue: %s, created: %s",
+			customIssues[0].Key,
+			customIssues[0].Created.Format(time.RFC3339))
+	}
+}
+
+// Reload refreshes the data right away and returns the number of cached issues. Calls overlapping a
+// reload in progress wait for its result instead of starting another refresh.
+func (j *JiraClient) Reload(ctx context.Context) (int, error) {
+	j.reloadMu.Lock()
+	if j.reloadClosed {
+		j.reloadMu.Unlock()
+		return 0, errRefreshStopped
+	}
+	call := j.reload
+	if call == nil {
+		call = &reloadCall{done: make(chan struct{})}
+		j.reload = call
+
+		// The refresh outlives the caller which started it, other callers may still wait for it
+		refreshCtx := context.WithoutCancel(ctx)
+		if j.reloadCtx != nil {
+			refreshCtx = j.reloadCtx
+		}
+		if j.reloadWG != nil {
+			j.reloadWG.Add(1)
+		}
+		go func(ctx context.Context, wg *sync.WaitGroup) {
+			if wg != nil {
+				defer wg.Done()
+			}
+			before := j.GetLastRefreshTime()
+			j.RefreshData(ctx)
+
+			j.mu.RLock()
+			call.count = len(j.issues)
+			if !j.lastRefresh.After(before) {
+				call.err = errors.New("refresh failed, see the logs for details")
+			}
+			j.mu.RUnlock()
+
+			j.reloadMu.Lock()
+			j.reload = nil
+			j.reloadMu.Unlock()
+			close(call.done)
+		}(refreshCtx, j.reloadWG)
+	}
+	j.reloadMu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.count, call.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// mergeIssues applies incrementally fetched issues over the cached ones by key, dropping those which left
+// the refresh scope, and keeps the newest created first order of a full refresh
+func mergeIssues(cached, updated []*JiraIssue, openOnly bool) []*JiraIssue {
+	byKey := make(map[string]*JiraIssue, len(cached)+len(updated))
+	for _, issue := range cached {
+		byKey[issue.Key] = issue
+	}
+	for _, issue := range updated {
+		if slices.Contains(excludedStatuses, issue.Status) || (openOnly && !issue.IsOpen()) {
+			delete(byKey, issue.Key)
+			continue
+		}
+		byKey[issue.Key] = issue
+	}
+
+	merged := make([]*JiraIssue, 0, len(byKey))
+	for _, issue := range byKey {
+		merged = append(merged, issue)
+	}
+	sort.Slice(merged, func(a, b int) bool {
+		if !merged[a].Created.Equal(merged[b].Created) {
+			return merged[a].Created.After(merged[b].Created)
+		}
+		return merged[a].Key < merged[b].Key
+	})
+	return merged
+}
+
+// filterIssues keeps the issues carrying all labels of the label filter and of one of the environments
+// of the environment filter
+func (j *JiraClient) filterIssues(issues []*JiraIssue) []*JiraIssue {
+	if len(j.labelFilter) == 0 && len(j.envFilter) == 0 {
+		return issues
+	}
+
+	filtered := make([]*JiraIssue, 0, len(issues))
+	for _, issue := range issues {
+		if issue.HasLabels(j.labelFilter) && j.inEnvironments(issue) {
+			filtered = append(filtered, issue)
+		}
+	}
+	return filtered
+}
+
+// inEnvironments reports whether the issue environment is one of the environment filter, any is when unset
+func (j *JiraClient) inEnvironments(issue *JiraIssue) bool {
+	if len(j.envFilter) == 0 {
+		return true
+	}
+	for _, environment := range j.envFilter {
+		if strings.EqualFold(environment, issue.Environment) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolutionFilter tells whether the served issues are narrowed to resolved (done) ones (resolved is true)
+// or open ones, ok is false when issues are served regardless of their resolution
+func (j *JiraClient) ResolutionFilter() (resolved bool, ok bool) {
+	return j.options.OnlyResolved, j.options.OnlyResolved || j.options.OnlyUnresolved
+}
+
+// setIssueCache replaces the raw issues kept for merging incremental refreshes
+func (j *JiraClient) setIssueCache(issueCache map[string]*jira.Issue) {
+	j.mu.Lock()
+	j.issueCache = issueCache
+	j.mu.Unlock()
+
+	if j.metrics != nil {
+		cachedGauge(j.metrics, "jira_cache_size", "Count of raw issues in the cache", j.labels(nil)).Set(float64(len(issueCache)))
+	}
+}
+
+// storeIssues replaces the converted issues served from memory and their indexes
+func (j *JiraClient) storeIssues(customIssues []*JiraIssue) {
+	issuesByKey := make(map[string]*JiraIssue, len(customIssues))
+	byProject := make(map[string][]*JiraIssue)
+	for _, issue := range customIssues {
+		issuesByKey[issue.Key] = issue
+		byProject[issue.Project] = append(byProject[issue.Project], issue)
+	}
+
+	j.mu.Lock()
+	j.issues = customIssues
+	j.issuesByKey = issuesByKey
+	j.byProject = byProject
+	j.mu.Unlock()
+}
+
+// GetIssueByKey fetches a single issue on demand, converts it like a refresh would and writes the updated
+// cache to the sinks, so that the cache file and OpenSearch keep matching the memory
+func (j *JiraClient) GetIssueByKey(ctx context.Context, key string) (*JiraIssue, error) {
+	options := &jira.GetQueryOptions{Fields: strings.Join(j.requestFields(), ",")}
+	if j.options.UseChangelog {
+		options.Expand = "changelog"
+	}
+	issue, resp, err := j.client.Issue.GetWithContext(ctx, key, options)
+	if err = j.scrubError(err); err != nil {
+		j.reportHttpError(j.obs.WithContext(ctx), httpResponse(resp), err)
+		return nil, fmt.Errorf("error getting issue %s: %w", key, err)
+	}
+
+	converted, err := j.ConvertToCustomIssues([]*jira.Issue{issue})
+	if err != nil {
+		return nil, err
+	}
+	customIssue := converted[0]
+
+	// Merged like a refresh, which must not replace the cache meanwhile
+	select {
+	case j.refreshing <- struct{}{}:
+		defer func() { <-j.refreshing }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	j.mu.RLock()
+	cached := j.issues
+	j.mu.RUnlock()
+
+	merged := j.filterIssues(mergeIssues(cached, converted, j.options.RefreshScope == "open"))
+	j.setIssueCache(j.rebuildIssueCache([]*jira.Issue{issue}, merged, true))
+	j.writeSinks(ctx, j.obs.WithContext(ctx), merged)
+
+	return customIssue, nil
+}
+
+// checkMatchedTotal compares the JQL matched total against the configured absolute range and the
+// last sane total, so a query broken by e.g. a field rename flips readiness instead of silently collapsing metrics
+func (j *JiraClient) checkMatchedTotal() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var problem string
+	switch {
+	case j.options.MinTotal > 0 && j.matched < j.options.MinTotal:
+		problem = fmt.Sprintf("below minimum %d", j.options.MinTotal)
+	case j.options.MaxTotal > 0 && j.matched > j.options.MaxTotal:
+		problem = fmt.Sprintf("above maximum %d", j.options.MaxTotal)
+	case j.options.MaxTotalChange > 0 && j.saneTotal > 0:
+		change := math.Abs(float64(j.matched-j.saneTotal)) / float64(j.saneTotal)
+		if change > j.options.MaxTotalChange {
+			problem = fmt.Sprintf("changed by %.0f%% from %d", change*100, j.saneTotal)
+		}
+	}
+
+	if problem != "" {
+		j.totalOK = false
+		j.obs.Error("Jira query matched %d issues, %s; check the query and field configuration", j.matched, problem)
+		return
+	}
+	j.totalOK = true
+	j.saneTotal = j.matched
+}
+
+// Ready reports whether data has been refreshed and the matched total passed the sanity checks
+func (j *JiraClient) Ready() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return !j.lastRefresh.IsZero() && j.totalOK
+}
+
+// countRefresh counts a finished refresh by its result: success, partial when pages failed but the fetched
+// ones were cached, error, or skipped while the circuit breaker is open
+func (j *JiraClient) countRefresh(result string) {
+	if j.metrics == nil {
+		return
+	}
+	j.metrics.Counter(metricsGroup, "refresh_total", "Count of Jira data refreshes by result", j.labels(map[string]string{"result": result})).Inc()
+}
+
+// setTimestampGauge publishes a point in time as a unix timestamp gauge
+func (j *JiraClient) setTimestampGauge(name, description string, t time.Time) {
+	if j.metrics == nil {
+		return
+	}
+	cachedGauge(j.metrics, name, description, j.labels(nil)).Set(float64(t.UnixNano()) / 1e9)
+}
+
+// updateIncidentMetrics publishes analytics gauges computed over the converted issues
+func (j *JiraClient) updateIncidentMetrics(issues []*JiraIssue, now time.Time) {
+	if j.metrics == nil {
+		return
+	}
+
+	cachedGauge(j.metrics, "incident_handoff_ratio", "Share of resolved incidents where reporter and assignee differ", j.labels(nil)).Set(HandoffRatio(issues))
+
+	projects := make(map[string]int)
+	statuses := make(map[string]int)
+	scores := make(map[string]int)
+	comments := make(map[string]int)
+	attachments := make(map[string]int)
+	labels := make(map[string]int)
+	incidents := make(map[[4]string]int)
+	for _, issue := range issues {
+		projects[labelValue(issue.Project)]++
+		statuses[labelValue(issue.Status)]++
+		scores[labelValue(issue.Service)] += issue.Score
+		comments[labelValue(issue.Service)] += issue.CommentCount
+		attachments[labelValue(issue.Service)] += len(issue.Attachments)
+		for _, label := range issue.Labels {
+			labels[label]++
+		}
+		incidents[[4]string{labelValue(issue.Service), labelValue(issue.Severity), labelValue(issue.Priority), labelValue(issue.Environment)}]++
+	}
+	var cached []gaugeValue
+	for project, count := range projects {
+		cached = append(cached, gaugeValue{labels: map[string]string{"project": project}, value: float64(count)})
+	}
+	j.setGauges("jira_issues_cached", "Count of cached issues by project", cached)
+
+	var byStatus []gaugeValue
+	for status, count := range statuses {
+		byStatus = append(byStatus, gaugeValue{labels: map[string]string{"status": status}, value: float64(count)})
+	}
+	j.setGauges("jira_issues_by_status", "Count of issues by current status", byStatus)
+
+	var byService []gaugeValue
+	for service, score := range scores {
+		byService = append(byService, gaugeValue{labels: map[string]string{"service": service}, value: float64(score)})
+	}
+	j.setGauges("incident_score_total", "Summed score of incidents by service", byService)
+
+	if j.options.IncludeCommentCounts {
+		var commentsByService []gaugeValue
+		for service, count := range comments {
+			commentsByService = append(commentsByService, gaugeValue{labels: map[string]string{"service": service}, value: float64(count)})
+		}
+		j.setGauges("incident_comments_total", "Summed comment count of incidents by service", commentsByService)
+	}
+
+	if j.options.IncludeAttachments {
+		var attachmentsByService []gaugeValue
+		for service, count := range attachments {
+			attachmentsByService = append(attachmentsByService, gaugeValue{labels: map[string]string{"service": service}, value: float64(count)})
+		}
+		j.setGauges("incident_attachments_total", "Summed attachment count of incidents by service", attachmentsByService)
+	}
+
+	var byLabel []gaugeValue
+	for label, count := range labels {
+		byLabel = append(byLabel, gaugeValue{labels: map[string]string{"label": label}, value: float64(count)})
+	}
+	j.setGauges("jira_issues_by_label", "Count of cached issues by label", byLabel)
+
+	var byCategory []gaugeValue
+	for key, count := range incidents {
+		labels := map[string]string{"service": key[0], "severity": key[1], "priority": key[2], "environment": key[3]}
+		byCategory = append(byCategory, gaugeValue{labels: labels, value: float64(count)})
+	}
+	j.setGauges("incidents_total", "Count of cached incidents by service, severity, priority and environment", byCategory)
+
+	var mttd, mttr []gaugeValue
+	for severity, stats := range ComputeIncidentMetrics(issues) {
+		labels := map[string]string{"severity": severity}
+		if stats.Detected > 0 {
+			mttd = append(mttd, gaugeValue{labels: labels, value: stats.MTTD.Seconds()})
+		}
+		if stats.Resolved > 0 {
+			mttr = append(mttr, gaugeValue{labels: labels, value: stats.MTTR.Seconds()})
+		}
+	}
+	j.setGauges("incident_mttd_seconds", "Mean time from creation to detection of incidents", mttd)
+	j.setGauges("incident_mttr_seconds", "Mean time from start to resolution of incidents", mttr)
+
+	var recurrence []gaugeValue
+	for _, group := range IncidentRecurrence(issues) {
+		labels := map[string]string{"service": group.Service, "root_cause": group.RootCause}
+		recurrence = append(recurrence, gaugeValue{labels: labels, value: float64(group.Count)})
+	}
+	j.setGauges("incident_recurrence", "Count of cached incidents by service and root cause", recurrence)
+
+	var workload []gaugeValue
+	for assignee, count := range AssigneeWorkload(issues, j.options.WorkloadThreshold) {
+		workload = append(workload, gaugeValue{labels: map[string]string{"assignee": assignee}, value: float64(count)})
+	}
+	j.setGauges("open_incidents_by_assignee", "Count of open incidents by assignee, assignees at or below the workload threshold summed as other", workload)
+
+	j.setGauges("open_incident_age", "Count of open incidents by age bucket, each bucket counting ages up to its bound above the previous one", openAgeBuckets(issues, now))
+
+	var missing []gaugeValue
+	for _, field := range lifecycleFields {
+		keys := missingLifecycleField(issues, field.get)
+		if len(keys) > 0 {
+			j.obs.Debug("Issues missing the %s timestamp: %s", field.name, strings.Join(keys, ","))
+		}
+		missing = append(missing, gaugeValue{labels: map[string]string{"field": field.name}, value: float64(len(keys))})
+	}
+	j.setGauges("incident_missing_field_total", "Count of cached incidents missing a lifecycle timestamp needed for MTTD and MTTR", missing)
+}
+
+// lifecycleFields are the timestamps incidents need to contribute to MTTD and MTTR
+var lifecycleFields = []struct {
+	name string
+	get  func(*JiraIssue) time.Time
+}{
+	{"detected", func(issue *JiraIssue) time.Time { return issue.Detected }},
+	{"started", func(issue *JiraIssue) time.Time { return issue.Started }},
+}
+
+// missingLifecycleField returns the keys of the issues without the timestamp
+func missingLifecycleField(issues []*JiraIssue, get func(*JiraIssue) time.Time) []string {
+	var keys []string
+	for _, issue := range issues {
+		if get(issue).IsZero() {
+			keys = append(keys, issue.Key)
+		}
+	}
+	return keys
+}
+
+// ageBuckets are the upper age bounds of open incidents, older ones fall in the "older" bucket
+var ageBuckets = []struct {
+	name  string
+	bound time.Duration
+}{
+	{"1h", time.Hour},
+	{"4h", 4 * time.Hour},
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// openAgeBuckets counts open issues by the first bucket bounding their age at now, every bucket is reported
+func openAgeBuckets(issues []*JiraIssue, now time.Time) []gaugeValue {
+	counts := make([]int, len(ageBuckets)+1)
+	for _, issue := range issues {
+		if !issue.IsOpen() || issue.Created.IsZero() {
+			continue
+		}
+		age := now.Sub(issue.Created)
+		n := len(ageBuckets)
+		for i, bucket := range ageBuckets {
+			if age <= bucket.bound {
+				n = i
+				break
+			}
+		}
+		counts[n]++
+	}
+
+	values := make([]gaugeValue, 0, len(counts))
+	for i, count := range counts {
+		name := "older"
+		if i < len(ageBuckets) {
+			name = ageBuckets[i].name
+		}
+		values = append(values, gaugeValue{labels: map[string]string{"bucket": name}, value: float64(count)})
+	}
+	return values
+}
+
+// Tenant returns the tenant name of the client, empty when a single Jira instance is polled
+func (j *JiraClient) Tenant() string {
+	return j.options.Tenant
+}
+
+// Location returns the timezone of the Jira instance, the one JQL dates are written in
+func (j *JiraClient) Location() *time.Location {
+	return j.location
+}
+
+// labels adds the tenant label to the metric labels
+func (j *JiraClient) labels(labels map[string]string) map[string]string {
+	return tenantLabels(j.options.Tenant, labels)
+}
+
+// SetSearcher replaces the way search pages are fetched, e.g. with a fake in tests
+func (j *JiraClient) SetSearcher(searcher IssueSearcher) {
+	j.searcher = searcher
+}
+
+// SetAuditWriter enables storing raw issues of every refresh on disk
+func (j *JiraClient) SetAuditWriter(audit *AuditWriter) {
+	j.audit = audit
+}
+
+// AddSink pushes the converted issues of every successful refresh to the sink, after the in-memory cache
+// and the cache file
+func (j *JiraClient) AddSink(sink Sink) {
+	j.sinks = append(j.sinks, sink)
+}
+
+// writeSinks writes the issues to every sink in turn, a failing sink does not keep the others from
+// getting the issues
+func (j *JiraClient) writeSinks(ctx context.Context, obs *Observability, issues []*JiraIssue) {
+	for _, sink := range j.sinks {
+		if err := sink.Write(ctx, issues); err != nil {
+			obs.Error("Failed to write issues to a sink: %v", err)
+			if j.metrics != nil {
+				j.metrics.Counter(metricsGroup, "sink_errors_total", "Count of failed writes of refreshed issues to a sink", j.labels(nil)).Inc()
+			}
+		}
+	}
+}
+
+// SetNotifier enables notifications about new alert eligible incidents at or above the notifier severity
+func (j *JiraClient) SetNotifier(notifier *Notifier) error {
+	if severity := notifier.options.MinSeverity; severity != "" {
+		if _, ok := j.severities[severity]; !ok {
+			return fmt.Errorf("minimum notification severity %q is not in the severity order", severity)
+		}
+	}
+	j.notifier = notifier
+	return nil
+}
+
+// notifyEligible reports whether the issue is severe enough for the notifier
+func (j *JiraClient) notifyEligible(issue *JiraIssue) bool {
+	if j.notifier == nil || j.notifier.options.MinSeverity == "" {
+		return true
+	}
+
+	rank, ok := j.SeverityRank(issue.Severity)
+	if !ok {
+		return false
+	}
+	return rank <= j.severities[j.notifier.options.MinSeverity]
+}
+
+// newAlertIssues returns the alert and notification eligible issues missing in the previous refresh. The
+// first refresh only records the baseline, so that a restart does not notify about every known incident again.
+func (j *JiraClient) newAlertIssues(issues []*JiraIssue) []*JiraIssue {
+	seen := make(map[string]bool)
+	var fresh []*JiraIssue
+	for _, issue := range issues {
+		if !j.AlertEligible(issue) || !j.notifyEligible(issue) {
+			continue
+		}
+		seen[issue.Key] = true
+		if j.alerted != nil && !j.alerted[issue.Key] {
+			fresh = append(fresh, issue)
+		}
+	}
+	j.alerted = seen
+	return fresh
+}
+
+// Flush persists the cached issues and sends pending data on graceful shutdown: it waits for the audit
+// files in progress, saves the cache file and posts notifications which failed before
+func (j *JiraClient) Flush(ctx context.Context) error {
+	var errs []error
+
+	// The running refresh updates the cache and queues notifications, the previous data is saved without it
+	select {
+	case j.refreshing <- struct{}{}:
+		defer func() { <-j.refreshing }()
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("refresh in progress not complete: %w", ctx.Err()))
+	}
+
+	if j.audit != nil {
+		if err := j.audit.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if j.options.CacheFilePath != "" {
+		if issues := j.GetCachedIssues(); issues != nil {
+			if err := j.saveCacheFile(issues); err != nil {
+				errs = append(errs, err)
+			} else {
+				j.obs.Info("Saved %d issues to cache file %s", len(issues), j.options.CacheFilePath)
+			}
+		}
+	}
+
+	if j.notifier != nil {
+		pending, err := j.notifier.Flush(ctx, j.options.URL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%d incidents not notified: %w", j.notifier.Pending(), err))
+		} else if pending > 0 {
+			j.obs.Info("Notified about %d pending incidents", pending)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetCachedIssues returns the converted issues of the last successful refresh, nil before the first one
+func (j *JiraClient) GetCachedIssues() []*JiraIssue {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.issues
+}
+
+// GetCachedProjectIssues returns the converted issues of one project from the last successful refresh
+func (j *JiraClient) GetCachedProjectIssues(project string) []*JiraIssue {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.byProject[project]
+}
+
+// GetCachedIssue returns the converted issue with the given key from the last successful refresh
+func (j *JiraClient) GetCachedIssue(key string) (*JiraIssue, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	issue, ok := j.issuesByKey[key]
+	return issue, ok
+}
+
+// GetLastRefreshTime returns the timestamp of the last successful data refresh
+func (j *JiraClient) GetLastRefreshTime() time.Time {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.lastRefresh
+}
+
+// TestConnection verifies connection to Jira. Every attempt is bounded by the connect timeout, failed
+// attempts are repeated with exponential backoff up to the configured retries unless the error is final.
+func (j *JiraClient) TestConnection(ctx context.Context) error {
+	backoff := time.Duration(j.options.RetryBackoff) * time.Millisecond
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		user, resp, err := j.getSelf(ctx)
+		if err == nil {
+			j.obs.Info("Successfully connected to Jira as %s", user.Name)
+			return nil
+		}
+
+		// An attempt running out of time is worth repeating, unlike the caller giving up
+		timedOut := errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+		if attempt > j.options.MaxRetries || ctx.Err() != nil || (!timedOut && !retryable(resp, err)) {
+			return fmt.Errorf("jira connection test failed: %w", err)
+		}
+
+		delay := backoff << (attempt - 1)
+		j.obs.Warn("Jira connection test failed (attempt %d of %d), retrying in %s: %v", attempt, j.options.MaxRetries+1, delay, err)
+		if err := sleepContext(ctx, delay); err != nil {
+			return fmt.Errorf("jira connection test failed: %w", err)
+		}
+	}
+}
+
+// getSelf fetches the current user within the connect timeout
+func (j *JiraClient) getSelf(ctx context.Context) (*jira.User, *jira.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, j.connectTimeout)
+	defer cancel()
+
+	// The go-jira library doesnt have a Myself method, use the Current User API instead
+	user, resp, err := j.client.User.GetSelfWithContext(ctx)
+	if err = j.scrubError(err); err != nil {
+		j.reportHttpError(j.obs, httpResponse(resp), err)
+		return nil, resp, classifyError(resp, err)
+	}
+	return user, resp, nil
+}
+
+// reportHttpError logs HTTP response details on error and counts the failure by status code, requests
+// refused for their credentials are reported apart as they need fixing the configuration
+func (j *JiraClient) reportHttpError(obs *Observability, resp *http.Response, err error) {
+	code := "none"
+	switch {
+	case resp == nil:
+		obs.Error("HTTP request failed with no response: %v", err)
+	case isAuthError(resp):
+		obs.Error("Jira rejected the credentials of the %s auth method with status %d, check the username, token and permissions: %v", j.authMethod(), resp.StatusCode, err)
+		code = strconv.Itoa(resp.StatusCode)
+	default:
+		obs.Error("HTTP request failed - Status: %d, Error: %v", resp.StatusCode, err)
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	// Record metric for API errors
+	if j.metrics != nil {
+		labels := map[string]string{"code": code}
+		j.metrics.Counter(metricsGroup, "jira_api_errors_total", "Count of failed Jira API calls by status code", j.labels(labels)).Inc()
+		if isAuthError(resp) {
+			j.metrics.Counter(metricsGroup, "jira_auth_errors_total", "Count of Jira API calls refused for their credentials by status code", j.labels(labels)).Inc()
+		}
+	}
+}
+
+// authMethod names the configured auth method for the logs
+func (j *JiraClient) authMethod() string {
+	if j.options.AuthMethod == "" {
+		return "basic"
+	}
+	return j.options.AuthMethod
+}
+
+// httpResponse unwraps the HTTP response of a go-jira call, nil when the request did not get one
+func httpResponse(resp *jira.Response) *http.Response {
+	if resp == nil {
+		return nil
 	}
+	return resp.Response
 }
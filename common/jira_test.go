@@ -0,0 +1,2016 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// pageSearcher serves the issues in pages like Jira does, optionally failing some pages by start offset
+type pageSearcher struct {
+	mu       sync.Mutex
+	issues   []jira.Issue
+	pageSize int
+	fail     map[int]error
+	calls    []jira.SearchOptions
+	jqls     []string
+}
+
+func (s *pageSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, *options)
+	s.jqls = append(s.jqls, jql)
+	s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := s.fail[options.StartAt]; err != nil {
+		return nil, nil, err
+	}
+
+	size := options.MaxResults
+	if s.pageSize > 0 && s.pageSize < size {
+		size = s.pageSize
+	}
+	start := min(options.StartAt, len(s.issues))
+	end := min(start+size, len(s.issues))
+	return s.issues[start:end], &jira.Response{StartAt: options.StartAt, MaxResults: size, Total: len(s.issues)}, nil
+}
+
+// testOptions returns minimal options accepted by NewJiraClient
+func testOptions() JiraOptions {
+	return JiraOptions{
+		URL:           "https://jira.example.com",
+		Username:      "aim",
+		ApiToken:      "token",
+		ProjectKey:    "INCI",
+		SeverityOrder: []string{"SEV1", "SEV2", "SEV3"},
+		RetryBackoff:  1,
+	}
+}
+
+// newTestClient creates a client searching through the fake searcher
+func newTestClient(t *testing.T, options JiraOptions, searcher IssueSearcher) *JiraClient {
+	t.Helper()
+	client, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil)
+	if err != nil {
+		t.Fatalf("NewJiraClient: %v", err)
+	}
+	if searcher != nil {
+		client.SetSearcher(searcher)
+	}
+	return client
+}
+
+// testIssue builds a Jira issue created at the given time, with custom fields keyed by field ID
+func testIssue(key string, created time.Time, unknowns map[string]interface{}) jira.Issue {
+	if unknowns == nil {
+		unknowns = map[string]interface{}{}
+	}
+	return jira.Issue{
+		ID:  key,
+		Key: key,
+		Fields: &jira.IssueFields{
+			Summary:  "Incident " + key,
+			Created:  jira.Time(created),
+			Updated:  jira.Time(created),
+			Status:   &jira.Status{Name: "Open"},
+			Unknowns: unknowns,
+		},
+	}
+}
+
+// testIssues builds n issues created one minute apart, the newest first
+func testIssues(n int) []jira.Issue {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issues := make([]jira.Issue, 0, n)
+	for i := n; i > 0; i-- {
+		issues = append(issues, testIssue("INCI-"+strconv.Itoa(i), base.Add(time.Duration(i)*time.Minute), nil))
+	}
+	return issues
+}
+
+// convertOne converts a single issue through the client
+func convertOne(t *testing.T, client *JiraClient, issue jira.Issue) *JiraIssue {
+	t.Helper()
+	converted, err := client.ConvertToCustomIssues([]*jira.Issue{&issue})
+	if err != nil {
+		t.Fatalf("ConvertToCustomIssues: %v", err)
+	}
+	if len(converted) != 1 {
+		t.Fatalf("converted %d issues, want 1", len(converted))
+	}
+	return converted[0]
+}
+
+func TestConvertDateOnlyFields(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	options := testOptions()
+	options.FieldMapping = map[string]string{"started": "customfield_1", "fixed": "customfield_2"}
+	options.DateOnlyFields = []string{"started"}
+	options.Timezone = "Europe/Berlin"
+	client := newTestClient(t, options, nil)
+
+	tests := []struct {
+		name    string
+		started interface{}
+		fixed   interface{}
+		want    time.Time
+		wantFix time.Time
+	}{
+		{
+			name:    "date-only at start of day in the timezone",
+			started: "2024-03-05",
+			fixed:   "2024-03-05T10:30:00.000+0000",
+			want:    time.Date(2024, 3, 5, 0, 0, 0, 0, berlin),
+			wantFix: time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			name:    "date-time value in a date-only field is not parsed",
+			started: "2024-03-05T10:30:00.000+0000",
+		},
+		{
+			name:    "date-only value in a date-time field falls back to the date format",
+			started: "2024-03-05",
+			fixed:   "2024-03-05",
+			want:    time.Date(2024, 3, 5, 0, 0, 0, 0, berlin),
+			wantFix: time.Date(2024, 3, 5, 0, 0, 0, 0, berlin),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknowns := map[string]interface{}{"customfield_1": tt.started}
+			if tt.fixed != nil {
+				unknowns["customfield_2"] = tt.fixed
+			}
+			issue := convertOne(t, client, testIssue("INCI-1", time.Now(), unknowns))
+			if !issue.Started.Equal(tt.want) {
+				t.Errorf("Started = %v, want %v", issue.Started, tt.want)
+			}
+			if !issue.Fixed.Equal(tt.wantFix) {
+				t.Errorf("Fixed = %v, want %v", issue.Fixed, tt.wantFix)
+			}
+		})
+	}
+}
+
+func TestConvertComponentService(t *testing.T) {
+	options := testOptions()
+	options.FieldMapping = map[string]string{"service": "customfield_1"}
+	options.ServiceMap = map[string]string{"payments-api": "payments"}
+	client := newTestClient(t, options, nil)
+
+	tests := []struct {
+		name       string
+		components []*jira.Component
+		field      interface{}
+		want       string
+	}{
+		{"mapped component wins over the field", []*jira.Component{{Name: "payments-api"}}, "billing", "payments"},
+		{"mapped component after unmapped ones", []*jira.Component{{Name: "web"}, {Name: "payments-api"}}, nil, "payments"},
+		{"custom field when no component is mapped", []*jira.Component{{Name: "web"}}, "billing", "billing"},
+		{"raw component without a field", []*jira.Component{{Name: "web"}}, nil, "web"},
+		{"null components are skipped", []*jira.Component{nil, {Name: ""}, {Name: "web"}}, nil, "web"},
+		{"only null components", []*jira.Component{nil}, nil, ""},
+		{"no components", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknowns := map[string]interface{}{}
+			if tt.field != nil {
+				unknowns["customfield_1"] = tt.field
+			}
+			issue := testIssue("INCI-1", time.Now(), unknowns)
+			issue.Fields.Components = tt.components
+			if got := convertOne(t, client, issue).Service; got != tt.want {
+				t.Errorf("Service = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertUserFields(t *testing.T) {
+	server := &jira.User{Name: "jdoe", Key: "JIRAUSER1", DisplayName: "John Doe"}
+	cloud := &jira.User{AccountID: "5b10ac8d82e05b22cc7d4ef5", DisplayName: "Jane Roe"}
+	cloudHead := map[string]interface{}{"accountId": "5b10a2844c20165700ede21g", "displayName": "Head Person"}
+
+	tests := []struct {
+		name         string
+		order        []string
+		assignee     *jira.User
+		reporter     *jira.User
+		head         interface{}
+		wantAssignee string
+		wantReporter string
+		wantHead     string
+	}{
+		{
+			name:         "server payload uses the name",
+			assignee:     server,
+			reporter:     server,
+			head:         map[string]interface{}{"name": "boss", "displayName": "The Boss"},
+			wantAssignee: "jdoe",
+			wantReporter: "jdoe",
+			wantHead:     "boss",
+		},
+		{
+			name:         "cloud payload falls back to the account ID",
+			assignee:     cloud,
+			reporter:     cloud,
+			head:         cloudHead,
+			wantAssignee: "5b10ac8d82e05b22cc7d4ef5",
+			wantReporter: "5b10ac8d82e05b22cc7d4ef5",
+			wantHead:     "5b10a2844c20165700ede21g",
+		},
+		{
+			name:         "configured order prefers the display name",
+			order:        []string{"displayName", "name"},
+			assignee:     server,
+			reporter:     cloud,
+			head:         cloudHead,
+			wantAssignee: "John Doe",
+			wantReporter: "Jane Roe",
+			wantHead:     "Head Person",
+		},
+		{
+			name:         "plain string head",
+			assignee:     nil,
+			head:         "boss",
+			wantAssignee: "",
+			wantHead:     "boss",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.FieldMapping = map[string]string{"head": "customfield_1"}
+			options.UserFields = tt.order
+			client := newTestClient(t, options, nil)
+
+			issue := testIssue("INCI-1", time.Now(), map[string]interface{}{"customfield_1": tt.head})
+			issue.Fields.Assignee = tt.assignee
+			issue.Fields.Reporter = tt.reporter
+			got := convertOne(t, client, issue)
+			if got.Assignee != tt.wantAssignee || got.Reporter != tt.wantReporter || got.Head != tt.wantHead {
+				t.Errorf("assignee, reporter, head = %q, %q, %q, want %q, %q, %q",
+					got.Assignee, got.Reporter, got.Head, tt.wantAssignee, tt.wantReporter, tt.wantHead)
+			}
+		})
+	}
+}
+
+func TestConvertUserDisplayAndEmail(t *testing.T) {
+	// Jira Cloud omits the name, the email is hidden by the privacy settings of the reporter
+	payload := `{"key": "INCI-1", "fields": {
+		"summary": "Incident", "created": "2024-03-01T10:00:00.000+0000", "status": {"name": "Open"},
+		"assignee": {"accountId": "5b10ac8d82e05b22cc7d4ef5", "displayName": "Jane Roe", "emailAddress": "jane@example.com"},
+		"reporter": {"accountId": "5b10a2844c20165700ede21g", "displayName": ""}
+	}}`
+	var issue jira.Issue
+	if err := json.Unmarshal([]byte(payload), &issue); err != nil {
+		t.Fatal(err)
+	}
+
+	got := convertOne(t, newTestClient(t, testOptions(), nil), issue)
+	want := struct{ Assignee, AssigneeDisplay, AssigneeEmail, Reporter, ReporterDisplay string }{
+		"5b10ac8d82e05b22cc7d4ef5", "Jane Roe", "jane@example.com", "5b10a2844c20165700ede21g", "5b10a2844c20165700ede21g",
+	}
+	if got.Assignee != want.Assignee || got.AssigneeDisplay != want.AssigneeDisplay || got.AssigneeEmail != want.AssigneeEmail ||
+		got.Reporter != want.Reporter || got.ReporterDisplay != want.ReporterDisplay {
+		t.Errorf("user fields = %q, %q, %q, %q, %q, want %+v",
+			got.Assignee, got.AssigneeDisplay, got.AssigneeEmail, got.Reporter, got.ReporterDisplay, want)
+	}
+
+	fields := newTestClient(t, testOptions(), nil).requestFields()
+	for _, field := range []string{"assignee", "reporter"} {
+		if !slices.Contains(fields, field) {
+			t.Errorf("request fields %v miss %s", fields, field)
+		}
+	}
+}
+
+func TestNewJiraClientUserFields(t *testing.T) {
+	options := testOptions()
+	options.UserFields = []string{"name", "email"}
+	if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+		t.Fatal("expected an error for an unsupported user field")
+	}
+}
+
+func TestConvertMetricsLink(t *testing.T) {
+	options := testOptions()
+	options.FieldMapping = map[string]string{"metrics": "customfield_1|customfield_2"}
+	client := newTestClient(t, options, nil)
+
+	const dashboard = "https://grafana.example.com/d/abc"
+	tests := []struct {
+		name     string
+		unknowns map[string]interface{}
+		want     string
+	}{
+		{"plain link", map[string]interface{}{"customfield_1": dashboard}, dashboard},
+		{"url object", map[string]interface{}{"customfield_1": map[string]interface{}{"url": dashboard, "title": "Dashboard"}}, dashboard},
+		{"href object", map[string]interface{}{"customfield_1": map[string]interface{}{"href": dashboard}}, dashboard},
+		{"identifier", map[string]interface{}{"customfield_1": "abc"}, "abc"},
+		{"alternative field", map[string]interface{}{"customfield_2": dashboard}, dashboard},
+		{"missing", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue := testIssue("INCI-1", time.Now(), tt.unknowns)
+			if got := convertOne(t, client, issue).Metrics; got != tt.want {
+				t.Errorf("Metrics = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlushPersistsCacheAndPendingNotifications(t *testing.T) {
+	stub := &webhookStub{statuses: []int{http.StatusServiceUnavailable}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	options := testOptions()
+	options.CacheFilePath = filepath.Join(t.TempDir(), "issues.json")
+	client := newTestClient(t, options, &pageSearcher{issues: testIssues(3)})
+	notifier := NewNotifier(NotifyOptions{WebhookURL: server.URL}, NewObservability(nil, nil, nil))
+	if err := client.SetNotifier(notifier); err != nil {
+		t.Fatal(err)
+	}
+	notifier.Post(context.Background(), []*JiraIssue{{Key: "INCI-9"}}, options.URL)
+
+	client.RefreshData(context.Background())
+	if err := os.Remove(options.CacheFilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	data, err := os.ReadFile(options.CacheFilePath)
+	if err != nil {
+		t.Fatalf("cache file not saved: %v", err)
+	}
+	var saved []*JiraIssue
+	if err := json.Unmarshal(data, &saved); err != nil || len(saved) != 3 {
+		t.Errorf("saved %d issues (%v), want 3", len(saved), err)
+	}
+	if notifier.Pending() != 0 {
+		t.Errorf("pending notifications not flushed")
+	}
+}
+
+func TestFlushWaitsForAuditWrite(t *testing.T) {
+	options := testOptions()
+	client := newTestClient(t, options, nil)
+	audit, err := NewAuditWriter(AuditOptions{Dir: t.TempDir()}, NewObservability(nil, nil, nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetAuditWriter(audit)
+
+	// Simulate a write in progress past the shutdown timeout
+	audit.busy <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush() = %v, want the deadline error", err)
+	}
+
+	<-audit.busy
+	if err := client.Flush(context.Background()); err != nil {
+		t.Errorf("Flush() = %v after the write completed", err)
+	}
+}
+
+func TestFieldMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		mapping  map[string]string
+		unknowns map[string]interface{}
+		want     string
+	}{
+		{
+			name:     "default mapping",
+			unknowns: map[string]interface{}{"customfield_18119": map[string]interface{}{"value": "SEV1"}},
+			want:     "SEV1",
+		},
+		{
+			name:     "custom field ID",
+			mapping:  map[string]string{"severity": "customfield_1"},
+			unknowns: map[string]interface{}{"customfield_1": "SEV2", "customfield_18119": "SEV1"},
+			want:     "SEV2",
+		},
+		{
+			name:     "first alternative holding a value",
+			mapping:  map[string]string{"severity": "customfield_1 | customfield_2"},
+			unknowns: map[string]interface{}{"customfield_1": nil, "customfield_2": "SEV3"},
+			want:     "SEV3",
+		},
+		{
+			name:     "unmapped field is ignored",
+			mapping:  map[string]string{"service": "customfield_3"},
+			unknowns: map[string]interface{}{"customfield_18119": "SEV1"},
+			want:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.FieldMapping = tt.mapping
+			client := newTestClient(t, options, nil)
+			if got := convertOne(t, client, testIssue("INCI-1", time.Now(), tt.unknowns)).Severity; got != tt.want {
+				t.Errorf("Severity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestFieldsIncludeMappedIDs(t *testing.T) {
+	options := testOptions()
+	options.FieldMapping = map[string]string{"severity": "customfield_2|customfield_1", "service": "customfield_3"}
+	client := newTestClient(t, options, nil)
+
+	fields := client.requestFields()
+	custom := fields[len(fields)-3:]
+	if want := []string{"customfield_1", "customfield_2", "customfield_3"}; !reflect.DeepEqual(custom, want) {
+		t.Errorf("custom request fields = %v, want %v", custom, want)
+	}
+}
+
+// commentedIssue builds an issue carrying n comments
+func commentedIssue(key string, n int) jira.Issue {
+	issue := testIssue(key, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	issue.Fields.Comments = &jira.Comments{}
+	for i := 0; i < n; i++ {
+		issue.Fields.Comments.Comments = append(issue.Fields.Comments.Comments, &jira.Comment{ID: strconv.Itoa(i + 1), Body: "update"})
+	}
+	return issue
+}
+
+func TestConvertCommentCounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   bool
+		wantCount int
+		wantField bool
+	}{
+		{name: "enabled", include: true, wantCount: 3, wantField: true},
+		{name: "disabled", include: false, wantCount: 0, wantField: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.IncludeCommentCounts = tt.include
+			client := newTestClient(t, options, nil)
+
+			if got := convertOne(t, client, commentedIssue("INCI-1", 3)).CommentCount; got != tt.wantCount {
+				t.Errorf("CommentCount = %d, want %d", got, tt.wantCount)
+			}
+			if got := slices.Contains(client.requestFields(), "comment"); got != tt.wantField {
+				t.Errorf("comment requested = %v, want %v", got, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestRequestFieldsOverride(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+	}{
+		{name: "override", requested: []string{"summary", " created ", "", "customfield_7"}, want: []string{"summary", "created", "customfield_7"}},
+		{name: "derived", requested: []string{""}, want: []string{"key", "summary", "created", "updated", "resolutiondate", "assignee", "reporter", "issuetype", "components", "priority", "labels", "project", "status", "customfield_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.FieldMapping = map[string]string{"severity": "customfield_1"}
+			options.RequestFields = tt.requested
+			searcher := &pageSearcher{issues: testIssues(2)}
+			client := newTestClient(t, options, searcher)
+
+			if _, err := client.GetIssues(context.Background()); err != nil {
+				t.Fatalf("GetIssues: %v", err)
+			}
+			if len(searcher.calls) == 0 {
+				t.Fatal("no search was made")
+			}
+			if got := searcher.calls[0].Fields; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchOptions.Fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// attachedIssue builds an issue carrying a log file and a screenshot
+func attachedIssue(key string) jira.Issue {
+	issue := testIssue(key, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+	issue.Fields.Attachments = []*jira.Attachment{
+		{Filename: "checkout.log", Size: 2048, Created: "2024-01-01T10:30:00.000+0000", Author: &jira.User{Name: "oncall", DisplayName: "On Call"}, Content: "https://jira/secure/attachment/1/checkout.log"},
+		{Filename: "dashboard.png", Size: 512000, Created: "2024-01-01T11:00:00.000+0000", Author: &jira.User{Name: "sre"}},
+	}
+	return issue
+}
+
+func TestConvertAttachments(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   bool
+		want      []AttachmentInfo
+		wantField bool
+	}{
+		{
+			name:    "enabled",
+			include: true,
+			want: []AttachmentInfo{
+				{Filename: "checkout.log", Size: 2048, Created: time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC), Author: "On Call"},
+				{Filename: "dashboard.png", Size: 512000, Created: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), Author: "sre"},
+			},
+			wantField: true,
+		},
+		{name: "disabled", include: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.IncludeAttachments = tt.include
+			client := newTestClient(t, options, nil)
+
+			got := convertOne(t, client, attachedIssue("INCI-1")).Attachments
+			if len(got) != len(tt.want) {
+				t.Fatalf("Attachments = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Filename != tt.want[i].Filename || got[i].Size != tt.want[i].Size || !got[i].Created.Equal(tt.want[i].Created) || got[i].Author != tt.want[i].Author {
+					t.Errorf("attachment %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+			if got := slices.Contains(client.requestFields(), "attachment"); got != tt.wantField {
+				t.Errorf("attachment requested = %v, want %v", got, tt.wantField)
+			}
+		})
+	}
+}
+
+// flakySearcher fails the first searches with the queued statuses, 0 standing for a network error
+type flakySearcher struct {
+	pageSearcher
+	statuses []int
+	attempts int
+}
+
+func (s *flakySearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	s.mu.Lock()
+	s.attempts++
+	var status int
+	failing := len(s.statuses) > 0
+	if failing {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	s.mu.Unlock()
+
+	if !failing {
+		return s.pageSearcher.Search(ctx, jql, options)
+	}
+	if status == 0 {
+		return nil, nil, errors.New("connection reset by peer")
+	}
+	return nil, &jira.Response{Response: &http.Response{StatusCode: status}}, fmt.Errorf("request failed with status %d", status)
+}
+
+func TestSearchWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxRetries   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "no failure", maxRetries: 3, wantAttempts: 1},
+		{name: "server errors retried", statuses: []int{500, 503}, maxRetries: 3, wantAttempts: 3},
+		{name: "network error retried", statuses: []int{0}, maxRetries: 1, wantAttempts: 2},
+		{name: "retries exhausted", statuses: []int{502, 502, 502}, maxRetries: 2, wantAttempts: 3, wantErr: true},
+		{name: "client error not retried", statuses: []int{400}, maxRetries: 3, wantAttempts: 1, wantErr: true},
+		{name: "retries disabled", statuses: []int{500}, maxRetries: 0, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.MaxRetries = tt.maxRetries
+			searcher := &flakySearcher{pageSearcher: pageSearcher{issues: testIssues(2)}, statuses: tt.statuses}
+			client := newTestClient(t, options, searcher)
+
+			issues, _, err := client.searchWithRetry(context.Background(), client.obs, "project = INCI", &jira.SearchOptions{MaxResults: 10})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("searchWithRetry() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(issues) != 2 {
+				t.Errorf("searchWithRetry() returned %d issues, want 2", len(issues))
+			}
+			if searcher.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", searcher.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestSearchWithRetryStopsOnCancel(t *testing.T) {
+	options := testOptions()
+	options.MaxRetries = 5
+	options.RetryBackoff = int(time.Hour / time.Millisecond)
+	searcher := &flakySearcher{statuses: []int{500, 500}}
+	client := newTestClient(t, options, searcher)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := client.searchWithRetry(ctx, client.obs, "project = INCI", &jira.SearchOptions{MaxResults: 10}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("searchWithRetry() = %v, want the deadline error", err)
+	}
+	if searcher.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", searcher.attempts)
+	}
+}
+
+// cancellingSearcher cancels the search context once the first page is served
+type cancellingSearcher struct {
+	pageSearcher
+	cancel context.CancelFunc
+}
+
+func (s *cancellingSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	issues, resp, err := s.pageSearcher.Search(ctx, jql, options)
+	s.cancel()
+	return issues, resp, err
+}
+
+func TestSearchIssuesStopsPagingOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := testOptions()
+	options.FetchConcurrency = 1
+	searcher := &cancellingSearcher{pageSearcher: pageSearcher{issues: testIssues(10), pageSize: 2}, cancel: cancel}
+	client := newTestClient(t, options, searcher)
+
+	issues, err := client.searchIssues(ctx, "project = INCI")
+	if !errors.Is(err, context.Canceled) || issues != nil {
+		t.Errorf("searchIssues() = %d issues, %v, want no issues and the cancellation", len(issues), err)
+	}
+	if len(searcher.calls) != 1 {
+		t.Errorf("searched %d pages after the cancellation, want 1", len(searcher.calls))
+	}
+}
+
+func TestConvertDeclaredFields(t *testing.T) {
+	client := newTestClient(t, testOptions(), nil)
+	at := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	stamp := "2024-03-05T10:30:00.000+0000"
+
+	issue := convertOne(t, client, testIssue("INCI-1", at, map[string]interface{}{
+		"customfield_20905": stamp,
+		"customfield_20911": stamp,
+		"customfield_21201": stamp,
+		"customfield_21501": []interface{}{map[string]interface{}{"value": "eu"}, map[string]interface{}{"value": "us"}},
+		"customfield_24800": map[string]interface{}{"value": "Rollback"},
+		"customfield_29800": "production",
+		"customfield_28222": "checkout",
+		"customfield_32112": map[string]interface{}{"value": "Payments"},
+		"customfield_30304": 7.0,
+	}))
+
+	tests := []struct {
+		field string
+		got   interface{}
+		want  interface{}
+	}{
+		{"Fixed", issue.Fixed, at},
+		{"Detected", issue.Detected, at},
+		{"Escalated", issue.Escalated, at},
+		{"Regions", issue.Regions, "eu,us"},
+		{"Recovery", issue.Recovery, "Rollback"},
+		{"Environment", issue.Environment, "production"},
+		{"Application", issue.Application, "checkout"},
+		{"BusinessProcess", issue.BusinessProcess, "Payments"},
+		{"Impact", issue.Impact, 7},
+	}
+	for _, tt := range tests {
+		if got, ok := tt.got.(time.Time); ok {
+			if !got.Equal(tt.want.(time.Time)) {
+				t.Errorf("%s = %v, want %v", tt.field, got, tt.want)
+			}
+			continue
+		}
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestJiraIssueJSONOmitsZeroValues(t *testing.T) {
+	created := time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		issue       JiraIssue
+		wantKeys    []string
+		missingKeys []string
+	}{
+		{
+			name:        "zero values omitted",
+			issue:       JiraIssue{Key: "INCI-1", Created: created, Updated: created},
+			wantKeys:    []string{"key", "created", "updated"},
+			missingKeys: []string{"resolved", "closed", "started", "firefighting", "fixed", "detected", "escalated", "done", "summary", "severity", "components", "impact", "score"},
+		},
+		{
+			name:        "set values kept",
+			issue:       JiraIssue{Key: "INCI-1", Resolved: created, Started: created, Severity: "SEV1", Score: 3},
+			wantKeys:    []string{"resolved", "started", "severity", "score"},
+			missingKeys: []string{"closed", "done"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(&tt.issue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("%s missing in %s", key, data)
+				}
+			}
+			for _, key := range tt.missingKeys {
+				if _, ok := fields[key]; ok {
+					t.Errorf("%s not omitted in %s", key, data)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectClause(t *testing.T) {
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"", ""},
+		{" , ", ""},
+		{"INCI", "project = INCI"},
+		{"INCI, OPS,", "project in (INCI,OPS)"},
+	}
+	for _, tt := range tests {
+		if got := projectClause(tt.keys); got != tt.want {
+			t.Errorf("projectClause(%q) = %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestCachedIssuesByProject(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ops := testIssue("OPS-1", created, nil)
+	ops.Fields.Project = jira.Project{Key: "OPS"}
+	moved := testIssue("INCI-7", created, nil)
+	moved.Fields.Project = jira.Project{Key: "OPS"}
+
+	options := testOptions()
+	options.ProjectKey = "INCI,OPS"
+	searcher := &pageSearcher{issues: []jira.Issue{testIssue("INCI-1", created, nil), ops, moved}}
+	client := newTestClient(t, options, searcher)
+	client.RefreshData(context.Background())
+
+	if !strings.HasPrefix(searcher.jqls[0], "project in (INCI,OPS) AND ") {
+		t.Errorf("JQL = %q, want both projects", searcher.jqls[0])
+	}
+	tests := []struct {
+		project string
+		want    int
+	}{
+		{"INCI", 1},
+		{"OPS", 2},
+		{"NONE", 0},
+	}
+	for _, tt := range tests {
+		if got := len(client.GetCachedProjectIssues(tt.project)); got != tt.want {
+			t.Errorf("project %s has %d issues, want %d", tt.project, got, tt.want)
+		}
+	}
+}
+
+func TestMergeIssues(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := func(key string, minute int, status string, done bool) *JiraIssue {
+		i := &JiraIssue{Key: key, Created: base.Add(time.Duration(minute) * time.Minute), Status: status}
+		if done {
+			i.Done = base
+		}
+		return i
+	}
+	keys := func(issues []*JiraIssue) []string {
+		var keys []string
+		for _, i := range issues {
+			keys = append(keys, i.Key)
+		}
+		return keys
+	}
+	cached := []*JiraIssue{issue("INCI-2", 2, "Open", false), issue("INCI-1", 1, "Open", false)}
+
+	tests := []struct {
+		name     string
+		updated  []*JiraIssue
+		openOnly bool
+		want     []string
+		status   string
+	}{
+		{name: "new issue added newest first", updated: []*JiraIssue{issue("INCI-3", 3, "Open", false)}, want: []string{"INCI-3", "INCI-2", "INCI-1"}},
+		{name: "updated issue replaced", updated: []*JiraIssue{issue("INCI-1", 1, "In Progress", false)}, want: []string{"INCI-2", "INCI-1"}, status: "In Progress"},
+		{name: "excluded status dropped", updated: []*JiraIssue{issue("INCI-1", 1, excludedStatuses[0], false)}, want: []string{"INCI-2"}},
+		{name: "resolved kept in full scope", updated: []*JiraIssue{issue("INCI-1", 1, "Done", true)}, want: []string{"INCI-2", "INCI-1"}, status: "Done"},
+		{name: "resolved dropped in open scope", updated: []*JiraIssue{issue("INCI-1", 1, "Done", true)}, openOnly: true, want: []string{"INCI-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := mergeIssues(cached, tt.updated, tt.openOnly)
+			if got := keys(merged); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeIssues() = %v, want %v", got, tt.want)
+			}
+			if tt.status != "" && merged[len(merged)-1].Status != tt.status {
+				t.Errorf("INCI-1 status = %q, want %q", merged[len(merged)-1].Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestIncrementalRefreshQuery(t *testing.T) {
+	options := testOptions()
+	options.IncrementalRefresh = true
+	searcher := &pageSearcher{issues: testIssues(2)}
+	client := newTestClient(t, options, searcher)
+
+	client.RefreshData(context.Background())
+	client.RefreshData(context.Background())
+
+	if len(searcher.jqls) != 2 {
+		t.Fatalf("searched %d times, want 2", len(searcher.jqls))
+	}
+	if strings.Contains(searcher.jqls[0], "updated >=") {
+		t.Errorf("first refresh is not a full one: %s", searcher.jqls[0])
+	}
+	if !strings.Contains(searcher.jqls[1], `updated >= "`) || strings.Contains(searcher.jqls[1], "status not in") {
+		t.Errorf("second refresh is not incremental: %s", searcher.jqls[1])
+	}
+	if got := len(client.GetCachedIssues()); got != 2 {
+		t.Errorf("cached %d issues after the incremental refresh, want 2", got)
+	}
+}
+
+func TestCustomJQLOverridesQuery(t *testing.T) {
+	const custom = "project = OPS AND labels = incident ORDER BY key"
+	tests := []struct {
+		name    string
+		options func(*JiraOptions)
+		want    string
+	}{
+		{"custom query as is", func(o *JiraOptions) { o.JQL = "  " + custom + " " }, custom},
+		{"filter not appended", func(o *JiraOptions) { o.JQL = custom; o.QueryFilter = "priority = High" }, custom},
+		{"custom query without a project", func(o *JiraOptions) { o.JQL = custom; o.ProjectKey = "" }, custom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.IncrementalRefresh = true
+			tt.options(&options)
+			searcher := &pageSearcher{issues: testIssues(1)}
+			client := newTestClient(t, options, searcher)
+
+			// Refreshes stay full ones, the custom query can't be narrowed to updated issues
+			client.RefreshData(context.Background())
+			client.RefreshData(context.Background())
+			for _, jql := range searcher.jqls {
+				if jql != tt.want {
+					t.Errorf("JQL = %q, want %q", jql, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetIssuesCreatedBetween(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 6, 30, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		since    time.Time
+		until    time.Time
+		jql      string
+		timezone string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:  "both bounds",
+			since: since,
+			until: until,
+			want:  `project = INCI AND created >= "2024/01/01 00:00" AND created <= "2024/06/30 23:59" AND status not in (Cancelled,Rejected) ORDER BY created DESC`,
+		},
+		{
+			name:  "open start",
+			until: until,
+			want:  `project = INCI AND created <= "2024/06/30 23:59" AND status not in (Cancelled,Rejected) ORDER BY created DESC`,
+		},
+		{
+			name:     "jira timezone",
+			since:    since,
+			until:    until,
+			timezone: "America/New_York",
+			want:     `project = INCI AND created >= "2023/12/31 19:00" AND created <= "2024/06/30 19:59" AND status not in (Cancelled,Rejected) ORDER BY created DESC`,
+		},
+		{name: "until before since", since: until, until: since, wantErr: true},
+		{name: "custom jql", since: since, jql: "project = OPS", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.LookbackDays = 30
+			options.JQL = tt.jql
+			options.Timezone = tt.timezone
+			searcher := &pageSearcher{issues: testIssues(1)}
+			client := newTestClient(t, options, searcher)
+
+			_, err := client.GetIssuesCreatedBetween(context.Background(), tt.since, tt.until)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetIssuesCreatedBetween() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(searcher.jqls) != 0 {
+					t.Errorf("searched %v with an invalid window", searcher.jqls)
+				}
+				return
+			}
+			if len(searcher.jqls) != 1 || searcher.jqls[0] != tt.want {
+				t.Errorf("JQL = %q, want %q", searcher.jqls, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnscopedQueryRejected(t *testing.T) {
+	options := testOptions()
+	options.ProjectKey = " , "
+	if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+		t.Error("expected an error without a project key, query filter or custom jql")
+	}
+}
+
+func TestSearchPageSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxResults  int
+		serverCap   int
+		concurrency int
+		wantSize    int
+		wantPages   int
+	}{
+		{name: "default page size", wantSize: 1000, wantPages: 1},
+		{name: "configured page size", maxResults: 20, concurrency: 1, wantSize: 20, wantPages: 3},
+		{name: "server capping the page size", maxResults: 1000, serverCap: 25, concurrency: 1, wantSize: 1000, wantPages: 2},
+		{name: "server capping with concurrent pages", serverCap: 10, concurrency: 4, wantSize: 1000, wantPages: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.MaxResults = tt.maxResults
+			options.FetchConcurrency = tt.concurrency
+			searcher := &pageSearcher{issues: testIssues(45), pageSize: tt.serverCap}
+			client := newTestClient(t, options, searcher)
+
+			issues, err := client.searchIssues(context.Background(), "project = INCI")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != 45 {
+				t.Errorf("fetched %d issues, want 45", len(issues))
+			}
+			if len(searcher.calls) != tt.wantPages {
+				t.Errorf("searched %d pages, want %d", len(searcher.calls), tt.wantPages)
+			}
+			if got := searcher.calls[0].MaxResults; got != tt.wantSize {
+				t.Errorf("requested page size %d, want %d", got, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestSearchMaxIssues(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxResults  int
+		serverCap   int
+		maxIssues   int
+		concurrency int
+		wantIssues  int
+		wantPages   int
+	}{
+		{name: "cap across page boundaries", maxResults: 10, maxIssues: 25, concurrency: 1, wantIssues: 25, wantPages: 3},
+		{name: "cap on a page boundary", maxResults: 10, maxIssues: 20, concurrency: 1, wantIssues: 20, wantPages: 2},
+		{name: "cap with concurrent pages", maxResults: 10, maxIssues: 25, concurrency: 4, wantIssues: 25, wantPages: 3},
+		{name: "cap below the page size", maxIssues: 7, concurrency: 1, wantIssues: 7, wantPages: 1},
+		{name: "cap with the server capping the page size", serverCap: 10, maxIssues: 25, concurrency: 1, wantIssues: 25, wantPages: 3},
+		{name: "cap above the matched total", maxResults: 10, maxIssues: 100, concurrency: 1, wantIssues: 45, wantPages: 5},
+		{name: "unlimited", maxResults: 10, concurrency: 1, wantIssues: 45, wantPages: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.MaxResults = tt.maxResults
+			options.MaxIssues = tt.maxIssues
+			options.FetchConcurrency = tt.concurrency
+			all := testIssues(45)
+			searcher := &pageSearcher{issues: all, pageSize: tt.serverCap}
+			client := newTestClient(t, options, searcher)
+
+			issues, err := client.searchIssues(context.Background(), "project = INCI")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != tt.wantIssues {
+				t.Fatalf("fetched %d issues, want %d", len(issues), tt.wantIssues)
+			}
+			for n, issue := range issues {
+				if issue.Key != all[n].Key {
+					t.Fatalf("issue %d is %s, want %s", n, issue.Key, all[n].Key)
+				}
+			}
+			if len(searcher.calls) != tt.wantPages {
+				t.Errorf("searched %d pages, want %d", len(searcher.calls), tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestInvalidMaxResultsRejected(t *testing.T) {
+	for _, maxResults := range []int{-1, 1001} {
+		options := testOptions()
+		options.MaxResults = maxResults
+		if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+			t.Errorf("max results %d accepted", maxResults)
+		}
+	}
+}
+
+func TestLookbackQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		options func(*JiraOptions)
+		want    string
+	}{
+		{"default window", func(o *JiraOptions) {}, "created>=" + defaultLookback + " AND"},
+		{"relative function", func(o *JiraOptions) { o.LookbackJQL = " startOfYear(-2y) " }, "created>=startOfYear(-2y) AND"},
+		{"absolute date", func(o *JiraOptions) { o.LookbackJQL = `"2024/01/01"` }, `created>="2024/01/01" AND`},
+		{"days", func(o *JiraOptions) { o.LookbackDays = 30 }, "created>=-30d AND"},
+		{"days take precedence", func(o *JiraOptions) { o.LookbackDays = 90; o.LookbackJQL = "startOfYear(-2y)" }, "created>=-90d AND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			tt.options(&options)
+			searcher := &pageSearcher{issues: testIssues(1)}
+			client := newTestClient(t, options, searcher)
+
+			client.RefreshData(context.Background())
+			if len(searcher.jqls) != 1 {
+				t.Fatalf("searched %d times, want 1", len(searcher.jqls))
+			}
+			if !strings.Contains(searcher.jqls[0], tt.want) {
+				t.Errorf("JQL = %q, want it to contain %q", searcher.jqls[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidLookbackRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		options func(*JiraOptions)
+	}{
+		{"negative days", func(o *JiraOptions) { o.LookbackDays = -1 }},
+		{"unbalanced parentheses", func(o *JiraOptions) { o.LookbackJQL = "startOfYear(-1y" }},
+		{"ordering", func(o *JiraOptions) { o.LookbackJQL = "-30d ORDER BY key" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			tt.options(&options)
+			if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+				t.Error("invalid lookback accepted")
+			}
+		})
+	}
+}
+
+func TestScoreIssue(t *testing.T) {
+	weights := map[string]int{"SEV1": 10, "SEV2": 5}
+	tests := []struct {
+		name    string
+		weights map[string]int
+		issue   JiraIssue
+		want    int
+	}{
+		{"impact without weights", nil, JiraIssue{Severity: "SEV1", Impact: 3}, 3},
+		{"no impact without weights", nil, JiraIssue{Severity: "SEV1"}, 0},
+		{"weighted impact", weights, JiraIssue{Severity: "SEV2", Impact: 3}, 15},
+		{"severity alone", weights, JiraIssue{Severity: "SEV1"}, 10},
+		{"unknown severity", weights, JiraIssue{Severity: "SEV9", Impact: 4}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.SeverityWeights = tt.weights
+			client := newTestClient(t, options, nil)
+			if got := client.ScoreIssue(&tt.issue); got != tt.want {
+				t.Errorf("ScoreIssue() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// conversionIssues builds issues with varying mapped fields, components and users
+func conversionIssues(n int) []*jira.Issue {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	severities := []string{"SEV1", "SEV2", "SEV3", "sev9"}
+	issues := make([]*jira.Issue, 0, n)
+	for i := range n {
+		issue := testIssue(fmt.Sprintf("INCI-%d", i), base.Add(time.Duration(i)*time.Minute), map[string]interface{}{
+			"customfield_18119": map[string]interface{}{"value": severities[i%len(severities)]},
+			"customfield_33803": fmt.Sprintf("service-%d", i%7),
+			"customfield_18117": base.Add(time.Duration(i) * time.Hour).Format(jiraDateTimeLayout),
+		})
+		issue.Fields.Assignee = &jira.User{Name: fmt.Sprintf("user%d", i%5), DisplayName: fmt.Sprintf("User %d", i%5)}
+		issue.Fields.Components = []*jira.Component{{Name: fmt.Sprintf("component-%d", i%3)}}
+		issues = append(issues, &issue)
+	}
+	return issues
+}
+
+func TestConvertWorkersKeepOrder(t *testing.T) {
+	issues := conversionIssues(257)
+
+	options := testOptions()
+	options.ConvertWorkers = 1
+	want, err := newTestClient(t, options, nil).ConvertToCustomIssues(issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 2, 8, 1000} {
+		options.ConvertWorkers = workers
+		got, err := newTestClient(t, options, nil).ConvertToCustomIssues(issues)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers converted differently from the sequential conversion", workers)
+		}
+	}
+}
+
+func TestInvalidConvertWorkersRejected(t *testing.T) {
+	options := testOptions()
+	options.ConvertWorkers = -1
+	if _, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil); err == nil {
+		t.Error("negative convert workers accepted")
+	}
+}
+
+func BenchmarkConvertToCustomIssues(b *testing.B) {
+	issues := conversionIssues(20000)
+	for name, workers := range map[string]int{"sequential": 1, "parallel": runtime.NumCPU()} {
+		b.Run(name, func(b *testing.B) {
+			options := testOptions()
+			options.ConvertWorkers = workers
+			client, err := NewJiraClient(options, NewObservability(nil, nil, nil), nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for range b.N {
+				if _, err := client.ConvertToCustomIssues(issues); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertScoresIssues(t *testing.T) {
+	options := testOptions()
+	options.SeverityWeights = map[string]int{"SEV1": 10}
+	client := newTestClient(t, options, nil)
+
+	issue := convertOne(t, client, testIssue("INCI-1", time.Now(), map[string]interface{}{
+		"customfield_18119": map[string]interface{}{"value": "SEV1"},
+		"customfield_30304": "2",
+	}))
+	if issue.Impact != 2 || issue.Score != 20 {
+		t.Errorf("impact %d, score %d, want 2 and 20", issue.Impact, issue.Score)
+	}
+}
+
+func TestGetIssueByKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/INCI-5" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages":["Issue does not exist"]}`))
+			return
+		}
+		w.Write([]byte(`{"id":"5","key":"INCI-5","fields":{"summary":"Fetched on demand","created":"2024-03-05T10:30:00.000+0000","status":{"name":"Open"},"customfield_18119":{"value":"SEV1"}}}`))
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	options.CacheFilePath = filepath.Join(t.TempDir(), "cache.json")
+	client := newTestClient(t, options, &pageSearcher{issues: testIssues(2)})
+	sink := &recordingSink{}
+	client.AddSink(sink)
+	client.RefreshData(context.Background())
+
+	issue, err := client.GetIssueByKey(context.Background(), "INCI-5")
+	if err != nil {
+		t.Fatalf("GetIssueByKey: %v", err)
+	}
+	if issue.Summary != "Fetched on demand" || issue.Severity != "SEV1" {
+		t.Errorf("GetIssueByKey() = %+v", issue)
+	}
+	if cached, ok := client.GetCachedIssue("INCI-5"); !ok || cached.Summary != issue.Summary {
+		t.Error("fetched issue not added to the cache")
+	}
+	if got := len(client.GetCachedIssues()); got != 3 {
+		t.Errorf("cache holds %d issues, want 3", got)
+	}
+
+	// The sinks get the updated cache like after a refresh
+	if len(sink.writes) != 2 || len(sink.writes[1]) != 3 {
+		t.Errorf("sink writes = %d, want the refresh and the fetched issue with 3 issues", len(sink.writes))
+	}
+	restarted := newTestClient(t, options, nil)
+	restarted.LoadCacheFile()
+	if cached, ok := restarted.GetCachedIssue("INCI-5"); !ok || cached.Summary != issue.Summary {
+		t.Error("fetched issue not saved to the cache file")
+	}
+
+	if _, err := client.GetIssueByKey(context.Background(), "INCI-404"); err == nil {
+		t.Error("expected an error for a missing issue")
+	}
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	options.HTTPTimeout = 1
+	client := newTestClient(t, options, nil)
+
+	started := time.Now()
+	if _, err := client.GetIssueByKey(context.Background(), "INCI-1"); err == nil {
+		t.Fatal("expected the hung request to time out")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("request took %s with a 1s timeout", elapsed)
+	}
+}
+
+func TestAuthErrorsClassified(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantErr      bool
+		wantAuth     bool
+		wantCode     string
+	}{
+		{name: "unauthorized not retried", statuses: []int{401, 401, 401}, wantAttempts: 1, wantErr: true, wantAuth: true, wantCode: "401"},
+		{name: "forbidden not retried", statuses: []int{403, 403, 403}, wantAttempts: 1, wantErr: true, wantAuth: true, wantCode: "403"},
+		{name: "unavailable retried", statuses: []int{503, 503}, wantAttempts: 3},
+		{name: "unavailable exhausted", statuses: []int{503, 503, 503}, wantAttempts: 3, wantErr: true, wantCode: "503"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.MaxRetries = 2
+			searcher := &flakySearcher{pageSearcher: pageSearcher{issues: testIssues(2)}, statuses: tt.statuses}
+			client, meter := newMeteredClient(t, options, searcher)
+
+			_, err := client.GetIssues(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetIssues() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrAuth); got != tt.wantAuth {
+				t.Errorf("errors.Is(%v, ErrAuth) = %v, want %v", err, got, tt.wantAuth)
+			}
+			if searcher.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", searcher.attempts, tt.wantAttempts)
+			}
+
+			wantAuthErrors := 0.0
+			if tt.wantAuth {
+				wantAuthErrors = 1
+			}
+			if got, _ := meter.value("jira_auth_errors_total", map[string]string{"code": tt.wantCode}); got != wantAuthErrors {
+				t.Errorf("jira_auth_errors_total{code=%s} = %v, want %v", tt.wantCode, got, wantAuthErrors)
+			}
+			if tt.wantErr {
+				if got, _ := meter.value("jira_api_errors_total", map[string]string{"code": tt.wantCode}); got != 1 {
+					t.Errorf("jira_api_errors_total{code=%s} = %v, want 1", tt.wantCode, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionAuthError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	client, meter := newMeteredClient(t, options, nil)
+	if err := client.TestConnection(context.Background()); !errors.Is(err, ErrAuth) {
+		t.Errorf("TestConnection() = %v, want an ErrAuth", err)
+	}
+	if got, _ := meter.value("jira_auth_errors_total", map[string]string{"code": "401"}); got != 1 {
+		t.Errorf("jira_auth_errors_total = %v, want 1", got)
+	}
+}
+
+func TestConnectionTimeoutAndRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		slowCalls int32
+		status    int
+		retries   int
+		wantErr   bool
+		wantCalls int32
+	}{
+		{name: "connected", status: http.StatusOK, retries: 2, wantCalls: 1},
+		{name: "slow attempt retried", slowCalls: 1, status: http.StatusOK, retries: 2, wantCalls: 2},
+		{name: "every attempt too slow", slowCalls: 10, status: http.StatusOK, retries: 2, wantErr: true, wantCalls: 3},
+		{name: "unavailable retried", status: http.StatusServiceUnavailable, retries: 1, wantErr: true, wantCalls: 2},
+		{name: "wrong credentials not retried", status: http.StatusUnauthorized, retries: 2, wantErr: true, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.slowCalls {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(10 * time.Second):
+					}
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"name":"aim"}`))
+			}))
+			defer server.Close()
+
+			options := testOptions()
+			options.URL = server.URL
+			options.MaxRetries = tt.retries
+			client := newTestClient(t, options, nil)
+			client.connectTimeout = 50 * time.Millisecond
+
+			started := time.Now()
+			err := client.TestConnection(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TestConnection() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("%d attempts, want %d", calls.Load(), tt.wantCalls)
+			}
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("connection test took %s with a 50ms timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestConnectionStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	options.MaxRetries = 5
+	client := newTestClient(t, options, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := client.TestConnection(ctx); err == nil {
+		t.Fatal("TestConnection() succeeded against a hung Jira")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("connection test took %s after the caller gave up", elapsed)
+	}
+}
+
+func TestConvertPriorityAndComponents(t *testing.T) {
+	options := testOptions()
+	options.PriorityMap = map[string]string{"Highest": "SEV1"}
+	client := newTestClient(t, options, nil)
+
+	tests := []struct {
+		name           string
+		priority       *jira.Priority
+		components     []*jira.Component
+		severity       interface{}
+		wantPriority   string
+		wantComponents []string
+		wantSeverity   string
+	}{
+		{
+			name:           "priority and components",
+			priority:       &jira.Priority{Name: "High"},
+			components:     []*jira.Component{{Name: "api"}, nil, {Name: ""}, {Name: "db"}},
+			wantPriority:   "High",
+			wantComponents: []string{"api", "db"},
+		},
+		{
+			name:         "severity from mapped priority",
+			priority:     &jira.Priority{Name: "Highest"},
+			wantPriority: "Highest",
+			wantSeverity: "SEV1",
+		},
+		{
+			name:         "severity field wins over priority",
+			priority:     &jira.Priority{Name: "Highest"},
+			severity:     "SEV3",
+			wantPriority: "Highest",
+			wantSeverity: "SEV3",
+		},
+		{name: "no priority"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknowns := map[string]interface{}{}
+			if tt.severity != nil {
+				unknowns["customfield_18119"] = tt.severity
+			}
+			issue := testIssue("INCI-1", time.Now(), unknowns)
+			issue.Fields.Priority = tt.priority
+			issue.Fields.Components = tt.components
+
+			got := convertOne(t, client, issue)
+			if got.Priority != tt.wantPriority || got.Severity != tt.wantSeverity || !reflect.DeepEqual(got.Components, tt.wantComponents) {
+				t.Errorf("priority %q, severity %q, components %q, want %q, %q, %q",
+					got.Priority, got.Severity, got.Components, tt.wantPriority, tt.wantSeverity, tt.wantComponents)
+			}
+		})
+	}
+}
+
+func TestLabelFilter(t *testing.T) {
+	labeled := func(key string, labels ...string) jira.Issue {
+		issue := testIssue(key, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), nil)
+		issue.Fields.Labels = labels
+		return issue
+	}
+	issues := []jira.Issue{
+		labeled("INCI-1", "incident", "customer-facing"),
+		labeled("INCI-2", "incident"),
+		labeled("INCI-3"),
+	}
+
+	tests := []struct {
+		name   string
+		filter []string
+		want   int
+	}{
+		{"no filter", nil, 3},
+		{"single label", []string{"incident"}, 2},
+		{"all labels required", []string{"incident", " customer-facing "}, 1},
+		{"blank labels ignored", []string{"", " "}, 3},
+		{"unknown label", []string{"postmortem"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.LabelFilter = tt.filter
+			client := newTestClient(t, options, &pageSearcher{issues: issues})
+			client.RefreshData(context.Background())
+			if got := len(client.GetCachedIssues()); got != tt.want {
+				t.Errorf("cached %d issues, want %d", got, tt.want)
+			}
+		})
+	}
+
+	client := newTestClient(t, testOptions(), nil)
+	if got := convertOne(t, client, issues[0]).Labels; !reflect.DeepEqual(got, []string{"incident", "customer-facing"}) {
+		t.Errorf("Labels = %q", got)
+	}
+}
+
+// environmentIssues are incidents of production, staging and an unset environment, with raw field values
+func environmentIssues() []jira.Issue {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []jira.Issue{
+		testIssue("INCI-1", created, map[string]interface{}{"customfield_29800": "Production"}),
+		testIssue("INCI-2", created, map[string]interface{}{"customfield_29800": map[string]interface{}{"value": "prd"}}),
+		testIssue("INCI-3", created, map[string]interface{}{"customfield_29800": "stg"}),
+		testIssue("INCI-4", created, nil),
+	}
+}
+
+func TestOnlyEnvironments(t *testing.T) {
+	synonyms := map[string]string{"production": "prod", "prd": "prod", "stg": "stage"}
+	tests := []struct {
+		name     string
+		only     []string
+		wantKeys []string
+	}{
+		{name: "no filter", wantKeys: []string{"INCI-1", "INCI-2", "INCI-3", "INCI-4"}},
+		{name: "prod only", only: []string{"prod"}, wantKeys: []string{"INCI-1", "INCI-2"}},
+		{name: "synonym in the filter", only: []string{" production "}, wantKeys: []string{"INCI-1", "INCI-2"}},
+		{name: "several environments", only: []string{"PROD", "stage"}, wantKeys: []string{"INCI-1", "INCI-2", "INCI-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.EnvironmentSynonyms = synonyms
+			options.OnlyEnvironments = tt.only
+			client := newTestClient(t, options, &pageSearcher{issues: environmentIssues()})
+			client.RefreshData(context.Background())
+
+			var keys []string
+			for _, issue := range client.GetCachedIssues() {
+				keys = append(keys, issue.Key)
+			}
+			slices.Sort(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("cached %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+
+	options := testOptions()
+	options.EnvironmentSynonyms = synonyms
+	client := newTestClient(t, options, nil)
+	for n, want := range []string{"prod", "prod", "stage", ""} {
+		if got := convertOne(t, client, environmentIssues()[n]).Environment; got != want {
+			t.Errorf("INCI-%d Environment = %q, want %q", n+1, got, want)
+		}
+	}
+}
+
+// slowSearcher serves pages with a delay, recording the highest number of searches in flight
+type slowSearcher struct {
+	pageSearcher
+	delay    time.Duration
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *slowSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+	return s.pageSearcher.Search(ctx, jql, options)
+}
+
+func TestSearchPagesConcurrently(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		fail        map[int]error
+		wantIssues  int
+		wantErr     bool
+	}{
+		{name: "sequential", concurrency: 1, wantIssues: 50},
+		{name: "bounded workers", concurrency: 3, wantIssues: 50},
+		{name: "failed page", concurrency: 3, fail: map[int]error{30: errors.New("page failed")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.FetchConcurrency = tt.concurrency
+			searcher := &slowSearcher{pageSearcher: pageSearcher{issues: testIssues(50), pageSize: 5, fail: tt.fail}, delay: 5 * time.Millisecond}
+			client := newTestClient(t, options, searcher)
+
+			issues, err := client.searchIssues(context.Background(), "project = INCI")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("searchIssues() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(issues) != tt.wantIssues {
+				t.Errorf("fetched %d issues, want %d", len(issues), tt.wantIssues)
+			}
+			if !tt.wantErr && !issuesNewestFirst(issues) {
+				t.Error("pages fetched concurrently are not in order")
+			}
+			if peak := int(searcher.peak.Load()); peak > tt.concurrency {
+				t.Errorf("%d searches in flight, want at most %d", peak, tt.concurrency)
+			}
+		})
+	}
+}
+
+// issuesNewestFirst reports whether the issues are sorted by creation time, newest first
+func issuesNewestFirst(issues []*jira.Issue) bool {
+	for i := 1; i < len(issues); i++ {
+		if time.Time(issues[i].Fields.Created).After(time.Time(issues[i-1].Fields.Created)) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockingSearcher signals every search on started and blocks it until release is closed
+type blockingSearcher struct {
+	pageSearcher
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	s.started <- struct{}{}
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	return s.pageSearcher.Search(ctx, jql, options)
+}
+
+func TestPartialRefresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      bool
+		concurrency int
+		wantCached  int
+	}{
+		{name: "partial results discarded", accept: false, concurrency: 1, wantCached: 0},
+		{name: "partial results cached", accept: true, concurrency: 1, wantCached: 4},
+		{name: "concurrent partial results cached", accept: true, concurrency: 3, wantCached: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.AcceptPartialRefresh = tt.accept
+			options.FetchConcurrency = tt.concurrency
+			searcher := &pageSearcher{issues: testIssues(6), pageSize: 2, fail: map[int]error{4: errors.New("page failed")}}
+			client := newTestClient(t, options, searcher)
+
+			issues, err := client.GetIssues(context.Background())
+			if !errors.Is(err, ErrPartial) {
+				t.Fatalf("GetIssues error = %v, want ErrPartial", err)
+			}
+			if len(issues) != 4 {
+				t.Errorf("GetIssues returned %d partial issues, want 4", len(issues))
+			}
+
+			client.RefreshData(context.Background())
+			if got := len(client.GetCachedIssues()); got != tt.wantCached {
+				t.Errorf("cached %d issues, want %d", got, tt.wantCached)
+			}
+			if refreshed := !client.GetLastRefreshTime().IsZero(); refreshed != tt.accept {
+				t.Errorf("refreshed = %v, want %v", refreshed, tt.accept)
+			}
+		})
+	}
+}
+
+func TestPartialRefreshKeepsCachedIssues(t *testing.T) {
+	options := testOptions()
+	options.AcceptPartialRefresh = true
+	options.FetchConcurrency = 1
+	searcher := &pageSearcher{issues: testIssues(6), pageSize: 2}
+	client := newTestClient(t, options, searcher)
+	client.RefreshData(context.Background())
+
+	// The issues of the failed page stay in the cache
+	searcher.fail = map[int]error{2: errors.New("page failed")}
+	client.RefreshData(context.Background())
+	if got := len(client.GetCachedIssues()); got != 6 {
+		t.Errorf("cached %d issues after a partial refresh, want 6", got)
+	}
+}
+
+func TestCancelledSearchHasNoPartialResults(t *testing.T) {
+	options := testOptions()
+	options.AcceptPartialRefresh = true
+	options.FetchConcurrency = 1
+	client := newTestClient(t, options, &pageSearcher{issues: testIssues(6), pageSize: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	issues, err := client.GetIssues(ctx)
+	if err == nil || errors.Is(err, ErrPartial) || issues != nil {
+		t.Errorf("GetIssues = %d issues, %v, want no issues and a non partial error", len(issues), err)
+	}
+}
+
+func TestRefreshLoopDrainsRunningRefresh(t *testing.T) {
+	options := testOptions()
+	options.RefreshInterval = 3600
+	options.CacheFilePath = filepath.Join(t.TempDir(), "cache.json")
+	searcher := &blockingSearcher{
+		pageSearcher: pageSearcher{issues: testIssues(2)},
+		started:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+	client := newTestClient(t, options, searcher)
+
+	ctx, stop := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	client.StartRefreshLoop(ctx, context.Background(), &wg)
+	<-searcher.started
+
+	// The first signal stops scheduling while the refresh is running
+	stop()
+	flushed := make(chan error, 1)
+	go func() { flushed <- client.Flush(context.Background()) }()
+
+	select {
+	case err := <-flushed:
+		t.Fatalf("Flush() = %v before the running refresh completed", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(searcher.release)
+	wg.Wait()
+	if err := <-flushed; err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+	if got := len(client.GetCachedIssues()); got != 2 {
+		t.Errorf("cached %d issues, want the 2 of the drained refresh", got)
+	}
+	data, err := os.ReadFile(options.CacheFilePath)
+	if err != nil || !strings.Contains(string(data), "INCI-1") {
+		t.Errorf("cache file = %q, %v, want the issues of the drained refresh", data, err)
+	}
+	if len(searcher.calls) != 1 {
+		t.Errorf("searches = %d, want no refresh scheduled after the stop", len(searcher.calls))
+	}
+}
+
+func TestRefreshLoopAbortedByRefreshContext(t *testing.T) {
+	options := testOptions()
+	options.RefreshInterval = 3600
+	searcher := &blockingSearcher{
+		pageSearcher: pageSearcher{issues: testIssues(2)},
+		started:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+	client := newTestClient(t, options, searcher)
+
+	ctx, stop := context.WithCancel(context.Background())
+	refreshCtx, abort := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	client.StartRefreshLoop(ctx, refreshCtx, &wg)
+	<-searcher.started
+
+	stop()
+	abort()
+	wg.Wait()
+	if issues := client.GetCachedIssues(); issues != nil {
+		t.Errorf("cached %d issues of an aborted refresh", len(issues))
+	}
+
+	// A refresh still running when the shutdown timeout is reached fails the flush
+	client.refreshing <- struct{}{}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Flush(flushCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush() = %v, want the deadline error", err)
+	}
+}
+
+// overlapSearcher serves fixed pages by start index, as a dataset mutating between page requests does
+type overlapSearcher struct {
+	pages map[int][]jira.Issue
+	total int
+}
+
+func (s *overlapSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	page := s.pages[options.StartAt]
+	return page, &jira.Response{StartAt: options.StartAt, MaxResults: options.MaxResults, Total: s.total}, nil
+}
+
+func TestReloadUpdatesLastRefreshTime(t *testing.T) {
+	client := newTestClient(t, testOptions(), &pageSearcher{issues: testIssues(3)})
+
+	count, err := client.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload() = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Reload() = %d issues, want 3", count)
+	}
+	if client.GetLastRefreshTime().IsZero() {
+		t.Error("last refresh time not updated by the reload")
+	}
+}
+
+func TestReloadReportsFailedRefresh(t *testing.T) {
+	client := newTestClient(t, testOptions(), &pageSearcher{fail: map[int]error{0: errors.New("jira is down")}})
+	if _, err := client.Reload(context.Background()); err == nil {
+		t.Error("Reload() succeeded with a failing search")
+	}
+}
+
+func TestConcurrentReloadsShareRefresh(t *testing.T) {
+	searcher := &blockingSearcher{
+		pageSearcher: pageSearcher{issues: testIssues(2)},
+		started:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+	client := newTestClient(t, testOptions(), searcher)
+
+	counts := make(chan int, 3)
+	reload := func() {
+		count, err := client.Reload(context.Background())
+		if err != nil {
+			t.Errorf("Reload() = %v", err)
+		}
+		counts <- count
+	}
+	go reload()
+	<-searcher.started
+	go reload()
+	go reload()
+
+	// The reloads arriving during the refresh wait for it instead of starting another one
+	time.Sleep(20 * time.Millisecond)
+	close(searcher.release)
+	for range 3 {
+		if count := <-counts; count != 2 {
+			t.Errorf("Reload() = %d issues, want 2", count)
+		}
+	}
+	if len(searcher.calls) != 1 {
+		t.Errorf("searches = %d, want a single shared refresh", len(searcher.calls))
+	}
+}
+
+func TestReloadWaitsForScheduledRefresh(t *testing.T) {
+	options := testOptions()
+	options.RefreshInterval = 3600
+	searcher := &blockingSearcher{
+		pageSearcher: pageSearcher{issues: testIssues(2)},
+		started:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+	client := newTestClient(t, options, searcher)
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	var wg sync.WaitGroup
+	client.StartRefreshLoop(ctx, context.Background(), &wg)
+	<-searcher.started
+
+	reloaded := make(chan error, 1)
+	go func() {
+		_, err := client.Reload(context.Background())
+		reloaded <- err
+	}()
+
+	// Refreshes run one at a time, the reload searches only once the scheduled refresh completed
+	select {
+	case <-searcher.started:
+		t.Fatal("reload searched during the scheduled refresh")
+	case err := <-reloaded:
+		t.Fatalf("Reload() = %v during the scheduled refresh", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(searcher.release)
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload() = %v", err)
+	}
+	if len(searcher.calls) != 2 {
+		t.Errorf("searches = %d, want the scheduled refresh and the reload", len(searcher.calls))
+	}
+	stop()
+	wg.Wait()
+}
+
+func TestShutdownDrainsReload(t *testing.T) {
+	options := testOptions()
+	options.RefreshInterval = 3600
+	searcher := &blockingSearcher{
+		pageSearcher: pageSearcher{issues: testIssues(2)},
+		started:      make(chan struct{}, 1),
+		release:      make(chan struct{}),
+	}
+	client := newTestClient(t, options, searcher)
+
+	ctx, stop := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	client.StartRefreshLoop(ctx, context.Background(), &wg)
+	<-searcher.started
+	searcher.release <- struct{}{}
+	for client.GetLastRefreshTime().IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The reload caller goes away, the refresh it started is still drained on shutdown
+	reloadCtx, cancelReload := context.WithCancel(context.Background())
+	go client.Reload(reloadCtx)
+	<-searcher.started
+	cancelReload()
+	stop()
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("refresh loop stopped before the running reload completed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(searcher.release)
+	<-stopped
+	if _, err := client.Reload(context.Background()); !errors.Is(err, errRefreshStopped) {
+		t.Errorf("Reload() after shutdown = %v, want %v", err, errRefreshStopped)
+	}
+}
+
+func TestGetIssuesDedupesOverlappingPages(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issue := func(key string, created, updated int) jira.Issue {
+		i := testIssue(key, base.Add(time.Duration(created)*time.Minute), nil)
+		i.Fields.Updated = jira.Time(base.Add(time.Duration(updated) * time.Minute))
+		return i
+	}
+
+	// INCI-3 was updated while paging and shifted to the second page, INCI-2 and INCI-4 share the creation time
+	searcher := &overlapSearcher{total: 5, pages: map[int][]jira.Issue{
+		0: {issue("INCI-5", 5, 5), issue("INCI-3", 3, 3)},
+		2: {issue("INCI-3", 3, 30), issue("INCI-4", 2, 2)},
+		4: {issue("INCI-2", 2, 2)},
+	}}
+	options := testOptions()
+	options.MaxResults = 2
+	options.FetchConcurrency = 1
+	client := newTestClient(t, options, searcher)
+
+	issues, err := client.GetIssues(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, issue := range issues {
+		keys = append(keys, issue.Key)
+	}
+	if want := []string{"INCI-5", "INCI-3", "INCI-2", "INCI-4"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	if updated := time.Time(issues[1].Fields.Updated); !updated.Equal(base.Add(30 * time.Minute)) {
+		t.Errorf("INCI-3 updated = %s, want the newest copy", updated)
+	}
+}
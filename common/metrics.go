@@ -0,0 +1,101 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+// gaugeCache holds every gauge created so far, by metrics, name and labels
+var gaugeCache = struct {
+	sync.Mutex
+	gauges map[string]sre.Gauge
+}{gauges: make(map[string]sre.Gauge)}
+
+// durationBuckets are the upper bounds in seconds of the duration histograms
+var durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// gaugeValue is a single labeled value of a gauge family
+type gaugeValue struct {
+	labels map[string]string
+	value  float64
+}
+
+// labelsKey returns a stable identity of a label set
+func labelsKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// cachedGauge returns the gauge of a series, creating it on first use only. The Prometheus meter reads the
+// value of the first gauge created for a series, a gauge created again for it later is never read
+func cachedGauge(metrics *sre.Metrics, name, description string, labels map[string]string) sre.Gauge {
+	key := fmt.Sprintf("%p %s{%s}", metrics, name, labelsKey(labels))
+
+	gaugeCache.Lock()
+	defer gaugeCache.Unlock()
+	gauge, ok := gaugeCache.gauges[key]
+	if !ok {
+		gauge = metrics.Gauge(metricsGroup, name, description, labels)
+		gaugeCache.gauges[key] = gauge
+	}
+	return gauge
+}
+
+// setGauges publishes a gauge family computed on refresh, zeroing the label sets of the previous
+// refresh which are gone now so they don't keep reporting stale values
+func (j *JiraClient) setGauges(name, description string, values []gaugeValue) {
+	if j.metrics == nil {
+		return
+	}
+
+	current := make(map[string]map[string]string, len(values))
+	for _, v := range values {
+		labels := j.labels(v.labels)
+		current[labelsKey(labels)] = labels
+		cachedGauge(j.metrics, name, description, labels).Set(v.value)
+	}
+
+	j.mu.Lock()
+	previous := j.gauges[name]
+	j.gauges[name] = current
+	j.mu.Unlock()
+
+	for key, labels := range previous {
+		if _, ok := current[key]; !ok {
+			cachedGauge(j.metrics, name, description, labels).Set(0)
+		}
+	}
+}
+
+// observeDuration records a duration in a Prometheus histogram, which sre has no meter for: the _bucket
+// counters of every bound it fits in, the _count counter and the _sum gauge of the seconds observed so far
+func (j *JiraClient) observeDuration(name, description string, duration time.Duration) {
+	if j.metrics == nil {
+		return
+	}
+
+	seconds := duration.Seconds()
+	for _, bound := range durationBuckets {
+		if seconds <= bound {
+			j.metrics.Counter(metricsGroup, name+"_bucket", description, j.labels(map[string]string{"le": strconv.FormatFloat(bound, 'g', -1, 64)})).Inc()
+		}
+	}
+	j.metrics.Counter(metricsGroup, name+"_bucket", description, j.labels(map[string]string{"le": "+Inf"})).Inc()
+	j.metrics.Counter(metricsGroup, name+"_count", description, j.labels(nil)).Inc()
+
+	j.mu.Lock()
+	j.durations[name] += seconds
+	sum := j.durations[name]
+	j.mu.Unlock()
+	cachedGauge(j.metrics, name+"_sum", description, j.labels(nil)).Set(sum)
+}
@@ -0,0 +1,624 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	vm "github.com/VictoriaMetrics/metrics"
+	"github.com/andygrunwald/go-jira"
+	sre "github.com/devopsext/sre/common"
+	"github.com/devopsext/sre/provider"
+)
+
+// testMeter records the last value of every metric series, keyed by name and labels
+type testMeter struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+type testSeries struct {
+	meter *testMeter
+	key   string
+}
+
+func (s testSeries) Inc() sre.Counter { return s.Add(1) }
+
+func (s testSeries) Add(value int) sre.Counter {
+	s.update(func(v float64) float64 { return v + float64(value) })
+	return s
+}
+
+func (s testSeries) Set(value float64) sre.Gauge {
+	s.update(func(float64) float64 { return value })
+	return s
+}
+
+func (s testSeries) update(fn func(float64) float64) {
+	s.meter.mu.Lock()
+	defer s.meter.mu.Unlock()
+	s.meter.values[s.key] = fn(s.meter.values[s.key])
+}
+
+func (m *testMeter) series(name string, labels sre.Labels) testSeries {
+	return testSeries{meter: m, key: seriesKey(name, labels)}
+}
+
+func (m *testMeter) Counter(group, name, description string, labels sre.Labels, prefixes ...string) sre.Counter {
+	return m.series(name, labels)
+}
+
+func (m *testMeter) Gauge(group, name, description string, labels sre.Labels, prefixes ...string) sre.Gauge {
+	return m.series(name, labels)
+}
+
+func (m *testMeter) Group(name string) sre.Group { return nil }
+
+func (m *testMeter) Stop() {}
+
+// value returns the recorded value of a series
+func (m *testMeter) value(name string, labels map[string]string) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[seriesKey(name, labels)]
+	return v, ok
+}
+
+func seriesKey(name string, labels map[string]string) string {
+	return name + "{" + labelsKey(labels) + "}"
+}
+
+// newMeteredClient creates a test client publishing its metrics to a test meter
+func newMeteredClient(t *testing.T, options JiraOptions, searcher IssueSearcher) (*JiraClient, *testMeter) {
+	t.Helper()
+	meter := &testMeter{values: make(map[string]float64)}
+	metrics := sre.NewMetrics()
+	metrics.Register(meter)
+
+	client, err := NewJiraClient(options, NewObservability(nil, nil, nil), metrics)
+	if err != nil {
+		t.Fatalf("NewJiraClient: %v", err)
+	}
+	if searcher != nil {
+		client.SetSearcher(searcher)
+	}
+	return client, meter
+}
+
+func TestRefreshEmitsMetrics(t *testing.T) {
+	client, meter := newMeteredClient(t, testOptions(), &pageSearcher{issues: testIssues(3)})
+	client.RefreshData(context.Background())
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   float64
+	}{
+		{"refresh_total", map[string]string{"result": "success"}, 1},
+		{"jira_issues_fetched_total", nil, 3},
+		{"jira_query_duration_seconds_count", nil, 1},
+		{"jira_query_duration_seconds_bucket", map[string]string{"le": "+Inf"}, 1},
+		{"refresh_issue_count", nil, 3},
+	}
+	for _, tt := range tests {
+		if got, ok := meter.value(tt.name, tt.labels); !ok || got != tt.want {
+			t.Errorf("%s%v = %v (recorded %v), want %v", tt.name, tt.labels, got, ok, tt.want)
+		}
+	}
+}
+
+func TestGaugesUpdateInPrometheus(t *testing.T) {
+	metrics := sre.NewMetrics()
+	metrics.Register(provider.NewPrometheusMeter(provider.PrometheusOptions{Prefix: "aimgauges"}, nil, nil))
+	options := testOptions()
+	options.Tenant = "ops"
+	client, err := NewJiraClient(options, NewObservability(nil, nil, nil), metrics)
+	if err != nil {
+		t.Fatalf("NewJiraClient: %v", err)
+	}
+
+	client.SetSearcher(&pageSearcher{issues: testIssues(3)})
+	client.RefreshData(context.Background())
+	client.setGauges("test_open", "test", []gaugeValue{{labels: map[string]string{"service": "api"}, value: 2}})
+	client.SetSearcher(&pageSearcher{issues: testIssues(5)})
+	client.RefreshData(context.Background())
+	client.setGauges("test_open", "test", []gaugeValue{{labels: map[string]string{"service": "web"}, value: 1}})
+
+	var scrape bytes.Buffer
+	vm.WritePrometheus(&scrape, false)
+	for _, line := range []string{
+		`aimgauges_refresh_issue_count{tenant="ops"} 5`,
+		`aimgauges_test_open{service="api",tenant="ops"} 0`,
+		`aimgauges_test_open{service="web",tenant="ops"} 1`,
+	} {
+		if !strings.Contains(scrape.String(), line+"\n") {
+			t.Errorf("scrape lacks %s:\n%s", line, scrape.String())
+		}
+	}
+}
+
+func TestSetGaugesZeroesStaleSeries(t *testing.T) {
+	client, meter := newMeteredClient(t, testOptions(), nil)
+
+	client.setGauges("open_issues", "test", []gaugeValue{
+		{labels: map[string]string{"service": "api"}, value: 2},
+		{labels: map[string]string{"service": "web"}, value: 1},
+	})
+	client.setGauges("open_issues", "test", []gaugeValue{
+		{labels: map[string]string{"service": "api"}, value: 3},
+	})
+
+	if got, _ := meter.value("open_issues", map[string]string{"service": "api"}); got != 3 {
+		t.Errorf("api = %v, want 3", got)
+	}
+	if got, ok := meter.value("open_issues", map[string]string{"service": "web"}); !ok || got != 0 {
+		t.Errorf("stale web = %v (recorded %v), want 0", got, ok)
+	}
+}
+
+func TestIssuesByStatusGauge(t *testing.T) {
+	issues := testIssues(3)
+	issues[0].Fields.Status = &jira.Status{Name: "In Progress"}
+	issues[1].Fields.Status = nil
+	client, meter := newMeteredClient(t, testOptions(), &pageSearcher{issues: issues})
+	client.RefreshData(context.Background())
+
+	if got := client.GetCachedIssues()[0].Status; got != "In Progress" {
+		t.Errorf("Status = %q, want In Progress", got)
+	}
+	tests := []struct {
+		status string
+		want   float64
+	}{
+		{"In Progress", 1},
+		{"Open", 1},
+		{"none", 1},
+	}
+	for _, tt := range tests {
+		if got, _ := meter.value("jira_issues_by_status", map[string]string{"status": tt.status}); got != tt.want {
+			t.Errorf("jira_issues_by_status{status=%q} = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestIncidentCommentsGauge(t *testing.T) {
+	issues := []jira.Issue{commentedIssue("INCI-1", 3), commentedIssue("INCI-2", 2), commentedIssue("INCI-3", 0)}
+	issues[2].Fields.Comments = nil
+	options := testOptions()
+	options.IncludeCommentCounts = true
+	client, meter := newMeteredClient(t, options, &pageSearcher{issues: issues})
+	client.RefreshData(context.Background())
+
+	if got, _ := meter.value("incident_comments_total", map[string]string{"service": "none"}); got != 5 {
+		t.Errorf("incident_comments_total = %v, want 5", got)
+	}
+}
+
+func TestIncidentMissingFieldGauge(t *testing.T) {
+	detected := "2024-01-01T10:00:00.000+0000"
+	started := "2024-01-01T10:05:00.000+0000"
+	issues := []jira.Issue{
+		testIssue("INCI-1", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), map[string]interface{}{"customfield_1": detected, "customfield_2": started}),
+		testIssue("INCI-2", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), map[string]interface{}{"customfield_1": detected}),
+		testIssue("INCI-3", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), map[string]interface{}{"customfield_2": started}),
+		testIssue("INCI-4", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), nil),
+	}
+	options := testOptions()
+	options.FieldMapping = map[string]string{"detected": "customfield_1", "started": "customfield_2"}
+	client, meter := newMeteredClient(t, options, &pageSearcher{issues: issues})
+	client.RefreshData(context.Background())
+
+	tests := []struct {
+		field string
+		want  float64
+	}{
+		{"detected", 2},
+		{"started", 2},
+	}
+	for _, tt := range tests {
+		if got, ok := meter.value("incident_missing_field_total", map[string]string{"field": tt.field}); !ok || got != tt.want {
+			t.Errorf("incident_missing_field_total{field=%q} = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestIncidentRecurrenceGauge(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	issues := []jira.Issue{
+		testIssue("INCI-1", created, map[string]interface{}{"customfield_33803": "api", "customfield_37238": "deploy"}),
+		testIssue("INCI-2", created, map[string]interface{}{"customfield_33803": "api", "customfield_37238": "deploy"}),
+		testIssue("INCI-3", created, map[string]interface{}{"customfield_33803": "api"}),
+	}
+	client, meter := newMeteredClient(t, testOptions(), &pageSearcher{issues: issues})
+	client.RefreshData(context.Background())
+
+	tests := []struct {
+		rootCause string
+		want      float64
+	}{
+		{"deploy", 2},
+		{"unknown", 1},
+	}
+	for _, tt := range tests {
+		labels := map[string]string{"service": "api", "root_cause": tt.rootCause}
+		if got, ok := meter.value("incident_recurrence", labels); !ok || got != tt.want {
+			t.Errorf("incident_recurrence%v = %v, want %v", labels, got, tt.want)
+		}
+	}
+}
+
+func TestOpenIncidentsByAssigneeGauge(t *testing.T) {
+	var issues []jira.Issue
+	for n, assignee := range []string{"alice", "alice", "alice", "bob", "bob", "carol", "", ""} {
+		issue := testIssue(fmt.Sprintf("INCI-%d", n+1), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil)
+		if assignee != "" {
+			issue.Fields.Assignee = &jira.User{Name: assignee}
+		}
+		issues = append(issues, issue)
+	}
+	options := testOptions()
+	options.WorkloadThreshold = 1
+	client, meter := newMeteredClient(t, options, &pageSearcher{issues: issues})
+	client.RefreshData(context.Background())
+
+	for assignee, want := range map[string]float64{"alice": 3, "bob": 2, "unassigned": 2, "other": 1} {
+		if got, _ := meter.value("open_incidents_by_assignee", map[string]string{"assignee": assignee}); got != want {
+			t.Errorf("open_incidents_by_assignee{assignee=%s} = %v, want %v", assignee, got, want)
+		}
+	}
+	if _, ok := meter.value("open_incidents_by_assignee", map[string]string{"assignee": "carol"}); ok {
+		t.Error("carol below the threshold got an own series")
+	}
+}
+
+func TestIncidentAttachmentsGauge(t *testing.T) {
+	options := testOptions()
+	options.IncludeAttachments = true
+	client, meter := newMeteredClient(t, options, &pageSearcher{issues: []jira.Issue{attachedIssue("INCI-1"), attachedIssue("INCI-2")}})
+	client.RefreshData(context.Background())
+
+	if got, _ := meter.value("incident_attachments_total", map[string]string{"service": "none"}); got != 4 {
+		t.Errorf("incident_attachments_total = %v, want 4", got)
+	}
+}
+
+func TestIssueCachePurgesDroppedIssues(t *testing.T) {
+	searcher := &pageSearcher{issues: testIssues(3)}
+	client, meter := newMeteredClient(t, testOptions(), searcher)
+
+	tests := []struct {
+		name   string
+		issues []jira.Issue
+		want   int
+	}{
+		{"initial load", testIssues(3), 3},
+		{"dropped issue purged", testIssues(2), 2},
+		{"empty result", nil, 0},
+	}
+	for _, tt := range tests {
+		searcher.issues = tt.issues
+		client.RefreshData(context.Background())
+
+		client.mu.RLock()
+		size := len(client.issueCache)
+		client.mu.RUnlock()
+		if size != tt.want {
+			t.Errorf("%s: cache holds %d raw issues, want %d", tt.name, size, tt.want)
+		}
+		if got, _ := meter.value("jira_cache_size", nil); got != float64(tt.want) {
+			t.Errorf("%s: jira_cache_size = %v, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshResultCounters(t *testing.T) {
+	searcher := &pageSearcher{issues: testIssues(2), fail: map[int]error{0: errors.New("jira is down")}}
+	client, meter := newMeteredClient(t, testOptions(), searcher)
+
+	client.RefreshData(context.Background())
+	if _, ok := meter.value("last_refresh_timestamp_seconds", nil); ok {
+		t.Error("last successful refresh recorded for a failed refresh")
+	}
+
+	searcher.fail = nil
+	client.RefreshData(context.Background())
+	client.RefreshData(context.Background())
+
+	tests := []struct {
+		result string
+		want   float64
+	}{
+		{"error", 1},
+		{"success", 2},
+	}
+	for _, tt := range tests {
+		if got, _ := meter.value("refresh_total", map[string]string{"result": tt.result}); got != tt.want {
+			t.Errorf("refresh_total{result=%q} = %v, want %v", tt.result, got, tt.want)
+		}
+	}
+	if got, ok := meter.value("last_refresh_timestamp_seconds", nil); !ok || got <= 0 {
+		t.Errorf("last_refresh_timestamp_seconds = %v, want the refresh time", got)
+	}
+	if _, ok := meter.value("last_refresh_complete_timestamp", nil); ok {
+		t.Error("last_refresh_complete_timestamp duplicates last_refresh_timestamp_seconds")
+	}
+}
+
+func TestApiErrorsCountedByStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		code   string
+	}{
+		{"server error", http.StatusInternalServerError, "500"},
+		{"client error", http.StatusBadRequest, "400"},
+		{"no response", 0, "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := &flakySearcher{statuses: []int{tt.status}}
+			client, meter := newMeteredClient(t, testOptions(), searcher)
+			client.RefreshData(context.Background())
+
+			if got, _ := meter.value("jira_api_errors_total", map[string]string{"code": tt.code}); got != 1 {
+				t.Errorf("jira_api_errors_total{code=%q} = %v, want 1", tt.code, got)
+			}
+		})
+	}
+}
+
+func TestIncidentCountGauges(t *testing.T) {
+	withPriority := severityIssue("INCI-3", "SEV1", "api")
+	withPriority.Fields.Priority = &jira.Priority{Name: "High"}
+	searcher := &pageSearcher{issues: []jira.Issue{
+		severityIssue("INCI-1", "SEV1", "api"),
+		severityIssue("INCI-2", "SEV1", "api"),
+		withPriority,
+		severityIssue("INCI-4", "SEV2", ""),
+	}}
+	client, meter := newMeteredClient(t, testOptions(), searcher)
+	client.RefreshData(context.Background())
+
+	series := func(service, severity, priority string) map[string]string {
+		return map[string]string{"service": service, "severity": severity, "priority": priority, "environment": "none"}
+	}
+	tests := []struct {
+		labels map[string]string
+		want   float64
+	}{
+		{series("api", "SEV1", "none"), 2},
+		{series("api", "SEV1", "High"), 1},
+		{series("none", "SEV2", "none"), 1},
+	}
+	for _, tt := range tests {
+		if got, _ := meter.value("incidents_total", tt.labels); got != tt.want {
+			t.Errorf("incidents_total%v = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+
+	// Categories without incidents anymore drop to zero
+	searcher.issues = searcher.issues[:2]
+	client.RefreshData(context.Background())
+	if got, _ := meter.value("incidents_total", series("none", "SEV2", "none")); got != 0 {
+		t.Errorf("stale incidents_total = %v, want 0", got)
+	}
+}
+
+func TestIncidentsByEnvironment(t *testing.T) {
+	options := testOptions()
+	options.EnvironmentSynonyms = map[string]string{"production": "prod", "prd": "prod", "stg": "stage"}
+	client, meter := newMeteredClient(t, options, &pageSearcher{issues: environmentIssues()})
+	client.RefreshData(context.Background())
+
+	for environment, want := range map[string]float64{"prod": 2, "stage": 1, "none": 1} {
+		labels := map[string]string{"service": "none", "severity": "none", "priority": "none", "environment": environment}
+		if got, _ := meter.value("incidents_total", labels); got != want {
+			t.Errorf("incidents_total{environment=%s} = %v, want %v", environment, got, want)
+		}
+	}
+}
+
+func TestSeverityAliases(t *testing.T) {
+	tests := []struct {
+		name        string
+		aliases     map[string]string
+		order       []string
+		raw         []string
+		want        []string
+		wantUnknown float64
+	}{
+		{
+			name:        "aliases and canonical values",
+			aliases:     map[string]string{"Sev 1": "SEV1", "SEV-1": "SEV1", "S1": "SEV1", "S2": "SEV2"},
+			order:       []string{"SEV1", "SEV2", "SEV3"},
+			raw:         []string{"Sev 1", "sev-1", " S1 ", "s2", "SEV3", "P1"},
+			want:        []string{"SEV1", "SEV1", "SEV1", "SEV2", "SEV3", "P1"},
+			wantUnknown: 1,
+		},
+		{
+			name: "identity without aliases nor order",
+			raw:  []string{"Sev 1", "P1"},
+			want: []string{"Sev 1", "P1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.SeverityAliases = tt.aliases
+			options.SeverityOrder = tt.order
+			client, meter := newMeteredClient(t, options, nil)
+
+			for n, raw := range tt.raw {
+				issue := convertOne(t, client, severityIssue("INCI-1", raw, ""))
+				if issue.Severity != tt.want[n] {
+					t.Errorf("severity %q = %q, want %q", raw, issue.Severity, tt.want[n])
+				}
+			}
+			if got, _ := meter.value("jira_unknown_severity_total", nil); got != tt.wantUnknown {
+				t.Errorf("jira_unknown_severity_total = %v, want %v", got, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+// clockSearcher advances the stubbed clock of the client by the delay on every search
+type clockSearcher struct {
+	pageSearcher
+	now   *time.Time
+	delay time.Duration
+}
+
+func (s *clockSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	*s.now = s.now.Add(s.delay)
+	return s.pageSearcher.Search(ctx, jql, options)
+}
+
+func TestRefreshDurationGauges(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	searcher := &clockSearcher{pageSearcher: pageSearcher{issues: testIssues(3)}, now: &now, delay: 2 * time.Second}
+	client, meter := newMeteredClient(t, testOptions(), searcher)
+	client.now = func() time.Time { return now }
+
+	client.RefreshData(context.Background())
+	if got, _ := meter.value("refresh_duration_seconds", nil); got != 2 {
+		t.Errorf("refresh_duration_seconds = %v, want 2", got)
+	}
+	if got, _ := meter.value("refresh_issue_count", nil); got != 3 {
+		t.Errorf("refresh_issue_count = %v, want 3", got)
+	}
+	if got := client.GetLastRefreshTime(); !got.Equal(now) {
+		t.Errorf("last refresh = %s, want the stubbed clock %s", got, now)
+	}
+
+	// A failed refresh keeps the values of the last successful one
+	searcher.delay = 5 * time.Second
+	searcher.fail = map[int]error{0: errors.New("jira unavailable")}
+	client.RefreshData(context.Background())
+	if got, _ := meter.value("refresh_duration_seconds", nil); got != 2 {
+		t.Errorf("refresh_duration_seconds = %v after a failure, want 2", got)
+	}
+	if got, _ := meter.value("refresh_issue_count", nil); got != 3 {
+		t.Errorf("refresh_issue_count = %v after a failure, want 3", got)
+	}
+
+	// The count follows the issues of the latest successful refresh
+	searcher.fail = nil
+	searcher.issues = testIssues(1)
+	client.RefreshData(context.Background())
+	if got, _ := meter.value("refresh_issue_count", nil); got != 1 {
+		t.Errorf("refresh_issue_count = %v, want 1", got)
+	}
+	if got, _ := meter.value("refresh_duration_seconds", nil); got != 5 {
+		t.Errorf("refresh_duration_seconds = %v, want 5", got)
+	}
+}
+
+func TestConvertTimeFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		formats  []string
+		value    string
+		want     time.Time
+		failures float64
+	}{
+		{name: "jira format", value: "2024-03-05T10:30:00.000+0000", want: time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{name: "jira format without milliseconds", value: "2024-03-05T10:30:00+0000", want: time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{name: "rfc3339", value: "2024-03-05T10:30:00Z", want: time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{name: "date", value: "2024-03-05", want: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "configured format", formats: []string{" ", "02.01.2006 15:04"}, value: "05.03.2024 10:30", want: time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC)},
+		{name: "configured formats replace the defaults", formats: []string{"02.01.2006 15:04"}, value: "2024-03-05", failures: 1},
+		{name: "no matching format", value: "March 5th", failures: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testOptions()
+			options.FieldMapping = map[string]string{"started": "customfield_1"}
+			options.TimeFormats = tt.formats
+			client, meter := newMeteredClient(t, options, nil)
+
+			issue := convertOne(t, client, testIssue("INCI-1", time.Now(), map[string]interface{}{"customfield_1": tt.value}))
+			if !issue.Started.Equal(tt.want) {
+				t.Errorf("Started = %v, want %v", issue.Started, tt.want)
+			}
+			failures, _ := meter.value("jira_timeparse_failures_total", map[string]string{"field": "started"})
+			if failures != tt.failures {
+				t.Errorf("jira_timeparse_failures_total = %v, want %v", failures, tt.failures)
+			}
+		})
+	}
+}
+
+func TestOpenIncidentAgeBuckets(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	ages := map[string]time.Duration{
+		"INCI-1": 30 * time.Minute,
+		"INCI-2": time.Hour,
+		"INCI-3": time.Hour + time.Second,
+		"INCI-4": 4 * time.Hour,
+		"INCI-5": 10 * time.Hour,
+		"INCI-6": 24*time.Hour + time.Minute,
+		"INCI-7": 7*24*time.Hour + time.Second,
+		"INCI-8": 30 * 24 * time.Hour,
+	}
+	var issues []jira.Issue
+	for key, age := range ages {
+		issues = append(issues, testIssue(key, now.Add(-age), nil))
+	}
+	// Resolved incidents are not open, whatever their age
+	resolved := testIssue("INCI-9", now.Add(-2*time.Hour), nil)
+	resolved.Fields.Resolutiondate = jira.Time(now.Add(-time.Hour))
+	issues = append(issues, resolved)
+
+	client, meter := newMeteredClient(t, testOptions(), &pageSearcher{issues: issues})
+	client.now = func() time.Time { return now }
+	client.RefreshData(context.Background())
+
+	want := map[string]float64{"1h": 2, "4h": 2, "24h": 1, "7d": 1, "older": 2}
+	for bucket, count := range want {
+		if got, ok := meter.value("open_incident_age", map[string]string{"bucket": bucket}); !ok || got != count {
+			t.Errorf("open_incident_age{bucket=%q} = %v (recorded %v), want %v", bucket, got, ok, count)
+		}
+	}
+
+	// Emptied buckets are reported as zero
+	client.SetSearcher(&pageSearcher{issues: []jira.Issue{resolved}})
+	client.RefreshData(context.Background())
+	for bucket := range want {
+		if got, ok := meter.value("open_incident_age", map[string]string{"bucket": bucket}); !ok || got != 0 {
+			t.Errorf("open_incident_age{bucket=%q} = %v (recorded %v), want 0", bucket, got, ok)
+		}
+	}
+}
+
+func TestPageDurationHistogram(t *testing.T) {
+	delay := 60 * time.Millisecond
+	searcher := &slowSearcher{pageSearcher: pageSearcher{issues: testIssues(20), pageSize: 5}, delay: delay}
+	client, meter := newMeteredClient(t, testOptions(), searcher)
+	client.RefreshData(context.Background())
+
+	pages := float64(len(searcher.calls))
+	if pages < 4 {
+		t.Fatalf("%v searches, want at least 4 pages", pages)
+	}
+	if got, _ := meter.value("jira_page_duration_seconds_count", nil); got != pages {
+		t.Errorf("jira_page_duration_seconds_count = %v, want one per search %v", got, pages)
+	}
+	// Every page waited longer than the lowest bucket
+	for bound, want := range map[string]float64{"0.05": 0, "60": pages, "+Inf": pages} {
+		if got, _ := meter.value("jira_page_duration_seconds_bucket", map[string]string{"le": bound}); got != want {
+			t.Errorf("jira_page_duration_seconds_bucket{le=%q} = %v, want %v", bound, got, want)
+		}
+	}
+	if got, _ := meter.value("jira_page_duration_seconds_sum", nil); got < pages*delay.Seconds() {
+		t.Errorf("jira_page_duration_seconds_sum = %v, want at least %v", got, pages*delay.Seconds())
+	}
+	if got, _ := meter.value("jira_query_duration_seconds_count", nil); got != 1 {
+		t.Errorf("jira_query_duration_seconds observed %v times, want once per query", got)
+	}
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NotifyOptions holds settings for notifying about new incidents
+type NotifyOptions struct {
+	WebhookURL string
+	Timeout    int
+	// MinSeverity is the least severe severity notified, empty notifies every alert eligible incident
+	MinSeverity string
+}
+
+// maxPendingNotifications bounds the incidents kept for a retry while the webhook is failing
+const maxPendingNotifications = 100
+
+// Notifier posts new incidents to a Slack compatible webhook
+type Notifier struct {
+	options NotifyOptions
+	obs     *Observability
+	client  *http.Client
+
+	mu sync.Mutex
+	// pending holds the incidents of failed posts, sent again with the next post or on Flush
+	pending []*JiraIssue
+}
+
+type webhookMessage struct {
+	Text string `json:"text"`
+}
+
+func NewNotifier(options NotifyOptions, obs *Observability) *Notifier {
+	timeout := time.Duration(options.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &Notifier{
+		options: options,
+		obs:     obs,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Post sends a single message listing the issues and those of previously failed posts, linked to the
+// Jira instance at jiraURL. Issues of a failed post are kept pending.
+func (n *Notifier) Post(ctx context.Context, issues []*JiraIssue, jiraURL string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	issues = append(n.pending, issues...)
+	n.pending = nil
+	if len(issues) == 0 {
+		return nil
+	}
+
+	if err := n.post(ctx, issues, jiraURL); err != nil {
+		if len(issues) > maxPendingNotifications {
+			issues = issues[len(issues)-maxPendingNotifications:]
+		}
+		n.pending = issues
+		return err
+	}
+	return nil
+}
+
+// Flush posts the incidents of failed posts, if any
+func (n *Notifier) Flush(ctx context.Context, jiraURL string) (int, error) {
+	n.mu.Lock()
+	pending := len(n.pending)
+	n.mu.Unlock()
+	if pending == 0 {
+		return 0, nil
+	}
+	return pending, n.Post(ctx, nil, jiraURL)
+}
+
+// Pending returns the count of incidents waiting for a retry
+func (n *Notifier) Pending() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.pending)
+}
+
+// post sends a single message listing the issues
+func (n *Notifier) post(ctx context.Context, issues []*JiraIssue, jiraURL string) error {
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%d new incident(s):", len(issues))
+	for _, issue := range issues {
+		link := fmt.Sprintf("<%s/browse/%s|%s>", strings.TrimRight(jiraURL, "/"), issue.Key, issue.Key)
+		fmt.Fprintf(&text, "\n• %s [%s] %s", link, labelValue(issue.Severity), issue.Summary)
+		if issue.Service != "" {
+			fmt.Fprintf(&text, " (%s)", issue.Service)
+		}
+	}
+
+	body, err := json.Marshal(webhookMessage{Text: text.String()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.options.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
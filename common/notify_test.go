@@ -0,0 +1,150 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// webhookStub records the posted messages, answering with the queued status codes then 200
+type webhookStub struct {
+	mu       sync.Mutex
+	statuses []int
+	messages []string
+}
+
+func (s *webhookStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var message webhookMessage
+	json.NewDecoder(r.Body).Decode(&message)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, message.Text)
+	if len(s.statuses) > 0 {
+		w.WriteHeader(s.statuses[0])
+		s.statuses = s.statuses[1:]
+	}
+}
+
+func TestNotifierKeepsFailedPostsPending(t *testing.T) {
+	stub := &webhookStub{statuses: []int{http.StatusBadGateway}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	notifier := NewNotifier(NotifyOptions{WebhookURL: server.URL}, NewObservability(nil, nil, nil))
+	ctx := context.Background()
+
+	if err := notifier.Post(ctx, []*JiraIssue{{Key: "INCI-1"}}, "https://jira.example.com"); err == nil {
+		t.Fatal("expected the failed post to return an error")
+	}
+	if got := notifier.Pending(); got != 1 {
+		t.Fatalf("Pending() = %d, want 1", got)
+	}
+
+	if err := notifier.Post(ctx, []*JiraIssue{{Key: "INCI-2"}}, "https://jira.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if got := notifier.Pending(); got != 0 {
+		t.Errorf("Pending() = %d after a successful post, want 0", got)
+	}
+	last := stub.messages[len(stub.messages)-1]
+	if !strings.Contains(last, "2 new incident(s)") || !strings.Contains(last, "INCI-1") || !strings.Contains(last, "INCI-2") {
+		t.Errorf("retried message = %q, want both incidents", last)
+	}
+}
+
+func TestNotifierFlush(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []int
+		wantFlushed int
+		wantErr     bool
+		wantPending int
+	}{
+		{name: "nothing pending", wantFlushed: 0},
+		{name: "pending posted", statuses: []int{http.StatusBadGateway}, wantFlushed: 1},
+		{name: "webhook still failing", statuses: []int{http.StatusBadGateway, http.StatusBadGateway}, wantFlushed: 1, wantErr: true, wantPending: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &webhookStub{statuses: tt.statuses}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			notifier := NewNotifier(NotifyOptions{WebhookURL: server.URL}, NewObservability(nil, nil, nil))
+			if len(tt.statuses) > 0 {
+				notifier.Post(context.Background(), []*JiraIssue{{Key: "INCI-1"}}, "https://jira.example.com")
+			}
+
+			flushed, err := notifier.Flush(context.Background(), "https://jira.example.com")
+			if flushed != tt.wantFlushed || (err != nil) != tt.wantErr {
+				t.Errorf("Flush() = %d, %v, want %d, error %v", flushed, err, tt.wantFlushed, tt.wantErr)
+			}
+			if got := notifier.Pending(); got != tt.wantPending {
+				t.Errorf("Pending() = %d, want %d", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestRefreshNotifiesNewSevereIncidents(t *testing.T) {
+	tests := []struct {
+		name        string
+		minSeverity string
+		want        []string
+	}{
+		{name: "default SEV2", minSeverity: "SEV2", want: []string{"INCI-11", "INCI-12"}},
+		{name: "SEV1 only", minSeverity: "SEV1", want: []string{"INCI-11"}},
+		{name: "all", minSeverity: "", want: []string{"INCI-11", "INCI-12", "INCI-13"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &webhookStub{}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			searcher := &pageSearcher{issues: []jira.Issue{severityIssue("INCI-1", "SEV1", "payments")}}
+			client := newTestClient(t, testOptions(), searcher)
+			notifier := NewNotifier(NotifyOptions{WebhookURL: server.URL, MinSeverity: tt.minSeverity}, NewObservability(nil, nil, nil))
+			if err := client.SetNotifier(notifier); err != nil {
+				t.Fatal(err)
+			}
+
+			// The first refresh only records the known incidents
+			client.RefreshData(context.Background())
+			searcher.issues = append(searcher.issues,
+				severityIssue("INCI-11", "SEV1", "payments"),
+				severityIssue("INCI-12", "SEV2", "payments"),
+				severityIssue("INCI-13", "SEV3", "payments"),
+			)
+			client.RefreshData(context.Background())
+			client.RefreshData(context.Background())
+
+			if len(stub.messages) != 1 {
+				t.Fatalf("posted %d messages, want 1: %q", len(stub.messages), stub.messages)
+			}
+			message := stub.messages[0]
+			for _, key := range []string{"INCI-1", "INCI-11", "INCI-12", "INCI-13"} {
+				want := slices.Contains(tt.want, key)
+				if got := strings.Contains(message, "|"+key+">"); got != want {
+					t.Errorf("message %q contains %s = %v, want %v", message, key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetNotifierRejectsUnknownSeverity(t *testing.T) {
+	client := newTestClient(t, testOptions(), nil)
+	notifier := NewNotifier(NotifyOptions{WebhookURL: "http://127.0.0.1:0", MinSeverity: "P1"}, NewObservability(nil, nil, nil))
+	if err := client.SetNotifier(notifier); err == nil {
+		t.Error("expected an error for a severity missing in the severity order")
+	}
+}
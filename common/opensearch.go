@@ -0,0 +1,180 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+// OpenSearchOptions holds settings for indexing converted issues into OpenSearch or Elasticsearch
+type OpenSearchOptions struct {
+	URL      string
+	Index    string
+	Username string
+	Password string
+	Timeout  int
+	// BatchSize bounds the documents of a bulk request
+	BatchSize int
+	// Tenant labels the sink metrics when several Jira instances are polled
+	Tenant string
+}
+
+// defaultOpenSearchBatchSize is the number of documents of a bulk request
+const defaultOpenSearchBatchSize = 500
+
+// OpenSearchSink bulk indexes converted issues, keyed by issue key so that every refresh upserts them
+type OpenSearchSink struct {
+	options OpenSearchOptions
+	obs     *Observability
+	metrics *sre.Metrics
+	client  *http.Client
+}
+
+type bulkAction struct {
+	Index bulkTarget `json:"index"`
+}
+
+type bulkTarget struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// bulkResponse is the part of a bulk response telling the outcome of every document
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func NewOpenSearchSink(options OpenSearchOptions, obs *Observability, metrics *sre.Metrics) (*OpenSearchSink, error) {
+	if strings.TrimSpace(options.URL) == "" {
+		return nil, fmt.Errorf("opensearch URL is not configured")
+	}
+	if strings.TrimSpace(options.Index) == "" {
+		return nil, fmt.Errorf("opensearch index is not configured")
+	}
+
+	timeout := time.Duration(options.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultOpenSearchBatchSize
+	}
+
+	return &OpenSearchSink{
+		options: options,
+		obs:     obs,
+		metrics: metrics,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Write indexes the issues in batches, documents rejected by OpenSearch are counted and reported in the
+// returned error while the others stay indexed
+func (s *OpenSearchSink) Write(ctx context.Context, issues []*JiraIssue) error {
+	indexed, failed := 0, 0
+	var firstErr error
+	for start := 0; start < len(issues); start += s.options.BatchSize {
+		batch := issues[start:min(start+s.options.BatchSize, len(issues))]
+		rejected, err := s.bulk(ctx, batch)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		indexed += len(batch) - rejected
+		failed += rejected
+
+		if ctx.Err() != nil {
+			failed += len(issues) - start - len(batch)
+			break
+		}
+	}
+
+	if s.metrics != nil {
+		labels := tenantLabels(s.options.Tenant, nil)
+		s.metrics.Counter(metricsGroup, "opensearch_documents_indexed_total", "Count of issues indexed into OpenSearch", labels).Add(indexed)
+		s.metrics.Counter(metricsGroup, "opensearch_documents_failed_total", "Count of issues OpenSearch failed to index", labels).Add(failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("opensearch: %d of %d issues not indexed into %s: %w", failed, len(issues), s.options.Index, firstErr)
+	}
+	s.obs.Debug("Indexed %d issues into OpenSearch index %s", indexed, s.options.Index)
+	return nil
+}
+
+// bulk sends a single bulk request and returns the number of documents not indexed, all of them when the
+// request failed as a whole. The error describes the first rejection or the request failure.
+func (s *OpenSearchSink) bulk(ctx context.Context, issues []*JiraIssue) (int, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, issue := range issues {
+		if err := encoder.Encode(bulkAction{Index: bulkTarget{Index: s.options.Index, ID: issue.Key}}); err != nil {
+			return len(issues), err
+		}
+		if err := encoder.Encode(issue); err != nil {
+			return len(issues), err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.options.URL, "/")+"/_bulk", &body)
+	if err != nil {
+		return len(issues), fmt.Errorf("error creating bulk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.options.Username != "" {
+		req.SetBasicAuth(s.options.Username, s.options.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return len(issues), fmt.Errorf("error sending bulk request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return len(issues), fmt.Errorf("bulk request responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return len(issues), fmt.Errorf("error decoding bulk response: %w", err)
+	}
+	if !result.Errors {
+		return 0, nil
+	}
+
+	rejected := 0
+	var firstErr error
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Error == nil && outcome.Status < http.StatusBadRequest {
+				continue
+			}
+			rejected++
+			reason := fmt.Sprintf("status %d", outcome.Status)
+			if outcome.Error != nil {
+				reason = fmt.Sprintf("%s: %s", outcome.Error.Type, outcome.Error.Reason)
+			}
+			s.obs.Warn("OpenSearch rejected issue %s: %s", outcome.ID, reason)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("issue %s rejected: %s", outcome.ID, reason)
+			}
+		}
+	}
+	return rejected, firstErr
+}
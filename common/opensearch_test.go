@@ -0,0 +1,181 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+	sre "github.com/devopsext/sre/common"
+)
+
+// bulkStub is a bulk endpoint indexing documents by ID, rejecting the IDs in reject
+type bulkStub struct {
+	mu       sync.Mutex
+	status   int
+	reject   map[string]bool
+	requests int
+	docs     map[string]JiraIssue
+}
+
+func (s *bulkStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	if user, password, ok := r.BasicAuth(); !ok || user != "aim" || password != "secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.status != 0 {
+		http.Error(w, "cluster unavailable", s.status)
+		return
+	}
+
+	var items []string
+	failed := false
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action bulkAction
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			http.Error(w, "malformed bulk body", http.StatusBadRequest)
+			return
+		}
+		var doc JiraIssue
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			http.Error(w, "malformed document", http.StatusBadRequest)
+			return
+		}
+
+		id := action.Index.ID
+		if s.reject[id] {
+			failed = true
+			items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`, id))
+			continue
+		}
+		s.docs[action.Index.Index+"/"+id] = doc
+		items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":201}}`, id))
+	}
+	fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, failed, strings.Join(items, ","))
+}
+
+func newOpenSearchSink(t *testing.T, url string, batchSize int) (*OpenSearchSink, *testMeter) {
+	t.Helper()
+	meter := &testMeter{values: make(map[string]float64)}
+	metrics := sre.NewMetrics()
+	metrics.Register(meter)
+
+	sink, err := NewOpenSearchSink(OpenSearchOptions{URL: url, Index: "aim-issues", Username: "aim", Password: "secret", BatchSize: batchSize}, NewObservability(nil, nil, nil), metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sink, meter
+}
+
+func sinkIssues(keys ...string) []*JiraIssue {
+	issues := make([]*JiraIssue, 0, len(keys))
+	for _, key := range keys {
+		issues = append(issues, &JiraIssue{Key: key, Summary: "Incident " + key, Created: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	return issues
+}
+
+func TestOpenSearchSinkWrite(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		reject       map[string]bool
+		wantErr      bool
+		wantIndexed  float64
+		wantFailed   float64
+		wantRequests int
+	}{
+		{name: "all indexed", wantIndexed: 5, wantRequests: 3},
+		{name: "rejected documents", reject: map[string]bool{"INCI-2": true, "INCI-5": true}, wantErr: true, wantIndexed: 3, wantFailed: 2, wantRequests: 3},
+		{name: "cluster unavailable", status: http.StatusServiceUnavailable, wantErr: true, wantFailed: 5, wantRequests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &bulkStub{status: tt.status, reject: tt.reject, docs: make(map[string]JiraIssue)}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+			sink, meter := newOpenSearchSink(t, server.URL, 2)
+
+			err := sink.Write(context.Background(), sinkIssues("INCI-1", "INCI-2", "INCI-3", "INCI-4", "INCI-5"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Write() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if stub.requests != tt.wantRequests {
+				t.Errorf("%d bulk requests, want %d", stub.requests, tt.wantRequests)
+			}
+			if got, _ := meter.value("opensearch_documents_indexed_total", nil); got != tt.wantIndexed {
+				t.Errorf("opensearch_documents_indexed_total = %v, want %v", got, tt.wantIndexed)
+			}
+			if got, _ := meter.value("opensearch_documents_failed_total", nil); got != tt.wantFailed {
+				t.Errorf("opensearch_documents_failed_total = %v, want %v", got, tt.wantFailed)
+			}
+			if len(stub.docs) != int(tt.wantIndexed) {
+				t.Errorf("%d documents indexed, want %v", len(stub.docs), tt.wantIndexed)
+			}
+		})
+	}
+}
+
+func TestOpenSearchSinkUpsertsByKey(t *testing.T) {
+	stub := &bulkStub{docs: make(map[string]JiraIssue)}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	sink, _ := newOpenSearchSink(t, server.URL, 0)
+
+	issues := sinkIssues("INCI-1", "INCI-2")
+	if err := sink.Write(context.Background(), issues); err != nil {
+		t.Fatal(err)
+	}
+	issues[0].Status = "Resolved"
+	if err := sink.Write(context.Background(), issues); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stub.docs) != 2 {
+		t.Fatalf("%d documents, want 2", len(stub.docs))
+	}
+	if got := stub.docs["aim-issues/INCI-1"].Status; got != "Resolved" {
+		t.Errorf("INCI-1 status = %q, want Resolved", got)
+	}
+}
+
+func TestRefreshWritesToOpenSearch(t *testing.T) {
+	stub := &bulkStub{docs: make(map[string]JiraIssue)}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	sink, _ := newOpenSearchSink(t, server.URL, 0)
+
+	client := newTestClient(t, testOptions(), &pageSearcher{issues: []jira.Issue{
+		testIssue("INCI-1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil),
+	}})
+	client.AddSink(sink)
+	client.RefreshData(context.Background())
+
+	if got := stub.docs["aim-issues/INCI-1"].Summary; got != "Incident INCI-1" {
+		t.Errorf("indexed summary = %q, want Incident INCI-1", got)
+	}
+}
+
+func TestOpenSearchSinkRequiresURLAndIndex(t *testing.T) {
+	for _, options := range []OpenSearchOptions{{Index: "aim"}, {URL: "http://localhost:9200"}} {
+		if _, err := NewOpenSearchSink(options, NewObservability(nil, nil, nil), nil); err == nil {
+			t.Errorf("options %+v accepted", options)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	sre "github.com/devopsext/sre/common"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rateLimitRetries bounds the retries of a request answered with 429 Too Many Requests
+	rateLimitRetries = 3
+	// maxRetryAfter caps the wait requested by Jira through Retry-After
+	maxRetryAfter = time.Minute
+)
+
+// rateLimitTransport spaces out outbound Jira requests and waits as told by Jira when rate limited
+type rateLimitTransport struct {
+	limiter *rate.Limiter
+	obs     *Observability
+	metrics *sre.Metrics
+	labels  map[string]string
+	base    http.RoundTripper
+	// lowRemaining is the number of requests left in the Jira rate limit window below which a warning is logged
+	lowRemaining int
+	// now and sleep are replaced by tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// newRateLimitTransport limits requests to the given rate, 0 only honors Retry-After
+func newRateLimitTransport(requestsPerSecond float64, lowRemaining int, obs *Observability, metrics *sre.Metrics, labels map[string]string, base http.RoundTripper) *rateLimitTransport {
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	if requestsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), int(math.Max(1, math.Ceil(requestsPerSecond))))
+	}
+
+	return &rateLimitTransport{
+		limiter:      limiter,
+		obs:          obs,
+		metrics:      metrics,
+		labels:       labels,
+		base:         base,
+		lowRemaining: lowRemaining,
+		now:          time.Now,
+		sleep:        sleepContext,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err == nil {
+			t.observeRateLimit(req, resp)
+		}
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == rateLimitRetries {
+			return resp, err
+		}
+
+		// Requests with a body can only be resent when it can be recreated
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		if t.metrics != nil {
+			t.metrics.Counter(metricsGroup, "jira_rate_limited_total", "Count of Jira API calls answered with 429 Too Many Requests", t.labels).Inc()
+		}
+
+		// A wait ending after the caller gives up would only delay the error
+		now := t.now()
+		delay := retryAfter(resp.Header.Get("Retry-After"), now)
+		if t.metrics != nil {
+			cachedGauge(t.metrics, "jira_rate_limit_retry_after_seconds", "Wait requested by the last Jira rate limited response", t.labels).Set(delay.Seconds())
+		}
+		if deadline, ok := req.Context().Deadline(); ok && now.Add(delay).After(deadline) {
+			t.obs.WithContext(req.Context()).Warn("Jira rate limit hit, not retrying %s as the %s wait exceeds the deadline", req.URL.Path, delay)
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		t.obs.WithContext(req.Context()).Warn("Jira rate limit hit, retrying %s in %s", req.URL.Path, delay)
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// observeRateLimit publishes the requests left in the Jira rate limit window and warns when they run low
+func (t *rateLimitTransport) observeRateLimit(req *http.Request, resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	if t.metrics != nil {
+		cachedGauge(t.metrics, "jira_rate_limit_remaining", "Requests left in the Jira rate limit window", t.labels).Set(float64(remaining))
+	}
+	if remaining < t.lowRemaining {
+		t.obs.WithContext(req.Context()).Warn("Jira rate limit is running low, %d requests left (limit %s)", remaining, resp.Header.Get("X-RateLimit-Limit"))
+	}
+}
+
+// sleepContext waits for the duration, returning early with the context error
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date, one second when missing
+func retryAfter(header string, now time.Time) time.Duration {
+	delay := time.Second
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		delay = at.Sub(now)
+	}
+	return min(max(delay, 0), maxRetryAfter)
+}
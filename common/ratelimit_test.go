@@ -0,0 +1,238 @@
+package common
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sre "github.com/devopsext/sre/common"
+)
+
+// roundTripFunc answers requests without a network
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// queuedResponses answers with 429 and the queued Retry-After headers, then with 200
+func queuedResponses(retryAfters ...string) (http.RoundTripper, *atomic.Int32) {
+	var calls atomic.Int32
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := int(calls.Add(1)) - 1
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}
+		if n < len(retryAfters) {
+			resp.StatusCode = http.StatusTooManyRequests
+			resp.Header.Set("Retry-After", retryAfters[n])
+		}
+		return resp, nil
+	}), &calls
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "", want: time.Second},
+		{header: "5", want: 5 * time.Second},
+		{header: "-5", want: 0},
+		{header: "3600", want: maxRetryAfter},
+		{header: now.Add(30 * time.Second).Format(http.TimeFormat), want: 30 * time.Second},
+		{header: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{header: "soon", want: time.Second},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitTransportRetryAfter(t *testing.T) {
+	// The deadline of the caller is checked against the real clock, Retry-After dates have a second precision
+	start := time.Now().Truncate(time.Second)
+	tests := []struct {
+		name        string
+		retryAfters []string
+		timeout     time.Duration
+		wantStatus  int
+		wantCalls   int32
+		wantLimited int
+		wantSleeps  []time.Duration
+	}{
+		{
+			name:        "waits longer than a request may take",
+			retryAfters: []string{"45", start.Add(75 * time.Second).Format(http.TimeFormat)},
+			wantStatus:  http.StatusOK,
+			wantCalls:   3,
+			wantLimited: 2,
+			wantSleeps:  []time.Duration{45 * time.Second, 30 * time.Second},
+		},
+		{
+			name:        "gives up after the retries",
+			retryAfters: []string{"1", "1", "1", "1", "1"},
+			wantStatus:  http.StatusTooManyRequests,
+			wantCalls:   rateLimitRetries + 1,
+			wantLimited: rateLimitRetries,
+			wantSleeps:  []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:        "wait beyond the caller deadline",
+			retryAfters: []string{"45"},
+			timeout:     10 * time.Second,
+			wantStatus:  http.StatusTooManyRequests,
+			wantCalls:   1,
+			wantLimited: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, calls := queuedResponses(tt.retryAfters...)
+			meter := &testMeter{values: make(map[string]float64)}
+			metrics := sre.NewMetrics()
+			metrics.Register(meter)
+			transport := newRateLimitTransport(0, 0, NewObservability(nil, nil, nil), metrics, nil,
+				&timeoutTransport{timeout: time.Millisecond, base: base})
+
+			// A stubbed clock which only advances by the waits
+			now := start
+			var sleeps []time.Duration
+			transport.now = func() time.Time { return now }
+			transport.sleep = func(ctx context.Context, d time.Duration) error {
+				sleeps = append(sleeps, d)
+				now = now.Add(d)
+				return nil
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, now.Add(tt.timeout))
+				defer cancel()
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://jira.example.com/rest/api/2/search", nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus || calls.Load() != tt.wantCalls {
+				t.Errorf("status %d after %d calls, want %d after %d", resp.StatusCode, calls.Load(), tt.wantStatus, tt.wantCalls)
+			}
+			if len(sleeps) != len(tt.wantSleeps) {
+				t.Fatalf("slept %v, want %v", sleeps, tt.wantSleeps)
+			}
+			for n := range sleeps {
+				if sleeps[n] != tt.wantSleeps[n] {
+					t.Errorf("slept %v, want %v", sleeps, tt.wantSleeps)
+				}
+			}
+			if got, _ := meter.value("jira_rate_limited_total", nil); got != float64(tt.wantLimited) {
+				t.Errorf("jira_rate_limited_total = %v, want %d", got, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestRateLimitTransportSpacesRequests(t *testing.T) {
+	base, calls := queuedResponses()
+	transport := newRateLimitTransport(100, 0, NewObservability(nil, nil, nil), nil, nil, base)
+
+	// The burst of 100 passes right away, the next 20 requests are spaced by 10ms
+	started := time.Now()
+	for n := 0; n < 120; n++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://jira.example.com/rest/api/2/myself", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(started); elapsed < 150*time.Millisecond {
+		t.Errorf("%d requests took %s, want them spaced out", calls.Load(), elapsed)
+	}
+}
+
+func TestRetryAfterWaitNotCutByHTTPTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id":"5","key":"INCI-5","fields":{"summary":"Rate limited","created":"2024-03-05T10:30:00.000+0000","status":{"name":"Open"}}}`))
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	options.HTTPTimeout = 1
+	client := newTestClient(t, options, nil)
+
+	issue, err := client.GetIssueByKey(context.Background(), "INCI-5")
+	if err != nil {
+		t.Fatalf("GetIssueByKey: %v", err)
+	}
+	if issue.Summary != "Rate limited" || calls.Load() != 2 {
+		t.Errorf("GetIssueByKey() = %q after %d calls", issue.Summary, calls.Load())
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(20-10*n)))
+		if n == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"startAt":0,"maxResults":50,"total":1,"issues":[{"id":"1","key":"INCI-1","fields":{"created":"2024-03-05T10:30:00.000+0000"}}]}`))
+	}))
+	defer server.Close()
+
+	options := testOptions()
+	options.URL = server.URL
+	options.RateLimitWarnBelow = 5
+	client, meter := newMeteredClient(t, options, nil)
+
+	issues, err := client.GetIssues(context.Background())
+	if err != nil {
+		t.Fatalf("GetIssues: %v", err)
+	}
+	if len(issues) != 1 || calls.Load() != 2 {
+		t.Fatalf("GetIssues() = %d issues after %d calls, want 1 after the rate limited call", len(issues), calls.Load())
+	}
+	if remaining, ok := meter.value("jira_rate_limit_remaining", nil); !ok || remaining != 0 {
+		t.Errorf("jira_rate_limit_remaining = %v (recorded %v), want the 0 of the last response", remaining, ok)
+	}
+	if wait, ok := meter.value("jira_rate_limit_retry_after_seconds", nil); !ok || wait != 0 {
+		t.Errorf("jira_rate_limit_retry_after_seconds = %v (recorded %v), want 0", wait, ok)
+	}
+}
+
+func TestRateLimitHeadersMissing(t *testing.T) {
+	meter := &testMeter{values: make(map[string]float64)}
+	metrics := sre.NewMetrics()
+	metrics.Register(meter)
+	base, _ := queuedResponses()
+	transport := newRateLimitTransport(0, 5, NewObservability(nil, nil, nil), metrics, nil, base)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://jira.example.com/rest/api/2/search", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if _, ok := meter.value("jira_rate_limit_remaining", nil); ok {
+		t.Error("jira_rate_limit_remaining recorded without the header")
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+)
+
+// ErrUnknownTenant is returned for a tenant which is not registered
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// ClientRegistry holds the Jira clients of the tenants polled by one process, keyed by tenant name
+type ClientRegistry struct {
+	clients map[string]*JiraClient
+}
+
+func NewClientRegistry() *ClientRegistry {
+	return &ClientRegistry{clients: make(map[string]*JiraClient)}
+}
+
+// Register adds the client under its tenant name, which must be unique
+func (r *ClientRegistry) Register(client *JiraClient) error {
+	tenant := client.Tenant()
+	if _, ok := r.clients[tenant]; ok {
+		return fmt.Errorf("tenant %q is already registered", tenant)
+	}
+	r.clients[tenant] = client
+	return nil
+}
+
+// Get returns the client of the tenant. An empty tenant selects the only registered client,
+// it is ambiguous as soon as several tenants are registered.
+func (r *ClientRegistry) Get(tenant string) (*JiraClient, error) {
+	if tenant == "" && len(r.clients) == 1 {
+		for _, client := range r.clients {
+			return client, nil
+		}
+	}
+	if tenant == "" {
+		return nil, fmt.Errorf("tenant is required, one of: %v", r.Tenants())
+	}
+	client, ok := r.clients[tenant]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTenant, tenant)
+	}
+	return client, nil
+}
+
+// Tenants returns the registered tenant names sorted
+func (r *ClientRegistry) Tenants() []string {
+	tenants := make([]string, 0, len(r.clients))
+	for tenant := range maps.Keys(r.clients) {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// Clients returns the registered clients in tenant name order
+func (r *ClientRegistry) Clients() []*JiraClient {
+	clients := make([]*JiraClient, 0, len(r.clients))
+	for _, tenant := range r.Tenants() {
+		clients = append(clients, r.clients[tenant])
+	}
+	return clients
+}
+
+// tenantLabels adds the tenant label to metric labels, a single unnamed tenant keeps the labels unchanged
+func tenantLabels(tenant string, labels map[string]string) map[string]string {
+	if tenant == "" {
+		return labels
+	}
+	out := make(map[string]string, len(labels)+1)
+	maps.Copy(out, labels)
+	out["tenant"] = tenant
+	return out
+}
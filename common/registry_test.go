@@ -0,0 +1,131 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+func TestClientRegistryIsolatesTenants(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	searchers := map[string]*pageSearcher{
+		"alpha": {issues: []jira.Issue{testIssue("ALPHA-1", created, nil), testIssue("ALPHA-2", created, nil)}},
+		"beta":  {issues: []jira.Issue{testIssue("BETA-1", created, nil)}},
+	}
+
+	registry := NewClientRegistry()
+	for tenant, searcher := range searchers {
+		options := testOptions()
+		options.Tenant = tenant
+		if err := registry.Register(newTestClient(t, options, searcher)); err != nil {
+			t.Fatalf("Register(%s): %v", tenant, err)
+		}
+	}
+
+	for _, client := range registry.Clients() {
+		client.RefreshData(context.Background())
+	}
+
+	want := map[string][]string{
+		"alpha": {"ALPHA-1", "ALPHA-2"},
+		"beta":  {"BETA-1"},
+	}
+	for tenant, keys := range want {
+		client, err := registry.Get(tenant)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", tenant, err)
+		}
+		var got []string
+		for _, issue := range client.GetCachedIssues() {
+			got = append(got, issue.Key)
+		}
+		if !reflect.DeepEqual(got, keys) {
+			t.Errorf("tenant %s cached %v, want %v", tenant, got, keys)
+		}
+	}
+}
+
+func TestClientRegistryGet(t *testing.T) {
+	single := NewClientRegistry()
+	if err := single.Register(newTestClient(t, testOptions(), &pageSearcher{})); err != nil {
+		t.Fatal(err)
+	}
+
+	multi := NewClientRegistry()
+	for _, tenant := range []string{"beta", "alpha"} {
+		options := testOptions()
+		options.Tenant = tenant
+		if err := multi.Register(newTestClient(t, options, &pageSearcher{})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		registry *ClientRegistry
+		tenant   string
+		want     string
+		unknown  bool
+		wantErr  bool
+	}{
+		{name: "single default", registry: single, tenant: "", want: ""},
+		{name: "selected tenant", registry: multi, tenant: "beta", want: "beta"},
+		{name: "ambiguous default", registry: multi, tenant: "", wantErr: true},
+		{name: "unknown tenant", registry: multi, tenant: "gamma", wantErr: true, unknown: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.registry.Get(tt.tenant)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Get(%q) succeeded, want an error", tt.tenant)
+				}
+				if errors.Is(err, ErrUnknownTenant) != tt.unknown {
+					t.Errorf("Get(%q) error %v, unknown tenant %v", tt.tenant, err, tt.unknown)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get(%q): %v", tt.tenant, err)
+			}
+			if client.Tenant() != tt.want {
+				t.Errorf("Get(%q) returned tenant %q, want %q", tt.tenant, client.Tenant(), tt.want)
+			}
+		})
+	}
+
+	if got, want := multi.Tenants(), []string{"alpha", "beta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tenants() = %v, want %v", got, want)
+	}
+
+	duplicate := testOptions()
+	duplicate.Tenant = "alpha"
+	if err := multi.Register(newTestClient(t, duplicate, nil)); err == nil {
+		t.Error("registering a tenant twice succeeded")
+	}
+}
+
+func TestTenantLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		tenant string
+		labels map[string]string
+		want   map[string]string
+	}{
+		{name: "no tenant", tenant: "", labels: map[string]string{"code": "500"}, want: map[string]string{"code": "500"}},
+		{name: "no tenant nil labels", tenant: "", labels: nil, want: nil},
+		{name: "tenant added", tenant: "prod", labels: nil, want: map[string]string{"tenant": "prod"}},
+		{name: "tenant merged", tenant: "prod", labels: map[string]string{"code": "500"}, want: map[string]string{"code": "500", "tenant": "prod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tenantLabels(tt.tenant, tt.labels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tenantLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// IssueSearcher runs a single JQL search page, so Jira access can be recorded, replayed or faked
+type IssueSearcher interface {
+	Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error)
+}
+
+// jiraSearcher searches through the go-jira client
+type jiraSearcher struct {
+	client *jira.Client
+}
+
+func (s *jiraSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	return s.client.Issue.SearchWithContext(ctx, jql, options)
+}
+
+// searchFixture is a recorded search page, it keeps no credentials or request headers
+type searchFixture struct {
+	JQL        string       `json:"jql"`
+	StartAt    int          `json:"startAt"`
+	MaxResults int          `json:"maxResults"`
+	Total      int          `json:"total"`
+	Issues     []jira.Issue `json:"issues"`
+}
+
+// fixturePath names the fixture file after the query and page offset
+func fixturePath(dir, jql string, startAt int) string {
+	sum := sha256.Sum256([]byte(jql))
+	return filepath.Join(dir, fmt.Sprintf("%s-%d.json", hex.EncodeToString(sum[:6]), startAt))
+}
+
+// RecordingSearcher saves every search page returned by the wrapped searcher to disk
+type RecordingSearcher struct {
+	searcher IssueSearcher
+	dir      string
+}
+
+func NewRecordingSearcher(searcher IssueSearcher, dir string) (*RecordingSearcher, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating record directory: %w", err)
+	}
+	return &RecordingSearcher{searcher: searcher, dir: dir}, nil
+}
+
+func (s *RecordingSearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	issues, resp, err := s.searcher.Search(ctx, jql, options)
+	if err != nil {
+		return issues, resp, err
+	}
+
+	fixture := searchFixture{
+		JQL:        jql,
+		StartAt:    options.StartAt,
+		MaxResults: options.MaxResults,
+		Issues:     issues,
+	}
+	if resp != nil {
+		fixture.Total = resp.Total
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return issues, resp, fmt.Errorf("error encoding search fixture: %w", err)
+	}
+	if err := os.WriteFile(fixturePath(s.dir, jql, options.StartAt), data, 0o644); err != nil {
+		return issues, resp, fmt.Errorf("error writing search fixture: %w", err)
+	}
+	return issues, resp, nil
+}
+
+// ReplaySearcher serves search pages previously saved by RecordingSearcher instead of calling Jira
+type ReplaySearcher struct {
+	dir string
+}
+
+func NewReplaySearcher(dir string) *ReplaySearcher {
+	return &ReplaySearcher{dir: dir}
+}
+
+func (s *ReplaySearcher) Search(ctx context.Context, jql string, options *jira.SearchOptions) ([]jira.Issue, *jira.Response, error) {
+	path := fixturePath(s.dir, jql, options.StartAt)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Past the last recorded page
+		return nil, &jira.Response{StartAt: options.StartAt, MaxResults: options.MaxResults}, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading search fixture: %w", err)
+	}
+
+	var fixture searchFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, nil, fmt.Errorf("error parsing search fixture %s: %w", path, err)
+	}
+
+	return fixture.Issues, &jira.Response{
+		StartAt:    fixture.StartAt,
+		MaxResults: fixture.MaxResults,
+		Total:      fixture.Total,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// SampleOptions controls how a representative subset of issues is selected
+type SampleOptions struct {
+	Rate     float64
+	Max      int
+	Stratify string
+	Seed     int64
+}
+
+// Enabled reports whether any sampling is requested
+func (o SampleOptions) Enabled() bool {
+	return o.Rate > 0 || o.Max > 0
+}
+
+// Validate checks the sampling options for consistency
+func (o SampleOptions) Validate() error {
+	if o.Rate < 0 || o.Rate > 1 {
+		return fmt.Errorf("sample rate must be between 0 and 1, got %v", o.Rate)
+	}
+	if o.Max < 0 {
+		return fmt.Errorf("sample max must not be negative, got %d", o.Max)
+	}
+	switch o.Stratify {
+	case "", "severity", "service":
+		return nil
+	default:
+		return fmt.Errorf("unsupported sample stratification %q, use severity or service", o.Stratify)
+	}
+}
+
+// SampleIssues returns a random subset of issues sized by rate and/or max, keeping the original order.
+// When stratified, each severity or service keeps its proportion of the whole set.
+func SampleIssues(issues []*JiraIssue, options SampleOptions) []*JiraIssue {
+	size := len(issues)
+	if options.Rate > 0 {
+		size = int(math.Round(float64(len(issues)) * options.Rate))
+	}
+	if options.Max > 0 && options.Max < size {
+		size = options.Max
+	}
+	if size >= len(issues) {
+		return issues
+	}
+
+	rnd := rand.New(rand.NewSource(options.Seed))
+
+	// Group issue indexes by stratum, a single group when not stratified
+	groups := make(map[string][]int)
+	for n, issue := range issues {
+		stratum := ""
+		switch options.Stratify {
+		case "severity":
+			stratum = issue.Severity
+		case "service":
+			stratum = issue.Service
+		}
+		groups[stratum] = append(groups[stratum], n)
+	}
+
+	strata := make([]string, 0, len(groups))
+	for stratum := range groups {
+		strata = append(strata, stratum)
+	}
+	sort.Strings(strata)
+
+	// Proportional quotas, distributing the rounding leftovers by largest remainder
+	quotas := make(map[string]int, len(strata))
+	remainders := make(map[string]float64, len(strata))
+	assigned := 0
+	for _, stratum := range strata {
+		exact := float64(len(groups[stratum])) * float64(size) / float64(len(issues))
+		quotas[stratum] = int(exact)
+		remainders[stratum] = exact - float64(quotas[stratum])
+		assigned += quotas[stratum]
+	}
+	byRemainder := append([]string(nil), strata...)
+	sort.SliceStable(byRemainder, func(a, b int) bool {
+		return remainders[byRemainder[a]] > remainders[byRemainder[b]]
+	})
+	for n := 0; assigned < size; n++ {
+		quotas[byRemainder[n%len(byRemainder)]]++
+		assigned++
+	}
+
+	var selected []int
+	for _, stratum := range strata {
+		indexes := groups[stratum]
+		rnd.Shuffle(len(indexes), func(a, b int) {
+			indexes[a], indexes[b] = indexes[b], indexes[a]
+		})
+		selected = append(selected, indexes[:quotas[stratum]]...)
+	}
+	sort.Ints(selected)
+
+	sampled := make([]*JiraIssue, 0, len(selected))
+	for _, n := range selected {
+		sampled = append(sampled, issues[n])
+	}
+	return sampled
+}
@@ -0,0 +1,27 @@
+package common
+
+import "context"
+
+// Sink receives the converted issues of every successful refresh
+type Sink interface {
+	Write(ctx context.Context, issues []*JiraIssue) error
+}
+
+// memorySink is the in-memory cache served by the API, the first sink of every client
+type memorySink struct {
+	j *JiraClient
+}
+
+func (s memorySink) Write(ctx context.Context, issues []*JiraIssue) error {
+	s.j.storeIssues(issues)
+	return nil
+}
+
+// cacheFileSink persists the issues to the cache file seeding the cache on restart
+type cacheFileSink struct {
+	j *JiraClient
+}
+
+func (s cacheFileSink) Write(ctx context.Context, issues []*JiraIssue) error {
+	return s.j.saveCacheFile(issues)
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingSink records the keys of every write, failing with err
+type recordingSink struct {
+	writes [][]string
+	err    error
+}
+
+func (s *recordingSink) Write(ctx context.Context, issues []*JiraIssue) error {
+	keys := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		keys = append(keys, issue.Key)
+	}
+	s.writes = append(s.writes, keys)
+	return s.err
+}
+
+func TestRefreshFansOutToSinks(t *testing.T) {
+	options := testOptions()
+	options.CacheFilePath = filepath.Join(t.TempDir(), "issues.json")
+	searcher := &pageSearcher{issues: testIssues(3)}
+	client, meter := newMeteredClient(t, options, searcher)
+
+	failing := &recordingSink{err: errors.New("backend down")}
+	recording := &recordingSink{}
+	client.AddSink(failing)
+	client.AddSink(recording)
+	client.RefreshData(context.Background())
+
+	for name, sink := range map[string]*recordingSink{"failing": failing, "recording": recording} {
+		if len(sink.writes) != 1 || len(sink.writes[0]) != 3 || sink.writes[0][0] != "INCI-3" {
+			t.Errorf("%s sink writes = %v, want one write of the 3 issues", name, sink.writes)
+		}
+	}
+	if got := len(client.GetCachedIssues()); got != 3 {
+		t.Errorf("in-memory cache holds %d issues, want 3", got)
+	}
+	if _, err := os.Stat(options.CacheFilePath); err != nil {
+		t.Errorf("cache file not written: %v", err)
+	}
+	if got, _ := meter.value("sink_errors_total", nil); got != 1 {
+		t.Errorf("sink_errors_total = %v, want 1", got)
+	}
+	if got, _ := meter.value("refresh_total", map[string]string{"result": "success"}); got != 1 {
+		t.Errorf("refresh_total{result=success} = %v, want 1", got)
+	}
+}
+
+func TestFailedRefreshSkipsSinks(t *testing.T) {
+	searcher := &pageSearcher{fail: map[int]error{0: errors.New("jira is down")}}
+	client := newTestClient(t, testOptions(), searcher)
+	recording := &recordingSink{}
+	client.AddSink(recording)
+	client.RefreshData(context.Background())
+
+	if len(recording.writes) != 0 {
+		t.Errorf("sink written %d times after a failed refresh", len(recording.writes))
+	}
+}
@@ -1,41 +1,111 @@
 package common
 
 import (
+	"context"
+	"fmt"
+
 	sre "github.com/devopsext/sre/common"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Observability struct {
-	logs    *sre.Logs
-	metrics *sre.Metrics
+	logs      *sre.Logs
+	metrics   *sre.Metrics
+	tracer    trace.Tracer
+	requestID string
+	tenant    string
+}
+
+type requestIDKey struct{}
+
+// Span wraps an OpenTelemetry span, all methods are no-ops without tracing
+type Span struct {
+	span trace.Span
+}
+
+// WithRequestID returns a context carrying the correlation ID of a refresh cycle
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID carried by the context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithContext returns an Observability tagging log lines with the context correlation ID
+func (o *Observability) WithContext(ctx context.Context) *Observability {
+	id := RequestID(ctx)
+	if id == "" {
+		return o
+	}
+	return &Observability{
+		logs:      o.logs,
+		metrics:   o.metrics,
+		tracer:    o.tracer,
+		requestID: id,
+		tenant:    o.tenant,
+	}
+}
+
+// WithTenant returns an Observability tagging log lines and spans with the tenant name
+func (o *Observability) WithTenant(tenant string) *Observability {
+	if tenant == "" {
+		return o
+	}
+	return &Observability{
+		logs:      o.logs,
+		metrics:   o.metrics,
+		tracer:    o.tracer,
+		requestID: o.requestID,
+		tenant:    tenant,
+	}
+}
+
+func (o *Observability) tag(obj interface{}) interface{} {
+	s, ok := obj.(string)
+	if !ok {
+		return obj
+	}
+	if o.requestID != "" {
+		s = fmt.Sprintf("[%s] %s", o.requestID, s)
+	}
+	if o.tenant != "" {
+		s = fmt.Sprintf("[%s] %s", o.tenant, s)
+	}
+	return s
 }
 
 func (o *Observability) Info(obj interface{}, args ...interface{}) {
 	if o.logs != nil {
-		o.logs.Info(obj, args...)
+		o.logs.Info(o.tag(obj), args...)
 	}
 }
 
 func (o *Observability) Warn(obj interface{}, args ...interface{}) {
 	if o.logs != nil {
-		o.logs.Warn(obj, args...)
+		o.logs.Warn(o.tag(obj), args...)
 	}
 }
 
 func (o *Observability) Debug(obj interface{}, args ...interface{}) {
 	if o.logs != nil {
-		o.logs.Debug(obj, args...)
+		o.logs.Debug(o.tag(obj), args...)
 	}
 }
 
 func (o *Observability) Error(obj interface{}, args ...interface{}) {
 	if o.logs != nil {
-		o.logs.Error(obj, args...)
+		o.logs.Error(o.tag(obj), args...)
 	}
 }
 
 func (o *Observability) Panic(obj interface{}, args ...interface{}) {
 	if o.logs != nil {
-		o.logs.Panic(obj, args...)
+		o.logs.Panic(o.tag(obj), args...)
 	}
 }
 
@@ -47,10 +117,69 @@ func (o *Observability) Metrics() *sre.Metrics {
 	return o.metrics
 }
 
-func NewObservability(logs *sre.Logs, metrics *sre.Metrics) *Observability {
+func (o *Observability) Tracer() trace.Tracer {
+	return o.tracer
+}
+
+// StartSpan starts a span named after the operation, as a child of the span carried by the context if any,
+// and returns a context carrying the new span
+func (o *Observability) StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if o.tracer == nil {
+		return ctx, &Span{}
+	}
+
+	ctx, span := o.tracer.Start(ctx, name)
+	if o.requestID != "" {
+		span.SetAttributes(attribute.String("request_id", o.requestID))
+	}
+	if o.tenant != "" {
+		span.SetAttributes(attribute.String("tenant", o.tenant))
+	}
+	return ctx, &Span{span: span}
+}
+
+// SetTag sets a span attribute, values other than strings, integers, floats and booleans are formatted as strings
+func (s *Span) SetTag(key string, value interface{}) {
+	if s.span == nil {
+		return
+	}
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.span.SetAttributes(attribute.Int64(key, v))
+	case float64:
+		s.span.SetAttributes(attribute.Float64(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+// Error records the error on the span and marks the span as failed
+func (s *Span) Error(err error) {
+	if s.span != nil && err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func (s *Span) Finish() {
+	if s.span != nil {
+		s.span.End()
+	}
+}
+
+// NewObservability returns an Observability logging and emitting metrics to the providers,
+// spans are started by the tracer, a nil tracer disables tracing
+func NewObservability(logs *sre.Logs, metrics *sre.Metrics, tracer trace.Tracer) *Observability {
 
 	return &Observability{
 		logs:    logs,
 		metrics: metrics,
+		tracer:  tracer,
 	}
 }
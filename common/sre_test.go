@@ -0,0 +1,150 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTracedClient creates a client recording its spans in memory
+func newTracedClient(t *testing.T, options JiraOptions, searcher IssueSearcher) (*JiraClient, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	client, err := NewJiraClient(options, NewObservability(nil, nil, provider.Tracer("test")).WithTenant("prod"), nil)
+	if err != nil {
+		t.Fatalf("NewJiraClient: %v", err)
+	}
+	client.SetSearcher(searcher)
+	return client, exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[string]interface{} {
+	m := make(map[string]interface{}, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		m[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return m
+}
+
+func spansNamed(spans tracetest.SpanStubs, name string) []tracetest.SpanStub {
+	var out []tracetest.SpanStub
+	for _, span := range spans {
+		if span.Name == name {
+			out = append(out, span)
+		}
+	}
+	return out
+}
+
+func TestRefreshSpans(t *testing.T) {
+	options := testOptions()
+	options.MaxResults = 2
+	options.FetchConcurrency = 1
+	client, exporter := newTracedClient(t, options, &pageSearcher{issues: testIssues(3)})
+
+	client.RefreshData(context.Background())
+
+	spans := exporter.GetSpans()
+	refreshes := spansNamed(spans, "refresh")
+	if len(refreshes) != 1 {
+		t.Fatalf("refresh spans = %d, want 1 of %v", len(refreshes), spans)
+	}
+	refresh := refreshes[0]
+	if refresh.Parent.IsValid() {
+		t.Error("refresh span has a parent, want a root span")
+	}
+	attrs := spanAttributes(refresh)
+	if attrs["issues"] != int64(3) || attrs["tenant"] != "prod" {
+		t.Errorf("refresh attributes = %v, want issues 3 and tenant prod", attrs)
+	}
+	if refresh.Status.Code == codes.Error {
+		t.Errorf("refresh status = %v, want no error", refresh.Status)
+	}
+
+	searches := spansNamed(spans, "jira.search")
+	if len(searches) != 2 {
+		t.Fatalf("jira.search spans = %d, want one per page", len(searches))
+	}
+	results := map[int64]int64{}
+	for _, search := range searches {
+		if search.Parent.SpanID() != refresh.SpanContext.SpanID() || search.SpanContext.TraceID() != refresh.SpanContext.TraceID() {
+			t.Errorf("jira.search span is not a child of the refresh span")
+		}
+		attrs := spanAttributes(search)
+		if jql, _ := attrs["jql"].(string); jql == "" {
+			t.Errorf("jira.search jql attribute missing in %v", attrs)
+		}
+		page, _ := attrs["page"].(int64)
+		results[page], _ = attrs["results"].(int64)
+	}
+	if want := map[int64]int64{1: 2, 2: 1}; len(results) != 2 || results[1] != want[1] || results[2] != want[2] {
+		t.Errorf("results per page = %v, want %v", results, want)
+	}
+}
+
+func TestRefreshSpansRecordErrors(t *testing.T) {
+	options := testOptions()
+	options.MaxRetries = 0
+	searcher := &pageSearcher{issues: testIssues(1), fail: map[int]error{0: errors.New("jira unavailable")}}
+	client, exporter := newTracedClient(t, options, searcher)
+
+	client.RefreshData(context.Background())
+
+	spans := exporter.GetSpans()
+	for _, name := range []string{"jira.search", "refresh"} {
+		found := spansNamed(spans, name)
+		if len(found) == 0 {
+			t.Fatalf("no %s span in %v", name, spans)
+		}
+		span := found[len(found)-1]
+		if span.Status.Code != codes.Error {
+			t.Errorf("%s status = %v, want error", name, span.Status)
+		}
+		recorded := false
+		for _, event := range span.Events {
+			recorded = recorded || event.Name == "exception"
+		}
+		if !recorded {
+			t.Errorf("%s span has no exception event", name)
+		}
+	}
+}
+
+func TestSpanTagTypes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	obs := NewObservability(nil, nil, provider.Tracer("test"))
+
+	_, span := obs.StartSpan(context.Background(), "test")
+	span.SetTag("string", "a")
+	span.SetTag("int", 1)
+	span.SetTag("bool", true)
+	span.SetTag("float", 0.5)
+	span.SetTag("other", []string{"x"})
+	span.Finish()
+
+	want := map[string]interface{}{"string": "a", "int": int64(1), "bool": true, "float": 0.5, "other": "[x]"}
+	got := spanAttributes(exporter.GetSpans()[0])
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("attribute %s = %#v, want %#v", key, got[key], value)
+		}
+	}
+}
+
+func TestSpansDisabledWithoutTracer(t *testing.T) {
+	ctx, span := NewObservability(nil, nil, nil).StartSpan(context.Background(), "refresh")
+	span.SetTag("issues", 1)
+	span.Error(errors.New("failed"))
+	span.Finish()
+	if ctx != context.Background() {
+		t.Error("context changed without a tracer")
+	}
+}
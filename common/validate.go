@@ -0,0 +1,56 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+// MissingFields returns the mapped custom field IDs unknown to the Jira instance by logical field,
+// the usual cause of custom fields that are always empty
+func (j *JiraClient) MissingFields(ctx context.Context) (map[string][]string, error) {
+	list, resp, err := j.client.Field.GetListWithContext(ctx)
+	if err = j.scrubError(err); err != nil {
+		j.reportHttpError(j.obs, httpResponse(resp), err)
+		return nil, fmt.Errorf("error listing jira fields: %w", err)
+	}
+
+	return missingFields(j.fields, list), nil
+}
+
+func missingFields(mapping map[string][]string, list []jira.Field) map[string][]string {
+	known := make(map[string]bool, len(list))
+	for _, field := range list {
+		known[field.ID] = true
+	}
+
+	missing := make(map[string][]string)
+	for name, ids := range mapping {
+		for _, id := range ids {
+			if !known[id] {
+				missing[name] = append(missing[name], id)
+			}
+		}
+		sort.Strings(missing[name])
+	}
+	for name, ids := range missing {
+		if len(ids) == 0 {
+			delete(missing, name)
+		}
+	}
+	return missing
+}
+
+// ValidateJQL runs the refresh query for a single issue, Jira rejects a query that does not parse
+func (j *JiraClient) ValidateJQL(ctx context.Context) error {
+	jql := j.buildJQL(j.options.RefreshScope == "open", time.Time{})
+	_, resp, err := j.searcher.Search(ctx, jql, &jira.SearchOptions{MaxResults: 1, Fields: []string{"key"}})
+	if err = j.scrubError(err); err != nil {
+		j.reportHttpError(j.obs, httpResponse(resp), err)
+		return fmt.Errorf("invalid jql %q: %w", jql, err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/andygrunwald/go-jira"
+)
+
+func TestMissingFields(t *testing.T) {
+	list := []jira.Field{{ID: "summary"}, {ID: "customfield_1"}, {ID: "customfield_3"}}
+
+	tests := []struct {
+		name    string
+		mapping map[string][]string
+		want    map[string][]string
+	}{
+		{
+			name:    "all known",
+			mapping: map[string][]string{"severity": {"customfield_1"}, "service": {"customfield_3"}},
+			want:    map[string][]string{},
+		},
+		{
+			name:    "unknown alternatives sorted",
+			mapping: map[string][]string{"severity": {"customfield_9", "customfield_1", "customfield_2"}, "service": {"customfield_4"}},
+			want:    map[string][]string{"severity": {"customfield_2", "customfield_9"}, "service": {"customfield_4"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingFields(tt.mapping, list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}